@@ -0,0 +1,271 @@
+package lmpsdat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// massTable is a named type whose underlying type is the one of the Masses.
+type massTable map[int]float64
+
+func TestDecodeFieldTypes(t *testing.T) {
+	const in = "argon\n\n1 atoms\n1 atom types\n\n0 10 xlo xhi\n0 20 ylo yhi\n0 30 zlo zhi\n\nMasses\n\n1 39.948\n\nAtoms\n\n1 1 1 2 3\n"
+	var v struct {
+		Title     *string           `lmpsdat:"Title"`
+		AtomsNbr  int               `lmpsdat:"atoms"`
+		AtomTypes int               `lmpsdat:"atom types"`
+		X         []float64         `lmpsdat:"xlo xhi"`
+		Y         [2]float64        `lmpsdat:"ylo yhi"`
+		Z         *[2]float64       `lmpsdat:"zlo zhi"`
+		Masses    massTable         `lmpsdat:"Masses"`
+		Atoms     map[int]*key.Atom `lmpsdat:"Atoms, atomic"`
+	}
+	if err := decodeString(in, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Title == nil || *v.Title != "argon" {
+		t.Errorf("Title = %v, want a pointer to %q", v.Title, "argon")
+	}
+	if len(v.X) != 2 || v.X[0] != 0 || v.X[1] != 10 || v.Z == nil || *v.Z != [2]float64{0, 30} {
+		t.Errorf("X = %v and Z = %v, want [0 10] and a pointer to [0 30]", v.X, v.Z)
+	}
+	// the nil maps are allocated.
+	if v.Masses == nil || v.Masses[1] != 39.948 || v.Atoms == nil {
+		t.Errorf("Masses = %v and Atoms = %v", v.Masses, v.Atoms)
+	}
+
+	// the fields are encoded as the plain types.
+	var a atomic
+	if err := decodeString(in, &a); err != nil {
+		t.Fatal(err)
+	}
+	if out, want := encodeString(t, &v), encodeString(t, &a); out != want {
+		t.Errorf("Encode = %q, want %q", out, want)
+	}
+
+	// a nil pointer is encoded as the zero value.
+	v.Title = nil
+	if out := encodeString(t, &v); !strings.HasPrefix(out, "\n\n1 atoms\n") {
+		t.Errorf("Encode = %q, want an empty title", out)
+	}
+
+	var bad struct {
+		Title int `lmpsdat:"Title"`
+	}
+	if err := decodeString(in, &bad); err == nil {
+		t.Error("Decode = nil with an int Title field")
+	}
+}
+
+// Named types whose underlying types are the ones of the Keys.
+type (
+	count      int
+	atomTable  map[int]*key.Atom
+	linkTable  map[int]*key.Link
+	coeffTable map[int][]float64
+)
+
+// typed is a structure whose fields are pointers, slices, and named types.
+type typed struct {
+	Title     *string     `lmpsdat:"Title"`
+	AtomsNbr  count       `lmpsdat:"atoms"`
+	AtomTypes count       `lmpsdat:"atom types"`
+	BondsNbr  *int        `lmpsdat:"bonds"`
+	BondTypes count       `lmpsdat:"bond types"`
+	X         []float64   `lmpsdat:"xlo xhi"`
+	Y         [2]float64  `lmpsdat:"ylo yhi"`
+	Z         *[2]float64 `lmpsdat:"zlo zhi"`
+	Masses    massTable   `lmpsdat:"Masses"`
+	Coeffs    coeffTable  `lmpsdat:"Bond Coeffs"`
+	Atoms     atomTable   `lmpsdat:"Atoms, full"`
+	Bonds     linkTable   `lmpsdat:"Bonds"`
+}
+
+const typedData = `typed
+
+4 atoms
+3 atom types
+2 bonds
+2 bond types
+
+0 10 xlo xhi
+0 10 ylo yhi
+0 10 zlo zhi
+
+Masses
+
+1 15.9994
+2 1.008
+3 12.011
+
+Bond Coeffs
+
+1 450 1
+2 300 1.5
+
+Atoms
+
+1 1 1 -0.8 1 1 1
+2 1 2 0.4 2 1 1
+3 2 1 -0.8 5 5 5
+4 2 2 0.4 6 5 5
+
+Bonds
+
+1 1 1 2
+2 1 3 4
+`
+
+func TestHelpersFieldTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		f    func(t *testing.T, v *typed)
+	}{
+		{"GetTitle", func(t *testing.T, v *typed) {
+			if title, err := GetTitle(v); err != nil || title != "typed" {
+				t.Errorf("GetTitle = %q, %v, want %q", title, err, "typed")
+			}
+			v.Title = nil
+			if title, err := GetTitle(v); err != nil || title != "" {
+				t.Errorf("GetTitle = %q, %v with a nil pointer, want an empty title", title, err)
+			}
+		}},
+		{"SetTitle", func(t *testing.T, v *typed) {
+			v.Title = nil
+			if err := SetTitle(v, "water"); err != nil {
+				t.Fatal(err)
+			}
+			if v.Title == nil || *v.Title != "water" {
+				t.Errorf("Title = %v, want a pointer to %q", v.Title, "water")
+			}
+		}},
+		{"GetBox", func(t *testing.T, v *typed) {
+			v.X = []float64{-1, 10}
+			b, err := GetBox(v)
+			if err != nil || b != (BoxDims{{-1, 10}, {0, 10}, {0, 10}}) {
+				t.Errorf("GetBox = %v, %v", b, err)
+			}
+		}},
+		{"Renumber", func(t *testing.T, v *typed) {
+			v.Atoms[10] = v.Atoms[4]
+			delete(v.Atoms, 4)
+			v.Bonds[2].Atoms()[1] = 10
+			remap, err := Renumber(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if remap[10] != 4 || v.Atoms[4] == nil || v.Bonds[2].Atoms()[1] != 4 {
+				t.Errorf("remap = %v, atoms = %v, bond 2 = %v", remap, v.Atoms, v.Bonds[2].Atoms())
+			}
+		}},
+		{"CompactTypes", func(t *testing.T, v *typed) {
+			v.Bonds[2].SetType(2)
+			v.Bonds[1].SetType(2)
+			if _, err := CompactTypes(v); err != nil {
+				t.Fatal(err)
+			}
+			if v.AtomTypes != 2 || len(v.Masses) != 2 || v.BondTypes != 1 || len(v.Coeffs) != 1 || v.Coeffs[1][0] != 300 {
+				t.Errorf("types = %d %d, Masses = %v, Coeffs = %v", v.AtomTypes, v.BondTypes, v.Masses, v.Coeffs)
+			}
+		}},
+		{"RemoveMolecule", func(t *testing.T, v *typed) {
+			if _, err := RemoveMolecule(v, 1); err != nil {
+				t.Fatal(err)
+			}
+			if len(v.Atoms) != 2 || v.AtomsNbr != 2 || len(v.Bonds) != 1 || *v.BondsNbr != 1 {
+				t.Errorf("atoms = %d (%d), bonds = %d (%d), want 2 and 1", len(v.Atoms), v.AtomsNbr, len(v.Bonds), *v.BondsNbr)
+			}
+		}},
+		{"Replicate", func(t *testing.T, v *typed) {
+			if err := Replicate(v, 2, 1, 3); err != nil {
+				t.Fatal(err)
+			}
+			if len(v.Atoms) != 24 || v.AtomsNbr != 24 || len(v.Bonds) != 12 || *v.BondsNbr != 12 {
+				t.Errorf("atoms = %d (%d), bonds = %d (%d), want 24 and 12", len(v.Atoms), v.AtomsNbr, len(v.Bonds), *v.BondsNbr)
+			}
+			if len(v.X) != 2 || v.X[1] != 20 || v.Y[1] != 10 || v.Z[1] != 30 {
+				t.Errorf("box = %v %v %v", v.X, v.Y, *v.Z)
+			}
+		}},
+		{"NewAtomReader", func(t *testing.T, v *typed) {
+			r, err := NewAtomReader(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a, ok := r.Next(); !ok || a.ID != 1 || a.TypeMass != 15.9994 {
+				t.Errorf("Next = %+v, %v", a, ok)
+			}
+		}},
+		{"Validate", func(t *testing.T, v *typed) {
+			if err := Validate(v); err != nil {
+				t.Fatal(err)
+			}
+			v.AtomsNbr = 5
+			if err := Validate(v); err == nil {
+				t.Error("Validate = nil with 5 atoms for 4 values")
+			}
+		}},
+		{"NormalizeForLAMMPS", func(t *testing.T, v *typed) {
+			*v.BondsNbr = 1
+			v.X = []float64{10, 0}
+			report, err := NormalizeForLAMMPS(v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *v.BondsNbr != 2 || v.X[0] >= v.X[1] || len(report) != 2 {
+				t.Errorf("bonds = %d, X = %v, report = %q", *v.BondsNbr, v.X, report)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v typed
+			if err := decodeString(typedData, &v); err != nil {
+				t.Fatal(err)
+			}
+			tt.f(t, &v)
+		})
+	}
+}
+
+func TestHelpersInvalidField(t *testing.T) {
+	var title struct {
+		Title int `lmpsdat:"Title"`
+	}
+	var counts struct {
+		AtomsNbr string            `lmpsdat:"atoms"`
+		Atoms    map[int]*key.Atom `lmpsdat:"Atoms, full"`
+	}
+	var atoms struct {
+		Atoms map[string]*key.Atom `lmpsdat:"Atoms, full"`
+	}
+	var box struct {
+		X []float64 `lmpsdat:"xlo xhi"`
+		Y []float64 `lmpsdat:"ylo yhi"`
+		Z []float64 `lmpsdat:"zlo zhi"`
+	}
+	box.X, box.Y, box.Z = []float64{0, 1, 2}, []float64{0, 1}, []float64{0, 1}
+	tests := []struct {
+		name string
+		f    func() error
+	}{
+		{"GetTitle", func() error { _, err := GetTitle(&title); return err }},
+		{"SetTitle", func() error { return SetTitle(&title, "x") }},
+		{"GetBox", func() error { _, err := GetBox(&box); return err }},
+		{"Renumber", func() error { _, err := Renumber(&atoms); return err }},
+		{"CompactTypes", func() error { _, err := CompactTypes(&atoms); return err }},
+		{"RemoveMolecule", func() error { _, err := RemoveMolecule(&atoms, 1); return err }},
+		{"NewAtomReader", func() error { _, err := NewAtomReader(&atoms); return err }},
+		{"Validate", func() error { return Validate(&counts) }},
+		{"NormalizeForLAMMPS", func() error { _, err := NormalizeForLAMMPS(&counts); return err }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.f(); err == nil {
+				t.Error("err = nil, want an error for a field that cannot be converted")
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// ResolvedAtom is an atom joined with the data of its type.
+type ResolvedAtom struct {
+	ID int
+	key.Atom
+
+	// TypeMass is the mass of the atom type given by the Masses table. If
+	// there is no such mass, it is the mass of the atom (see key.ColumnMass).
+	// The mass of the atom itself is kept in Atom.Mass.
+	TypeMass float64
+
+	// Element is the element of the atom type given by the table set with
+	// SetElements. It is empty if there is no such element.
+	Element string
+}
+
+// AtomReader reads the atoms of a decoded struct one by one in increasing order
+// of identifier. Each atom is joined with the mass and the element of its type.
+//
+// AtomReader must be instanced by using the NewAtomReader function.
+type AtomReader struct {
+	atoms    map[int]*key.Atom
+	masses   map[int]float64
+	elements map[int]string
+	ids      []int
+}
+
+// NewAtomReader returns an AtomReader reading the atoms of the struct pointed to
+// by v. The struct must have a field tagged with NameAtoms. The field tagged
+// with NameMasses is optional.
+func NewAtomReader(v interface{}) (*AtomReader, error) {
+	f, err := fields(v)
+	if err != nil {
+		return nil, err
+	}
+	atoms, ok, err := atomsOf(f)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("field with Name = %s is missing", key.NameAtoms)
+	}
+
+	r := &AtomReader{atoms: atoms, ids: atomIDs(atoms)}
+	masses, ok, err := fieldOf(f, key.NameMasses)
+	if err != nil {
+		return nil, err
+	} else if ok {
+		r.masses = masses.(map[int]float64)
+	}
+	return r, nil
+}
+
+// SetElements sets the table linking the atom types to their element (e.g.
+// 1: "O", 2: "H"). A nil table removes the previous one.
+func (r *AtomReader) SetElements(elements map[int]string) {
+	r.elements = elements
+}
+
+// Next returns the next atom. It returns false if there is no more atom. The
+// nil atoms are skipped.
+func (r *AtomReader) Next() (ResolvedAtom, bool) {
+	for len(r.ids) > 0 {
+		id := r.ids[0]
+		r.ids = r.ids[1:]
+		atom := r.atoms[id]
+		if atom == nil {
+			continue
+		}
+		ra := ResolvedAtom{ID: id, Atom: *atom, TypeMass: atom.Mass}
+		if m, ok := r.masses[atom.AtomType]; ok {
+			ra.TypeMass = m
+		}
+		ra.Element = r.elements[atom.AtomType]
+		return ra, true
+	}
+	return ResolvedAtom{}, false
+}
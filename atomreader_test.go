@@ -0,0 +1,58 @@
+package lmpsdat
+
+import "testing"
+
+func TestAtomReader(t *testing.T) {
+	s := fullSystem(t)
+	r, err := NewAtomReader(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetElements(map[int]string{1: "O", 2: "H"})
+
+	var ids []int
+	for {
+		atom, ok := r.Next()
+		if !ok {
+			break
+		}
+		ids = append(ids, atom.ID)
+		if atom.TypeMass != s.Masses[atom.AtomType] {
+			t.Errorf("mass of atom = %d = %g, want %g", atom.ID, atom.TypeMass, s.Masses[atom.AtomType])
+		}
+		if want := map[int]string{1: "O", 2: "H"}[atom.AtomType]; atom.Element != want {
+			t.Errorf("element of atom = %d = %q, want %q", atom.ID, atom.Element, want)
+		}
+		if atom.Atom != *s.Atoms[atom.ID] {
+			t.Errorf("atom = %d = %+v, want %+v", atom.ID, atom.Atom, *s.Atoms[atom.ID])
+		}
+	}
+	if len(ids) != 6 {
+		t.Fatalf("%d atoms read, want 6", len(ids))
+	}
+	for i, id := range ids {
+		if id != i+1 {
+			t.Errorf("identifiers = %v, want increasing order", ids)
+			break
+		}
+	}
+
+	// the mass of the atom is used without the mass of its type and is kept
+	// in Atom.Mass.
+	delete(s.Masses, 2)
+	s.Atoms[2].Mass = 2.014
+	r, err = NewAtomReader(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []struct{ typeMass, mass float64 }{{15.9994, 0}, {2.014, 2.014}, {0, 0}} {
+		atom, _ := r.Next()
+		if atom.TypeMass != want.typeMass || atom.Mass != want.mass {
+			t.Errorf("atom = %d: TypeMass = %g and Mass = %g, want %g and %g", atom.ID, atom.TypeMass, atom.Mass, want.typeMass, want.mass)
+		}
+	}
+
+	if _, err := NewAtomReader(&struct{ Title string }{}); err == nil {
+		t.Error("NewAtomReader = nil without an Atoms field")
+	}
+}
@@ -0,0 +1,63 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// BondLength is the length of the bond whose identifier is ID.
+type BondLength struct {
+	ID     int
+	Length float64
+}
+
+// BondLengths returns the length of each bond in increasing order of
+// identifier. The length is the minimum-image distance between the two atoms of
+// the bond: the box is assumed periodic in every dimension. A bond spanning the
+// boundary of the box therefore has the same length as if the atoms were in the
+// same image. An error is returned if a bond references an atom that does not
+// exist.
+func BondLengths(atoms map[int]*key.Atom, bonds map[int]*key.Link, box BoxDims) ([]BondLength, error) {
+	l := box.Lengths()
+	lengths := make([]BondLength, 0, len(bonds))
+	for id, bond := range bonds {
+		if bond == nil {
+			return nil, fmt.Errorf("bond = %d is nil", id)
+		}
+		a := bond.Atoms()
+		if len(a) != 2 {
+			return nil, fmt.Errorf("bond = %d has %d atoms, want 2", id, len(a))
+		}
+		a1, a2 := atoms[a[0]], atoms[a[1]]
+		if a1 == nil || a2 == nil {
+			return nil, fmt.Errorf("atoms = %d, %d of bond = %d do not exist", a[0], a[1], id)
+		}
+
+		d := [3]float64{a2.X - a1.X, a2.Y - a1.Y, a2.Z - a1.Z}
+		var sq float64
+		for i := range d {
+			if l[i] > 0 {
+				d[i] -= l[i] * math.Round(d[i]/l[i])
+			}
+			sq += d[i] * d[i]
+		}
+		lengths = append(lengths, BondLength{ID: id, Length: math.Sqrt(sq)})
+	}
+	sort.Slice(lengths, func(i, j int) bool { return lengths[i].ID < lengths[j].ID })
+	return lengths, nil
+}
+
+// LongBonds returns the bonds of lengths that are longer than max, e.g. the
+// bonds that may be broken.
+func LongBonds(lengths []BondLength, max float64) []BondLength {
+	var long []BondLength
+	for _, b := range lengths {
+		if b.Length > max {
+			long = append(long, b)
+		}
+	}
+	return long
+}
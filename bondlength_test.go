@@ -0,0 +1,45 @@
+package lmpsdat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestBondLengths(t *testing.T) {
+	atoms := map[int]*key.Atom{
+		1: {X: 0.5, Y: 5, Z: 5},
+		2: {X: 9.5, Y: 5, Z: 5}, // across the boundary of 1
+		3: {X: 1.5, Y: 5, Z: 5},
+		4: {X: 5, Y: 5, Z: 5},
+	}
+	bonds := map[int]*key.Link{
+		3: key.NewLink(1, 1, 2),
+		1: key.NewLink(1, 1, 3),
+		2: key.NewLink(1, 3, 4),
+	}
+	box := BoxDims{{0, 10}, {0, 10}, {0, 10}}
+	lengths, err := BondLengths(atoms, bonds, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BondLength{{1, 1}, {2, 3.5}, {3, 1}}
+	if len(lengths) != len(want) {
+		t.Fatalf("BondLengths = %v, want %v", lengths, want)
+	}
+	for i := range want {
+		if lengths[i].ID != want[i].ID || math.Abs(lengths[i].Length-want[i].Length) > 1e-12 {
+			t.Errorf("BondLengths = %v, want %v", lengths, want)
+			break
+		}
+	}
+	if long := LongBonds(lengths, 2); len(long) != 1 || long[0].ID != 2 {
+		t.Errorf("LongBonds = %v, want the bond = 2", long)
+	}
+
+	bonds[4] = key.NewLink(1, 1, 5)
+	if _, err := BondLengths(atoms, bonds, box); err == nil {
+		t.Error("BondLengths = nil with a missing atom")
+	}
+}
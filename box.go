@@ -0,0 +1,47 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// BoxDims contains the size of the box for the x, y, and z coordinates. For
+// each coordinate, the first value is the point in space where the box begins
+// and the second value is the point in space where the box ends.
+type BoxDims [3][2]float64
+
+// GetBox returns the size of the box stored in the struct pointed to by v. The
+// struct must have the fields tagged with NameBoxX, NameBoxY, and NameBoxZ.
+func GetBox(v interface{}) (BoxDims, error) {
+	var b BoxDims
+	f, err := fields(v)
+	if err != nil {
+		return b, err
+	}
+	for i, n := range []key.Name{key.NameBoxX, key.NameBoxY, key.NameBoxZ} {
+		v, ok, err := fieldOf(f, n)
+		if err != nil {
+			return b, err
+		} else if !ok {
+			return b, fmt.Errorf("field with Name = %s is missing", n)
+		}
+		b[i] = v.([2]float64)
+	}
+	return b, nil
+}
+
+// Lengths returns the lengths of the box for the x, y, and z coordinates.
+func (b BoxDims) Lengths() [3]float64 {
+	var l [3]float64
+	for i := range b {
+		l[i] = b[i][1] - b[i][0]
+	}
+	return l
+}
+
+// Volume returns the volume of the box.
+func (b BoxDims) Volume() float64 {
+	l := b.Lengths()
+	return l[0] * l[1] * l[2]
+}
@@ -0,0 +1,45 @@
+package lmpsdat
+
+import (
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestGetBox(t *testing.T) {
+	s := fullSystem(t)
+	s.Y = [2]float64{-1, 3}
+	b, err := GetBox(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BoxDims{{0, 10}, {-1, 3}, {0, 10}}); b != want {
+		t.Errorf("GetBox = %v, want %v", b, want)
+	}
+	if want := [3]float64{10, 4, 10}; b.Lengths() != want {
+		t.Errorf("Lengths = %v, want %v", b.Lengths(), want)
+	}
+	if b.Volume() != 400 {
+		t.Errorf("Volume = %g, want 400", b.Volume())
+	}
+
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"missing z", &struct {
+			X [2]float64 `lmpsdat:"xlo xhi"`
+			Y [2]float64 `lmpsdat:"ylo yhi"`
+		}{}},
+		{"not a pointer", *s},
+		{"nil pointer", (*system)(nil)},
+		{"atoms only", &struct {
+			Atoms map[int]*key.Atom `lmpsdat:"Atoms"`
+		}{}},
+	}
+	for _, tt := range tests {
+		if _, err := GetBox(tt.v); err == nil {
+			t.Errorf("%s: GetBox = nil, want an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// Translate adds d to the coordinates of each atom.
+func Translate(atoms map[int]*key.Atom, d [3]float64) {
+	for _, atom := range atoms {
+		atom.X += d[0]
+		atom.Y += d[1]
+		atom.Z += d[2]
+	}
+}
+
+// Centroid returns the geometric center of the atoms, i.e. the mean of their
+// coordinates. It returns the origin if there is no atom.
+func Centroid(atoms map[int]*key.Atom) [3]float64 {
+	var c [3]float64
+	if len(atoms) == 0 {
+		return c
+	}
+	for _, atom := range atoms {
+		c[0] += atom.X
+		c[1] += atom.Y
+		c[2] += atom.Z
+	}
+	for i := range c {
+		c[i] /= float64(len(atoms))
+	}
+	return c
+}
+
+// CenterOfMass returns the center of mass of the atoms. masses contains the
+// mass of each atom type (see NameMasses). An error is returned if the mass of
+// an atom type is missing or if the total mass is not greater than zero.
+//
+// The coordinates are used as they are: the image flags are not taken into
+// account.
+func CenterOfMass(atoms map[int]*key.Atom, masses map[int]float64) ([3]float64, error) {
+	var c [3]float64
+	var total float64
+	for id, atom := range atoms {
+		m, ok := masses[atom.AtomType]
+		if !ok {
+			return c, fmt.Errorf("mass of type = %d of atom = %d is missing", atom.AtomType, id)
+		}
+		c[0] += m * atom.X
+		c[1] += m * atom.Y
+		c[2] += m * atom.Z
+		total += m
+	}
+	if !(total > 0) {
+		return c, fmt.Errorf("total mass = %g is invalid: it must be greater than zero", total)
+	}
+	for i := range c {
+		c[i] /= total
+	}
+	return c, nil
+}
+
+// center returns the center of mass of the atoms if masses is not nil and
+// their geometric center otherwise.
+func center(atoms map[int]*key.Atom, masses map[int]float64) ([3]float64, error) {
+	if masses == nil {
+		return Centroid(atoms), nil
+	}
+	return CenterOfMass(atoms, masses)
+}
+
+// RecenterToBox translates the atoms so that their center is the center of the
+// box. The center of mass is used if masses is not nil (see CenterOfMass),
+// otherwise the geometric center is used (see Centroid). It does nothing if
+// there is no atom.
+func RecenterToBox(atoms map[int]*key.Atom, box BoxDims, masses map[int]float64) error {
+	if len(atoms) == 0 {
+		return nil
+	}
+	c, err := center(atoms, masses)
+	if err != nil {
+		return err
+	}
+	var d [3]float64
+	for i := range d {
+		d[i] = (box[i][0]+box[i][1])/2 - c[i]
+	}
+	Translate(atoms, d)
+	return nil
+}
+
+// RecenterToOrigin works like RecenterToBox but the atoms are translated so
+// that their center is the origin.
+func RecenterToOrigin(atoms map[int]*key.Atom, masses map[int]float64) error {
+	return RecenterToBox(atoms, BoxDims{}, masses)
+}
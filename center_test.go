@@ -0,0 +1,113 @@
+package lmpsdat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// dimer returns an atom of type 1 at the origin and an atom of type 2 at x = 4.
+func dimer() map[int]*key.Atom {
+	return map[int]*key.Atom{
+		1: {AtomType: 1, X: 0, Y: 1, Z: 2},
+		2: {AtomType: 2, X: 4, Y: 1, Z: 2},
+	}
+}
+
+// near reports whether each coordinate of got is within 1e-12 of want.
+func near(got, want [3]float64) bool {
+	for i := range got {
+		if math.Abs(got[i]-want[i]) > 1e-12 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCenter(t *testing.T) {
+	if got := Centroid(dimer()); got != [3]float64{2, 1, 2} {
+		t.Errorf("Centroid = %v, want [2 1 2]", got)
+	}
+	if got := Centroid(map[int]*key.Atom{}); got != [3]float64{} {
+		t.Errorf("Centroid without atom = %v, want the origin", got)
+	}
+
+	tests := []struct {
+		name    string
+		masses  map[int]float64
+		want    [3]float64
+		wantErr bool
+	}{
+		{"weighted", map[int]float64{1: 3, 2: 1}, [3]float64{1, 1, 2}, false},
+		{"equal masses", map[int]float64{1: 2, 2: 2}, [3]float64{2, 1, 2}, false},
+		{"missing mass", map[int]float64{1: 3}, [3]float64{}, true},
+		{"zero mass", map[int]float64{1: 0, 2: 0}, [3]float64{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CenterOfMass(dimer(), tt.masses)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("CenterOfMass = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !near(got, tt.want) {
+				t.Errorf("CenterOfMass = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecenter(t *testing.T) {
+	box := BoxDims{{0, 10}, {-2, 2}, {0, 6}}
+	masses := map[int]float64{1: 3, 2: 1}
+	tests := []struct {
+		name   string
+		origin bool
+		masses map[int]float64
+		want   [3]float64 // center after the translation
+	}{
+		{"box centroid", false, nil, [3]float64{5, 0, 3}},
+		{"box center of mass", false, masses, [3]float64{5, 0, 3}},
+		{"origin centroid", true, nil, [3]float64{}},
+		{"origin center of mass", true, masses, [3]float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atoms := dimer()
+			var err error
+			if tt.origin {
+				err = RecenterToOrigin(atoms, tt.masses)
+			} else {
+				err = RecenterToBox(atoms, box, tt.masses)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := center(atoms, tt.masses)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !near(got, tt.want) {
+				t.Errorf("center = %v, want %v", got, tt.want)
+			}
+			// the atoms are translated together.
+			if d := atoms[2].X - atoms[1].X; d != 4 {
+				t.Errorf("distance = %g, want 4", d)
+			}
+		})
+	}
+
+	atoms := dimer()
+	if err := RecenterToBox(atoms, box, map[int]float64{1: 3}); err == nil {
+		t.Error("RecenterToBox = nil with a missing mass")
+	}
+	if atoms[1].X != 0 || atoms[2].X != 4 {
+		t.Errorf("atoms = %+v %+v were translated despite the error", *atoms[1], *atoms[2])
+	}
+}
@@ -0,0 +1,66 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// NetCharge returns the sum of the charges of the atoms. The charges are summed
+// in increasing order of identifier so that the rounding errors, and thus the
+// result, do not depend on the order of the map.
+func NetCharge(atoms map[int]*key.Atom) float64 {
+	var q float64
+	for _, id := range atomIDs(atoms) {
+		q += atoms[id].Q
+	}
+	return q
+}
+
+// CheckIntegralCharge verifies that the net charge of the atoms does not
+// deviate from the nearest integer by more than tol. The net charge is computed
+// with the NetCharge function.
+func CheckIntegralCharge(atoms map[int]*key.Atom, tol float64) error {
+	net := NetCharge(atoms)
+	nearest := math.Round(net)
+	if math.Abs(net-nearest) > tol {
+		return fmt.Errorf("net charge = %g is not integral: it deviates from the nearest integer = %g by more than %g", net, nearest, tol)
+	}
+	return nil
+}
+
+// Neutralize subtracts NetCharge/N from the charge of each of the N atoms so
+// that the net charge becomes zero. It does nothing if there is no atom.
+//
+// This is a crude method: the residual charge is spread evenly regardless of
+// the chemistry of the atoms. It should only be used to fix a small residual
+// charge (e.g. due to rounding).
+func Neutralize(atoms map[int]*key.Atom) {
+	if len(atoms) == 0 {
+		return
+	}
+	dq := NetCharge(atoms) / float64(len(atoms))
+	for _, atom := range atoms {
+		atom.Q -= dq
+	}
+}
+
+// AssignCharges sets the charge of each atom to the charge of its type given by
+// typeCharge. An error is returned if the charge of an atom type is missing:
+// the atoms are then left untouched.
+func AssignCharges(atoms map[int]*key.Atom, typeCharge map[int]float64) error {
+	for _, id := range atomIDs(atoms) {
+		atom := atoms[id]
+		if atom == nil {
+			return fmt.Errorf("atom = %d is nil", id)
+		}
+		if _, ok := typeCharge[atom.AtomType]; !ok {
+			return fmt.Errorf("charge of type = %d of atom = %d is missing", atom.AtomType, id)
+		}
+	}
+	for _, atom := range atoms {
+		atom.Q = typeCharge[atom.AtomType]
+	}
+	return nil
+}
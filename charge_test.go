@@ -0,0 +1,137 @@
+package lmpsdat
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// charged returns the atoms whose charges are q, identified from one.
+func charged(q ...float64) map[int]*key.Atom {
+	atoms := make(map[int]*key.Atom, len(q))
+	for i, v := range q {
+		atoms[i+1] = &key.Atom{MolTag: 1, AtomType: 1, Q: v}
+	}
+	return atoms
+}
+
+func TestNetCharge(t *testing.T) {
+	tests := []struct {
+		name string
+		q    []float64
+		want float64
+	}{
+		{"neutral", []float64{-0.8476, 0.4238, 0.4238}, 0},
+		{"charged", []float64{1, 1, -1}, 1},
+		// 1e16 + 1 is rounded to 1e16: the charge 1 is lost in this order only.
+		{"rounding", []float64{1e16, 1, -1e16}, 0},
+		{"empty", nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atoms := charged(tt.q...)
+			for i := 0; i < 20; i++ { // the order of the map is randomized
+				if got := NetCharge(atoms); math.Abs(got-tt.want) > 1e-12 {
+					t.Fatalf("NetCharge = %g, want %g", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckIntegralCharge(t *testing.T) {
+	tests := []struct {
+		name    string
+		q       []float64
+		tol     float64
+		net     float64
+		wantErr bool
+	}{
+		{"neutral", []float64{-0.8476, 0.4238, 0.4238}, 1e-9, 0, false},
+		{"integral", []float64{1, 1, -1}, 1e-9, 1, false},
+		{"within tolerance", []float64{0.5, 0.5001}, 1e-3, 1.0001, false},
+		{"fractional", []float64{0.5, 0.25}, 1e-3, 0.75, true},
+		{"empty", nil, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atoms := charged(tt.q...)
+			if got := NetCharge(atoms); got-tt.net > 1e-12 || tt.net-got > 1e-12 {
+				t.Errorf("NetCharge = %g, want %g", got, tt.net)
+			}
+			if err := CheckIntegralCharge(atoms, tt.tol); (err != nil) != tt.wantErr {
+				t.Errorf("CheckIntegralCharge = %v, want error = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNeutralize(t *testing.T) {
+	tests := []struct {
+		name string
+		q    []float64
+	}{
+		{"residual", []float64{-0.8476, 0.4238, 0.4239}},
+		{"integral", []float64{1, 1, 1, -2}},
+		{"neutral", []float64{-1, 1}},
+		{"empty", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atoms := charged(tt.q...)
+			Neutralize(atoms)
+			if net := NetCharge(atoms); math.Abs(net) > 1e-12 {
+				t.Errorf("NetCharge = %g after Neutralize, want 0", net)
+			}
+		})
+	}
+
+	// the residual charge is spread evenly.
+	atoms := charged(0.5, 0.5)
+	Neutralize(atoms)
+	if atoms[1].Q != 0 || atoms[2].Q != 0 {
+		t.Errorf("charges = %g, %g, want 0, 0", atoms[1].Q, atoms[2].Q)
+	}
+}
+
+func TestAssignCharges(t *testing.T) {
+	s := fullSystem(t)
+	for _, atom := range s.Atoms {
+		atom.Q = 0
+	}
+	if err := AssignCharges(s.Atoms, map[int]float64{1: -0.8476, 2: 0.4238, 3: 1}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[int]float64{1: -0.8476, 2: 0.4238, 3: 0.4238, 4: -0.8476, 5: 0.4238, 6: 0.4238}
+	for id, q := range want {
+		if s.Atoms[id].Q != q {
+			t.Errorf("atom %d Q = %g, want %g", id, s.Atoms[id].Q, q)
+		}
+	}
+	// the charges are encoded.
+	if out := encodeString(t, s); !strings.Contains(out, "\n2 1 2 0.4238 1.8 1.5 1\n") {
+		t.Errorf("Encode does not write the charges assigned:\n%s", out)
+	}
+
+	tests := []struct {
+		name       string
+		atoms      map[int]*key.Atom
+		typeCharge map[int]float64
+	}{
+		{"missing type", map[int]*key.Atom{1: {AtomType: 1, Q: 0.5}, 2: {AtomType: 2, Q: 0.5}}, map[int]float64{1: -1}},
+		{"nil atom", map[int]*key.Atom{1: {AtomType: 1, Q: 0.5}, 2: nil}, map[int]float64{1: -1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := AssignCharges(tt.atoms, tt.typeCharge); err == nil {
+				t.Fatal("AssignCharges = nil, want an error")
+			}
+			// the atoms are left untouched.
+			if tt.atoms[1].Q != 0.5 {
+				t.Errorf("atom 1 Q = %g, want 0.5", tt.atoms[1].Q)
+			}
+		})
+	}
+}
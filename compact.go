@@ -0,0 +1,153 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// CompactTypes removes the types of the struct pointed to by v that are not used
+// by any atom or link and renumbers the remaining types from one while keeping
+// their order. For each kind of type, the types used are given by the fields
+// tagged with NameAtoms, NameBonds, NameAngles, and NameDihedrals: a kind is
+// left untouched if its field is missing. The improper types are therefore
+// never compacted.
+//
+// The references of the atoms and of the links are updated, the values of the
+// unused types are removed from the Masses and Coeffs tables (including the
+// class2 cross-terms and the PairIJ Coeffs table), and the values of the other
+// types are renumbered. The
+// fields containing the number of types (e.g. NameAtomTypes) are set to the
+// number of types used.
+//
+// It returns, for each kind of type identified by the Name of its header (e.g.
+// NameBondTypes), a map linking the previous types to the new ones.
+func CompactTypes(v interface{}) (map[key.Name]map[int]int, error) {
+	f, err := fields(v)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[key.Name]map[int]bool)
+	atoms, ok, err := atomsOf(f)
+	if err != nil {
+		return nil, err
+	} else if ok {
+		u := make(map[int]bool)
+		for id, atom := range atoms {
+			if atom == nil {
+				return nil, fmt.Errorf("atom = %d is nil", id)
+			}
+			u[atom.AtomType] = true
+		}
+		used[key.NameAtomTypes] = u
+	}
+	links := make(map[key.Name]map[int]*key.Link)
+	for _, name := range linkNames {
+		l, ok, err := linksOf(f, name)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		links[name] = l
+		u := make(map[int]bool)
+		for id, link := range l {
+			if link == nil {
+				return nil, fmt.Errorf("link = %d of %s is nil", id, name)
+			}
+			u[link.Type()] = true
+		}
+		used[typesOf[name]] = u
+	}
+	coeffs := make(map[key.Name]reflect.Value)
+	for table := range coeffsOf {
+		c, ok, err := fieldOf(f, table)
+		if err != nil {
+			return nil, err
+		} else if ok {
+			coeffs[table] = reflect.ValueOf(c)
+		}
+	}
+	pairIJ, _, err := fieldOf(f, key.NamePairIJCoeffs)
+	if err != nil {
+		return nil, err
+	}
+	for types := range used {
+		if _, _, err := intOf(f, types); err != nil {
+			return nil, err
+		}
+	}
+
+	// v is modified once every value is verified.
+	remaps := make(map[key.Name]map[int]int, len(used))
+	for types, u := range used {
+		old := make([]int, 0, len(u))
+		for typ := range u {
+			old = append(old, typ)
+		}
+		sort.Ints(old)
+		remap := make(map[int]int, len(old))
+		for i, typ := range old {
+			remap[typ] = i + 1
+		}
+		remaps[types] = remap
+
+		if _, ok := f[types]; ok {
+			if err := setField(f, types, len(old)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if remap, ok := remaps[key.NameAtomTypes]; ok {
+		for _, atom := range atoms {
+			atom.AtomType = remap[atom.AtomType]
+		}
+	}
+	for _, name := range linkNames {
+		remap, ok := remaps[typesOf[name]]
+		if !ok {
+			continue
+		}
+		for _, link := range links[name] {
+			link.SetType(remap[link.Type()])
+		}
+	}
+	for table, types := range coeffsOf {
+		c, ok := coeffs[table]
+		remap, ok2 := remaps[types]
+		if !ok || !ok2 || c.IsNil() {
+			continue
+		}
+		m := reflect.MakeMap(c.Type())
+		iter := c.MapRange()
+		for iter.Next() {
+			typ, ok := remap[int(iter.Key().Int())]
+			if ok {
+				m.SetMapIndex(reflect.ValueOf(typ), iter.Value())
+			}
+		}
+		if err := setField(f, table, m.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	if remap, ok := remaps[key.NameAtomTypes]; ok && pairIJ != nil {
+		if pairs := pairIJ.(map[[2]int][]float64); pairs != nil {
+			m := make(map[[2]int][]float64, len(pairs))
+			for ij, c := range pairs {
+				i, ok := remap[ij[0]]
+				j, ok2 := remap[ij[1]]
+				if ok && ok2 {
+					m[[2]int{i, j}] = c
+				}
+			}
+			if err := setField(f, key.NamePairIJCoeffs, m); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return remaps, nil
+}
@@ -0,0 +1,103 @@
+package lmpsdat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestCompactTypes(t *testing.T) {
+	// the hydrogens use the atom type 3 and the bonds the bond type 3: the
+	// types 2 are unused.
+	s := fullSystem(t)
+	s.AtomTypes, s.BondTypes = 3, 3
+	s.Masses[3] = s.Masses[2]
+	s.Masses[2] = 12.011
+	s.PairCoeffs[3] = s.PairCoeffs[2]
+	s.PairCoeffs[2] = []float64{0.07, 3.55}
+	s.BondCoeffs[2] = []float64{340, 1.09}
+	s.BondCoeffs[3] = []float64{450, 1}
+	for _, atom := range s.Atoms {
+		if atom.AtomType == 2 {
+			atom.AtomType = 3
+		}
+	}
+	for _, bond := range s.Bonds {
+		bond.SetType(3)
+	}
+
+	remaps, err := CompactTypes(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[key.Name]map[int]int{
+		key.NameAtomTypes:  {1: 1, 3: 2},
+		key.NameBondTypes:  {3: 1},
+		key.NameAngleTypes: {1: 1},
+	}
+	if !reflect.DeepEqual(remaps, want) {
+		t.Errorf("CompactTypes = %v, want %v", remaps, want)
+	}
+	if s.AtomTypes != 2 || s.BondTypes != 1 || s.AngleTypes != 1 {
+		t.Errorf("types = %d atom, %d bond, %d angle, want 2, 1, 1", s.AtomTypes, s.BondTypes, s.AngleTypes)
+	}
+
+	// the system is compacted back to testdata/full.data.
+	if got, want := encodeString(t, s), encodeString(t, fullSystem(t)); got != want {
+		t.Errorf("compacted system:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestCompactTypesUntouched(t *testing.T) {
+	// a kind of type without its table is not compacted.
+	var v struct {
+		AtomTypes int               `lmpsdat:"atom types"`
+		BondTypes int               `lmpsdat:"bond types"`
+		Masses    map[int]float64   `lmpsdat:"Masses"`
+		Atoms     map[int]*key.Atom `lmpsdat:"Atoms, full"`
+		Coeffs    map[int][]float64 `lmpsdat:"Bond Coeffs"`
+	}
+	v.AtomTypes, v.BondTypes = 2, 2
+	v.Masses = map[int]float64{1: 1, 2: 2}
+	v.Atoms = map[int]*key.Atom{1: {AtomType: 2}}
+	v.Coeffs = map[int][]float64{1: {1}, 2: {2}}
+	remaps, err := CompactTypes(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := remaps[key.NameBondTypes]; ok || v.BondTypes != 2 || len(v.Coeffs) != 2 {
+		t.Errorf("the bond types were compacted: %v, %d types, %v", remaps, v.BondTypes, v.Coeffs)
+	}
+	if v.AtomTypes != 1 || v.Atoms[1].AtomType != 1 || !reflect.DeepEqual(v.Masses, map[int]float64{1: 2}) {
+		t.Errorf("%d atom types, atom = %+v, Masses = %v, want 1 type of mass 2", v.AtomTypes, *v.Atoms[1], v.Masses)
+	}
+
+	s := fullSystem(t)
+	s.Atoms[7] = nil
+	if _, err := CompactTypes(s); err == nil {
+		t.Error("CompactTypes = nil with a nil atom")
+	}
+}
+
+func TestCompactTypesPairIJ(t *testing.T) {
+	var v struct {
+		AtomTypes int                  `lmpsdat:"atom types"`
+		Atoms     map[int]*key.Atom    `lmpsdat:"Atoms, full"`
+		PairIJ    map[[2]int][]float64 `lmpsdat:"PairIJ Coeffs"`
+	}
+	v.AtomTypes = 3
+	v.Atoms = map[int]*key.Atom{1: {AtomType: 1}, 2: {AtomType: 3}}
+	var err error
+	v.PairIJ, err = MixPairCoeffs(map[int][]float64{1: {0.1, 3}, 2: {0.2, 3}, 3: {0.4, 4}}, "arithmetic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CompactTypes(&v); err != nil {
+		t.Fatal(err)
+	}
+	want := map[[2]int][]float64{{1, 1}: {0.1, 3}, {1, 2}: v.PairIJ[[2]int{1, 2}], {2, 2}: {0.4, 4}}
+	if !reflect.DeepEqual(v.PairIJ, want) || v.PairIJ[[2]int{1, 2}][1] != 3.5 {
+		t.Errorf("PairIJ = %v, want the pairs of the types 1 and 3", v.PairIJ)
+	}
+}
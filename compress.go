@@ -0,0 +1,55 @@
+package lmpsdat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Magic numbers of the compressed formats detected by the Decoder.
+var (
+	magicGzip = []byte{0x1f, 0x8b}
+	magicZstd = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// errReader is a reader that always returns err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read(p []byte) (int, error) {
+	return 0, r.err
+}
+
+// sniff returns a reader that decompresses r if it begins with the magic
+// number of gzip or zstd. The bytes read to detect the format are restored with
+// an io.MultiReader.
+func sniff(r io.Reader) io.Reader {
+	magic := make([]byte, len(magicZstd))
+	n, err := io.ReadFull(r, magic)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return errReader{err}
+	}
+	magic = magic[:n]
+	r = io.MultiReader(bytes.NewReader(magic), r)
+
+	switch {
+	case bytes.HasPrefix(magic, magicGzip):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return errReader{fmt.Errorf("gzip.NewReader: %w", err)}
+		}
+		return gz
+	case bytes.HasPrefix(magic, magicZstd):
+		// a single goroutine decodes the stream synchronously.
+		zd, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return errReader{fmt.Errorf("zstd.NewReader: %w", err)}
+		}
+		return zd.IOReadCloser()
+	}
+	return r
+}
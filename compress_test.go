@@ -0,0 +1,77 @@
+package lmpsdat
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compress returns s compressed with the writer returned by newWriter.
+func compress(t testing.TB, s string, newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w, err := newWriter(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, s); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestDecodeCompressed(t *testing.T) {
+	full := readFile(t, "full.data")
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"plain", []byte(full)},
+		{"gzip", compress(t, full, func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		})},
+		{"zstd", compress(t, full, func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		})},
+	}
+	want := encodeString(t, fullSystem(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			if err := NewDecoder(bytes.NewReader(tt.in)).Decode(&s); err != nil {
+				t.Fatal(err)
+			}
+			if got := encodeString(t, &s); got != want {
+				t.Errorf("decoded = %s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeCompressedInvalid(t *testing.T) {
+	gz := compress(t, readFile(t, "full.data"), func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriter(w), nil
+	})
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"truncated gzip header", magicGzip},
+		{"truncated gzip", gz[:len(gz)/2]},
+		{"corrupted zstd", append(append([]byte(nil), magicZstd...), 0xff, 0xff, 0xff, 0xff)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			if err := NewDecoder(bytes.NewReader(tt.in)).Decode(&s); err == nil {
+				t.Error("Decode = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package lmpsdat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cancelReader calls cancel once n bytes were read from r.
+type cancelReader struct {
+	r      io.Reader
+	n      int
+	cancel func()
+}
+
+func (c *cancelReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if c.n -= n; c.n <= 0 && c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	return n, err
+}
+
+// bigFile returns a file of n atoms of atom style atomic.
+func bigFile(n int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "big\n\n%d atoms\n1 atom types\n\n0 10 xlo xhi\n0 10 ylo yhi\n0 10 zlo zhi\n\nMasses\n\n1 39.948\n\nAtoms\n\n", n)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "%d 1 %g %g %g\n", i, float64(i%10), float64(i%7), float64(i%3))
+	}
+	return b.String()
+}
+
+func TestDecodeContext(t *testing.T) {
+	in := bigFile(20000)
+	tests := []struct {
+		name string
+		ctx  func() (context.Context, func())
+		stop int // bytes read before cancel is called, 0 for never
+		want error
+	}{
+		{"background", func() (context.Context, func()) { return context.Background(), func() {} }, 0, nil},
+		{"canceled before", func() (context.Context, func()) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			return ctx, cancel
+		}, 0, context.Canceled},
+		{"canceled mid-decode", func() (context.Context, func()) {
+			return context.WithCancel(context.Background())
+		}, len(in) / 4, context.Canceled},
+		{"deadline exceeded", func() (context.Context, func()) {
+			return context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+		}, 0, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := tt.ctx()
+			defer cancel()
+			r := &cancelReader{r: strings.NewReader(in), n: len(in) + 1}
+			if tt.stop > 0 {
+				r.n, r.cancel = tt.stop, cancel
+			}
+			var a atomic
+			err := NewDecoder(r).DecodeContext(ctx, &a)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("DecodeContext = %v, want %v", err, tt.want)
+			}
+			if tt.want == nil && len(a.Atoms) != 20000 {
+				t.Errorf("len(Atoms) = %d, want 20000", len(a.Atoms))
+			}
+			if tt.stop > 0 && r.n < -len(in)/2 {
+				t.Errorf("the input was read until the end after the cancellation")
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// ConvertAtomStyle returns a copy of the atoms where only the columns of the
+// atom style to are kept. It is useful to encode atoms decoded with the atom
+// style from with another atom style.
+//
+// The columns of from that are not in to are set to zero (e.g. the molecule tag
+// and the charge when converting from AtomStyleFull to AtomStyleAtomic). The
+// columns of to that are not in from are set to their default value: 1 for the
+// molecule tag, 0 for the charge, the mass, the volume, the density, the
+// lineflag or triangleflag, and the electron columns (e.g. the spin). The image
+// flags are kept. An error is returned if the columns of from or to are unknown
+// (see key.Columns).
+func ConvertAtomStyle(atoms map[int]*key.Atom, from, to key.AtomStyle) (map[int]*key.Atom, error) {
+	fromCols, toCols := key.Columns(from), key.Columns(to)
+	if fromCols == nil {
+		return nil, fmt.Errorf("columns of atom style = %s are unknown", from.Name())
+	}
+	if toCols == nil {
+		return nil, fmt.Errorf("columns of atom style = %s are unknown", to.Name())
+	}
+
+	has := func(cols []key.Column, c key.Column) bool {
+		for _, col := range cols {
+			if col == c {
+				return true
+			}
+		}
+		return false
+	}
+
+	conv := make(map[int]*key.Atom, len(atoms))
+	for id, atom := range atoms {
+		if atom == nil {
+			return nil, fmt.Errorf("atom = %d is nil", id)
+		}
+		a := *atom
+		if !has(toCols, key.ColumnMolTag) {
+			a.MolTag = 0
+		} else if !has(fromCols, key.ColumnMolTag) {
+			a.MolTag = 1
+		}
+		for c, v := range map[key.Column]*float64{
+			key.ColumnQ:       &a.Q,
+			key.ColumnMass:    &a.Mass,
+			key.ColumnVolume:  &a.Volume,
+			key.ColumnDensity: &a.Density,
+			key.ColumnERadius: &a.ERadius,
+			key.ColumnCSRe:    &a.CSRe,
+			key.ColumnCSIm:    &a.CSIm,
+		} {
+			if !has(toCols, c) || !has(fromCols, c) {
+				*v = 0
+			}
+		}
+		lineFlag := has(fromCols, key.ColumnLineFlag) && has(toCols, key.ColumnLineFlag)
+		triFlag := has(fromCols, key.ColumnTriangleFlag) && has(toCols, key.ColumnTriangleFlag)
+		if !lineFlag && !triFlag {
+			a.Flag = 0
+		}
+		for c, v := range map[key.Column]*int{
+			key.ColumnSpin: &a.Spin,
+			key.ColumnETag: &a.ETag,
+		} {
+			if !has(toCols, c) || !has(fromCols, c) {
+				*v = 0
+			}
+		}
+		conv[id] = &a
+	}
+	return conv, nil
+}
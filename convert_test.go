@@ -0,0 +1,53 @@
+package lmpsdat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestConvertAtomStyle(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to key.AtomStyle
+		atom     key.Atom
+		want     key.Atom
+	}{
+		{"full to atomic", key.AtomStyleFull, key.AtomStyleAtomic,
+			key.Atom{MolTag: 2, AtomType: 1, Q: -0.8, X: 1, Y: 2, Z: 3, N: true, NX: 1},
+			key.Atom{AtomType: 1, X: 1, Y: 2, Z: 3, N: true, NX: 1}},
+		{"atomic to full", key.AtomStyleAtomic, key.AtomStyleFull,
+			key.Atom{AtomType: 1, X: 1, Y: 2, Z: 3},
+			key.Atom{MolTag: 1, AtomType: 1, X: 1, Y: 2, Z: 3}},
+		{"full to full", key.AtomStyleFull, key.AtomStyleFull,
+			key.Atom{MolTag: 2, AtomType: 1, Q: -0.8, X: 1, Y: 2, Z: 3},
+			key.Atom{MolTag: 2, AtomType: 1, Q: -0.8, X: 1, Y: 2, Z: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atom := tt.atom
+			conv, err := ConvertAtomStyle(map[int]*key.Atom{1: &atom}, tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *conv[1] != tt.want {
+				t.Errorf("atom = %+v, want %+v", *conv[1], tt.want)
+			}
+			if atom != tt.atom {
+				t.Errorf("original atom = %+v is modified", atom)
+			}
+		})
+	}
+
+	// the atoms converted are encoded with the target atom style.
+	s := fullSystem(t)
+	conv, err := ConvertAtomStyle(s.Atoms, key.AtomStyleFull, key.AtomStyleAtomic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := atomic{AtomsNbr: s.AtomsNbr, AtomTypes: s.AtomTypes, X: s.X, Y: s.Y, Z: s.Z, Masses: s.Masses, Atoms: conv}
+	if out := encodeString(t, &a); !strings.Contains(out, "\n2 2 1.8 1.5 1\n") {
+		t.Errorf("Encode = %q does not contain the atom 2 of atom style atomic", out)
+	}
+}
@@ -2,93 +2,575 @@ package lmpsdat
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"reflect"
+	"time"
 
 	"github.com/kpotier/lmpsdat/key"
 )
 
 // Decoder reads and decodes LAMMPS data values from an input stream.
 type Decoder struct {
-	r io.Reader
+	r          io.Reader
+	opts       key.Options
+	sections   map[key.Name]bool
+	hook       func(name key.Name, rows int, dur time.Duration)
+	bestEffort func(name key.Name, err error) // called for each table skipped if not nil
+	strict     bool
+	titles     int // number of lines of the title
+
+	scan    *bufio.Scanner
+	raw     []byte       // bytes of the last line read, including the line ending
+	pass    *Passthrough // retains the bytes read if not nil
+	peek    []byte       // first line of the next frame read by More
+	peekRaw []byte
+	ctx     context.Context // checked every ctxLines lines if not nil
+	lines   int
+	stats   *Stats // filled by decode if not nil
 }
 
-// NewDecoder returns a new decoder that reads from r.
+// ctxLines is the number of lines read between two verifications of the
+// context passed to DecodeContext.
+const ctxLines = 1024
+
+// FrameSeparator is the line separating two frames (i.e. two LAMMPS data files)
+// in a stream. The spaces surrounding it are ignored.
+const FrameSeparator = "---"
+
+// NewDecoder returns a new decoder that reads from r. The input compressed with
+// gzip or zstd is detected by its magic number and decompressed transparently.
 func NewDecoder(r io.Reader) *Decoder {
 	return &Decoder{
 		r: r,
 	}
 }
 
+// SetFortranExponent enables or disables the lenient parsing of the floats
+// having a D or d exponent (e.g. 1.5D+02) in the Atoms, Masses, and Coeffs
+// tables and in the bounds of the box. It is disabled by default.
+func (dec *Decoder) SetFortranExponent(b bool) {
+	dec.opts.FortranExponent = b
+}
+
+// SetExtraColumns enables or disables the decoding of the additional columns
+// that follow the atoms in the Bonds, Angles, and Dihedrals tables. These
+// columns are written back by the Encoder. It is disabled by default.
+func (dec *Decoder) SetExtraColumns(b bool) {
+	dec.opts.ExtraColumns = b
+}
+
+// SetDetectAtomStyle enables or disables the detection of the atom style of the
+// Atoms table when the tag of the field does not specify it. See
+// key.Options.DetectAtomStyle for the details of the detection. It is disabled
+// by default.
+func (dec *Decoder) SetDetectAtomStyle(b bool) {
+	dec.opts.DetectAtomStyle = b
+}
+
+// SetContiguousIDs enables or disables the requirement that the identifiers of
+// the Atoms, Bonds, Angles, and Dihedrals tables are between one and their
+// number. It is disabled by default. See key.Options.ContiguousIDs.
+func (dec *Decoder) SetContiguousIDs(b bool) {
+	dec.opts.ContiguousIDs = b
+}
+
+// SetCaseInsensitive enables or disables the matching of the headers of the
+// tables regardless of their case (e.g. "masses" for the Masses table). It is
+// disabled by default.
+func (dec *Decoder) SetCaseInsensitive(b bool) {
+	dec.opts.CaseInsensitive = b
+}
+
+// SetFlatZ enables or disables the acceptance of a box whose zlo is equal to
+// zhi, as written by some tools for 2D systems. It is disabled by default.
+func (dec *Decoder) SetFlatZ(b bool) {
+	dec.opts.FlatZ = b
+}
+
+// SetSplitBox enables or disables the decoding of the bounds of the box written
+// on separate lines, e.g. "xlo -5" and "xhi 5". See key.Options.SplitBox. It is
+// disabled by default.
+func (dec *Decoder) SetSplitBox(b bool) {
+	dec.opts.SplitBox = b
+}
+
+// SetWildcard enables or disables the expansion of the values of the Coeffs
+// tables whose type is "*" (e.g. "* 0.1 3.4") into every type. See
+// key.Options.Wildcard and key.Coeffs.Decode. It is disabled by default.
+func (dec *Decoder) SetWildcard(b bool) {
+	dec.opts.Wildcard = b
+}
+
+// SetStrictMasses enables or disables the rejection of the values of the Masses
+// table having fields after the mass that are not in a comment. See
+// key.Options.StrictMasses. It is disabled by default: these fields are
+// ignored.
+func (dec *Decoder) SetStrictMasses(b bool) {
+	dec.opts.StrictMasses = b
+}
+
+// SetStripTitleComment enables or disables the removal of the leading "#" of
+// the title (e.g. "# LAMMPS data file" is decoded as "LAMMPS data file"). See
+// key.Options.StripTitleComment. It is disabled by default: the title is kept
+// verbatim.
+func (dec *Decoder) SetStripTitleComment(b bool) {
+	dec.opts.StripTitleComment = b
+}
+
+// SetMixedImageFlags enables or disables the acceptance of an Atoms table where
+// only some atoms have the image flags. The missing image flags are set to 0 0
+// 0. It is disabled by default.
+func (dec *Decoder) SetMixedImageFlags(b bool) {
+	dec.opts.MixedImageFlags = b
+}
+
+// SetUniformColumns enables or disables the requirement that every value of the
+// Atoms, Links, and Coeffs tables has the same number of columns as the first
+// value of its table. See key.Options.UniformColumns. It is disabled by
+// default.
+func (dec *Decoder) SetUniformColumns(b bool) {
+	dec.opts.UniformColumns = b
+}
+
+// SetStyleCheck enables or disables the detection of an Atoms table whose
+// values suggest that the atom style does not match the file. Decode then
+// returns an error wrapping key.ErrStyleMismatch. See key.Options.StyleCheck.
+// It is disabled by default.
+func (dec *Decoder) SetStyleCheck(b bool) {
+	dec.opts.StyleCheck = b
+}
+
+// SetDelimiter sets the delimiter separating the fields of the values of the
+// tables (e.g. "|" for pipe-delimited rows). An empty delimiter, the default,
+// splits the fields around whitespace. See key.Options.Delimiter.
+func (dec *Decoder) SetDelimiter(d string) {
+	dec.opts.Delimiter = d
+}
+
+// SetFloat32 enables or disables the decoding of the Atoms table into
+// map[int]*key.AtomF32 instead of map[int]*key.Atom. The floats are stored as
+// float32 to reduce the memory used by very large systems. See key.AtomF32. It
+// is disabled by default.
+func (dec *Decoder) SetFloat32(b bool) {
+	dec.opts.Float32 = b
+}
+
+// SetTitleLines sets the number of lines of the title. Some generators write a
+// banner of several lines (e.g. a title and a date) before the blank line
+// separating it from the headers. The n first lines are then joined with a
+// newline into the value of NameTitle, and written back by the Encoder. They
+// are not decoded as headers even if the structure has no field tagged with
+// NameTitle. The default is one line.
+func (dec *Decoder) SetTitleLines(n int) {
+	dec.titles = n
+}
+
+// SetThousandsSeparator enables or disables the acceptance of the integers of
+// the Atoms and Links tables written with commas separating the groups of three
+// digits (e.g. 1,000). See key.Options.ThousandsSeparator. It is disabled by
+// default.
+func (dec *Decoder) SetThousandsSeparator(b bool) {
+	dec.opts.ThousandsSeparator = b
+}
+
+// SetStrictOrder enables or disables the requirement that the tables follow the
+// order documented by LAMMPS: the Masses and Coeffs tables, then the Atoms
+// table, then the Bonds, Angles, and Dihedrals tables. It is disabled by
+// default as LAMMPS accepts the tables in any order.
+func (dec *Decoder) SetStrictOrder(b bool) {
+	dec.strict = b
+}
+
+// SetSections restricts the decoding to the tables and headers whose Names are
+// given. The other tables are skipped and their corresponding fields are left
+// untouched. Calling SetSections without any Name removes the restriction.
+func (dec *Decoder) SetSections(names ...key.Name) {
+	if len(names) == 0 {
+		dec.sections = nil
+		return
+	}
+	dec.sections = make(map[key.Name]bool, len(names))
+	for _, n := range names {
+		dec.sections[n] = true
+	}
+}
+
+// SetBestEffort enables the best-effort decoding if fn is not nil: instead of
+// returning an error, a table that cannot be decoded (e.g. a corrupt Bonds
+// table) is skipped and the decoding resumes at the header of the next table.
+// fn is called with the Name of each table skipped and the error. The fields
+// of the tables skipped are left untouched and are not checked. The tables
+// skipped are also listed by DecodeWithStats.
+//
+// It is useful to recover the data of a partially corrupt file. The errors of
+// the headers and of the Check methods of the other tables are still returned.
+// A nil fn, the default, disables the best-effort decoding.
+func (dec *Decoder) SetBestEffort(fn func(name key.Name, err error)) {
+	dec.bestEffort = fn
+}
+
+// SetHook sets a function that is called each time a table or a header is
+// decoded. It receives the Name of the Key, the number of values (= 1 line)
+// decoded, and the time spent decoding them. It is useful to profile the
+// decoding of large files. A nil hook removes the previous one.
+func (dec *Decoder) SetHook(hook func(name key.Name, rows int, dur time.Duration)) {
+	dec.hook = hook
+}
+
 // Decode reads the next LAMMPS data-encoded value from its input and stores it
-// in the value pointed to by v.
+// in the value pointed to by v. If v has no field tagged with NameTitle, the
+// first line is analyzed as a header line instead of being skipped. As in
+// LAMMPS, the headers can appear in any order before the first table. The blank
+// lines are ignored, they do not end the headers. The tables that are not
+// supported (see key.ListUnsupportedSections) are skipped. An error is returned
+// if a table appears several times (e.g. two Masses tables).
+//
+// The input can contain several frames separated by a line equal to
+// FrameSeparator. Each call to Decode reads one frame, until the separator or
+// the end of the input, with new Keys: nothing is kept from the previous frame.
+// Use the More method to know if there is another frame.
 func (dec *Decoder) Decode(v interface{}) error {
-	ptr := reflect.TypeOf(v)
-	if ptr.Kind() != reflect.Ptr {
-		return fmt.Errorf("interface passed is not a pointer")
+	return dec.DecodeContext(context.Background(), v)
+}
+
+// DecodeContext works like Decode but the decoding stops if ctx is done: the
+// context is verified every 1024 lines, and ctx.Err() is returned (e.g.
+// context.Canceled). As the input is partially read, the Decoder must not be
+// used afterwards.
+func (dec *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
 	}
+	dec.ctx = ctx
+	defer func() { dec.ctx = nil }()
+	err := dec.decode(v, false, nil)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// DecodeInto works like Decode but the maps of v that are not nil are reused:
+// the decoded values are added to them instead of replacing them. An error is
+// returned if an identifier (e.g. atom or bond identifier) already exists in a
+// map. The fields that are not maps are replaced.
+func (dec *Decoder) DecodeInto(v interface{}) error {
+	return dec.decode(v, true, nil)
+}
 
-	val := reflect.ValueOf(v).Elem()
-	typ := ptr.Elem()
-	if typ.Kind() != reflect.Struct {
-		return fmt.Errorf("interface passed is not a pointer of a struct")
+// decode stores the decoded values into v. If merge is true, the values are
+// added to the maps of v that are not nil. If p is not nil, the original bytes
+// of each table and header are retained in p.
+func (dec *Decoder) decode(v interface{}, merge bool, p *Passthrough) error {
+	val, err := structOf(v)
+	if err != nil {
+		return err
 	}
 
-	nFields, keys := createNames(typ)
+	nFields, keys := createNames(val.Type(), dec.sections)
+	key.SetOptions(keys, &dec.opts)
 	kHead, kBody := headBody(keys)
 
 	inHeader := true
-	r := bufio.NewScanner(dec.r)
+	decoded := make(map[key.Name]bool) // tables already decoded
+	var order sectionOrder
+	r := dec.scanner()
+	dec.pass = p
+	defer func() { dec.pass = nil }()
 
-	if ok := r.Scan(); !ok {
+	title, ok := dec.firstLine()
+	if !ok {
 		if r.Err() != nil {
 			return fmt.Errorf("r.Scan title: %w", r.Err())
 		}
 		return nil
 	}
+	if dec.titles > 1 {
+		title = append([]byte(nil), title...) // title is overwritten by r.Scan
+		for i := 1; i < dec.titles && r.Scan(); i++ {
+			title = append(append(title, '\n'), r.Bytes()...)
+		}
+	}
 	if k, ok := keys[key.NameTitle]; ok {
-		if err := k.Set(r.Text()); err != nil {
-			return fmt.Errorf("k.Set for Key = %s: %w", key.NameTitle, err)
+		if err := k.Decode(title, r); err != nil {
+			return fmt.Errorf("k.Decode for Key = %s: %w", key.NameTitle, err)
+		}
+		p.add(key.NameTitle, 0)
+	} else if dec.titles <= 1 && !isComment(title) {
+		// the first line is not consumed as a title if the title is not
+		// requested. Some generators write the headers from the first line.
+		// A banner of several lines is never decoded as headers.
+		n, ok, err := dec.keyDecode(title, kHead, r)
+		if err != nil {
+			return err
+		} else if ok {
+			p.add(n, 0)
 		}
 	}
 
-	for r.Scan() {
+	skipped := make(map[key.Name]bool) // tables skipped by the best-effort decoding
+	resync, again := false, false
+	for again || r.Scan() {
+		again = false
 		s := r.Bytes()
+		start := p.lineStart()
+		if isSeparator(s) {
+			p.unread()
+			break
+		}
+		if len(bytes.TrimSpace(s)) == 0 || isComment(s) {
+			continue // blank lines may appear anywhere, even between the headers
+		}
+		if resync {
+			_, known := key.IsSection(s, &dec.opts)
+			_, unsupported := key.IsUnsupportedSection(s, &dec.opts)
+			if !known && !unsupported {
+				continue // the values of the table skipped
+			}
+			resync = false
+		}
 		if inHeader {
-			ok, err := keyDecode(s, kHead, r)
+			n, ok, err := dec.keyDecode(s, kHead, r)
 			if err != nil {
 				return err
 			} else if ok {
+				p.add(n, start)
 				continue
 			}
 		}
-		ok, err := keyDecode(s, kBody, r)
-		if err != nil {
+		if n, ok := key.IsSection(s, &dec.opts); ok && dec.strict {
+			if err := order.add(n); err != nil {
+				return err
+			}
+		}
+		n, ok, err := dec.keyDecode(s, kBody, r)
+		if err != nil && (dec.bestEffort == nil || r.Err() != nil) {
 			return err
+		} else if err != nil {
+			// the table is skipped until the header of the next table,
+			// which may be the line that made the decoding fail.
+			dec.bestEffort(n, err)
+			dec.stats.skip(n)
+			skipped[n] = true
+			if n == key.NameAtoms {
+				// the links cannot be verified with the atoms skipped.
+				for _, k := range keys {
+					if _, ok := k.(*key.Links); ok {
+						k.SetKeys((*key.Atoms)(nil))
+					}
+				}
+			}
+			delete(kBody, n)
+			inHeader = false
+			resync, again = true, true
+			continue
 		} else if ok {
+			p.add(n, start)
+			decoded[n] = true
 			inHeader = false
+			continue
+		}
+		if n, ok := key.IsSection(s, &dec.opts); ok && decoded[n] {
+			return fmt.Errorf("duplicate section = %s: the table appears several times in the file", n)
+		}
+		_, known := key.IsSection(s, &dec.opts)
+		_, unsupported := key.IsUnsupportedSection(s, &dec.opts)
+		if (known && dec.sections != nil) || unsupported {
+			inHeader = false
+			sep, err := skipSection(r)
+			if err != nil {
+				return err
+			} else if sep {
+				p.unread()
+				break
+			}
 		}
 	}
 	if r.Err() != nil {
 		return fmt.Errorf("r.Scan: %w", r.Err())
 	}
 
-	for _, k := range keys {
-		err := k.Check()
-		if err != nil {
-			return fmt.Errorf("k.Check for Key = %s: %w", k.Name(), err)
+	// the Links are checked last as they reference the atoms.
+	for _, links := range []bool{false, true} {
+		for _, k := range keys {
+			if _, ok := k.(*key.Links); ok != links || skipped[k.Name()] {
+				continue
+			}
+			err := k.Check()
+			if err != nil {
+				return fmt.Errorf("k.Check for Key = %s: %w", k.Name(), err)
+			}
 		}
 	}
+	if err := p.finish(keys); err != nil {
+		return err
+	}
+	dec.stats.finish(keys)
 
 	for n, f := range nFields {
+		if skipped[n] {
+			continue // the field is left untouched
+		}
 		v := reflect.ValueOf(keys[n].Get())
-		field := val.Field(f)
-		if !field.Type().AssignableTo(v.Type()) {
-			return fmt.Errorf("Key = %s has type = %s that is not assignable to type = %s", n, v.Type(), field.Type())
+		field := val.FieldByIndex(f)
+		if merge && field.Kind() == reflect.Map && !field.IsNil() && v.Type().AssignableTo(field.Type()) {
+			if err := mergeMap(field, v); err != nil {
+				return fmt.Errorf("mergeMap for Key = %s: %w", n, err)
+			}
+			continue
+		}
+		if err := assign(field, v); err != nil {
+			return fmt.Errorf("assign for Key = %s: %w", n, err)
 		}
-		field.Set(v)
 	}
 
 	return nil
 }
+
+// sectionOrder verifies that the tables follow the order documented by LAMMPS.
+type sectionOrder struct {
+	last key.Name
+	rank int
+}
+
+// add records the table whose Name is n. It returns an error if the table must
+// be before the previous one.
+func (o *sectionOrder) add(n key.Name) error {
+	rank := 0 // Masses and Coeffs tables
+	switch n {
+	case key.NameAtoms:
+		rank = 1
+	case key.NameBonds, key.NameAngles, key.NameDihedrals:
+		rank = 2
+	}
+	if o.last != "" && rank < o.rank {
+		return fmt.Errorf("table = %s is after table = %s: it must be before it", n, o.last)
+	}
+	o.last, o.rank = n, rank
+	return nil
+}
+
+// scanner returns the scanner reading the input. It is created on the first
+// call and then reused for each frame.
+func (dec *Decoder) scanner() *bufio.Scanner {
+	if dec.scan == nil {
+		dec.scan = bufio.NewScanner(sniff(dec.r))
+		dec.scan.Split(dec.split)
+	}
+	return dec.scan
+}
+
+// split works like bufio.ScanLines but retains the bytes read, including the
+// line ending, in dec.raw and in the Passthrough being decoded. It stops the
+// scan with the error of the context passed to DecodeContext once it is done.
+func (dec *Decoder) split(data []byte, atEOF bool) (int, []byte, error) {
+	advance, token, err := bufio.ScanLines(data, atEOF)
+	if advance > 0 {
+		if dec.ctx != nil {
+			if dec.lines++; dec.lines%ctxLines == 0 && dec.ctx.Err() != nil {
+				return 0, nil, dec.ctx.Err()
+			}
+		}
+		dec.raw = data[:advance]
+		dec.pass.record(dec.raw)
+	}
+	return advance, token, err
+}
+
+// firstLine returns the first line of the frame: the line read by the More
+// method or the next line. It returns false if there is no line.
+func (dec *Decoder) firstLine() ([]byte, bool) {
+	if dec.peek != nil {
+		s := dec.peek
+		dec.pass.record(dec.peekRaw)
+		dec.peek, dec.peekRaw = nil, nil
+		return s, true
+	}
+	if !dec.scanner().Scan() {
+		return nil, false
+	}
+	return dec.scanner().Bytes(), true
+}
+
+// More reports whether there is another frame to decode in the input. The
+// blank lines preceding the frame are skipped: the title of a frame that
+// follows a FrameSeparator must therefore not be blank.
+func (dec *Decoder) More() bool {
+	if dec.peek != nil {
+		return true
+	}
+	r := dec.scanner()
+	for r.Scan() {
+		if len(bytes.TrimSpace(r.Bytes())) == 0 {
+			continue
+		}
+		dec.peek = append([]byte(nil), r.Bytes()...)
+		dec.peekRaw = append([]byte(nil), dec.raw...)
+		return true
+	}
+	return false
+}
+
+// isSeparator returns true if the line s is a FrameSeparator.
+func isSeparator(s []byte) bool {
+	return string(bytes.TrimSpace(s)) == FrameSeparator
+}
+
+// isComment returns true if the first non-space byte of s is "#". LAMMPS
+// ignores everything following "#", so such a line is read as a blank line
+// (e.g. the comments written by Encoder.SetComment).
+func isComment(s []byte) bool {
+	s = bytes.TrimSpace(s)
+	return len(s) > 0 && s[0] == '#'
+}
+
+// mergeMap adds the values of the map src to the map dst. It returns an error
+// without modifying dst if a key of src already exists in dst.
+func mergeMap(dst, src reflect.Value) error {
+	iter := src.MapRange()
+	for iter.Next() {
+		if dst.MapIndex(iter.Key()).IsValid() {
+			return fmt.Errorf("identifier = %v already exists", iter.Key())
+		}
+	}
+	iter = src.MapRange()
+	for iter.Next() {
+		dst.SetMapIndex(iter.Key(), iter.Value())
+	}
+	return nil
+}
+
+// partialer is implemented by the Keys that can be decoded from several lines
+// (e.g. key.Box with key.Options.SplitBox).
+type partialer interface {
+	Partial() bool
+}
+
+// keyDecode calls the Keyword method for several Keys. If a Keyword returns
+// true, the Decode method will be called and this function will return the Name
+// of the Key and true. The hook set with SetHook is called after the Decode
+// method. The Key is then removed from keys unless it is partially decoded.
+func (dec *Decoder) keyDecode(s []byte, keys map[key.Name]key.Key, r *bufio.Scanner) (key.Name, bool, error) {
+	for n, k := range keys {
+		if k.Keyword(s) {
+			start := time.Now()
+			err := k.Decode(s, r)
+			if err != nil {
+				return n, true, fmt.Errorf("k.Decode for Key = %s: %w", k.Name(), err)
+			}
+			if dec.hook != nil {
+				dec.hook(n, rowsOf(k), time.Since(start))
+			}
+			dec.stats.add(n, k)
+			if p, ok := k.(partialer); ok && p.Partial() {
+				return n, true, nil // the Key expects another line
+			}
+			delete(keys, n)
+			return n, true, nil
+		}
+	}
+	return "", false, nil
+}
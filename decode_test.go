@@ -0,0 +1,938 @@
+package lmpsdat
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// cut returns s up to the end of the first line containing line.
+func cut(t testing.TB, s, line string) string {
+	t.Helper()
+	idx := strings.Index(s, line)
+	if idx == -1 {
+		t.Fatalf("line = %q not found", line)
+	}
+	return s[:idx+len(line)] + "\n"
+}
+
+func TestDecodeTruncated(t *testing.T) {
+	full := readFile(t, "full.data")
+	tests := []struct {
+		name    string
+		in      string
+		section key.Name
+	}{
+		{"atoms in the middle", cut(t, full, "3 1 2 0.4238 0.2 1.5 1"), key.NameAtoms},
+		{"atoms after the header", cut(t, full, "Atoms"), key.NameAtoms},
+		{"atoms after the blank line", cut(t, full, "Atoms") + "\n", key.NameAtoms},
+		{"masses", cut(t, full, "1 15.9994"), key.NameMasses},
+		{"pair coeffs", cut(t, full, "1 0.1553 3.166"), key.NamePairCoeffs},
+		{"bonds", cut(t, full, "2 1 1 3"), key.NameBonds},
+		{"angles", cut(t, full, "1 1 2 1 3"), key.NameAngles},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			err := decodeString(tt.in, &s)
+			if !errors.Is(err, key.ErrTruncated) {
+				t.Fatalf("err = %v, want ErrTruncated", err)
+			}
+			if !strings.Contains(err.Error(), string(tt.section)) {
+				t.Errorf("err = %v does not name the table = %s", err, tt.section)
+			}
+		})
+	}
+}
+
+func TestDecodeFortranExponent(t *testing.T) {
+	const in = `title
+
+1 atoms
+1 atom types
+
+0 10 xlo xhi
+0 10 ylo yhi
+0 10 zlo zhi
+
+Masses
+
+1 1.5D+01
+
+Atoms
+
+1 1 1.5D+02 -2.5d-1 1E0
+`
+	var a atomic
+	if err := decodeString(in, &a, func(dec *Decoder) { dec.SetFortranExponent(true) }); err != nil {
+		t.Fatal(err)
+	}
+	if got := *a.Atoms[1]; got.X != 150 || got.Y != -0.25 || got.Z != 1 {
+		t.Errorf("atom = %+v, want X = 150, Y = -0.25, Z = 1", got)
+	}
+	if a.Masses[1] != 15 {
+		t.Errorf("mass = %g, want 15", a.Masses[1])
+	}
+
+	var pe *key.ParseError
+	if err := decodeString(in, &a); !errors.As(err, &pe) {
+		t.Errorf("err = %v without SetFortranExponent, want a ParseError", err)
+	}
+}
+
+func TestDecodeSections(t *testing.T) {
+	full := readFile(t, "full.data")
+	tests := []struct {
+		name     string
+		sections []key.Name
+		check    func(s *system) bool
+	}{
+		{"atoms", []key.Name{key.NameAtoms}, func(s *system) bool {
+			return len(s.Atoms) == 6 && s.Bonds == nil && s.Masses == nil && s.PairCoeffs == nil
+		}},
+		{"bonds and masses", []key.Name{key.NameBonds, key.NameMasses}, func(s *system) bool {
+			return len(s.Bonds) == 4 && len(s.Masses) == 2 && s.Atoms == nil && s.Angles == nil
+		}},
+		{"headers", []key.Name{key.NameAtomsNbr, key.NameBondTypes}, func(s *system) bool {
+			return s.AtomsNbr == 6 && s.BondTypes == 1 && s.AnglesNbr == 0 && s.Atoms == nil
+		}},
+		{"every section", nil, func(s *system) bool {
+			return len(s.Atoms) == 6 && len(s.Bonds) == 4 && len(s.Angles) == 2
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			err := decodeString(full, &s, func(dec *Decoder) { dec.SetSections(tt.sections...) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(&s) {
+				t.Errorf("unexpected fields = %+v", s)
+			}
+		})
+	}
+}
+
+func TestDecodeInto(t *testing.T) {
+	full := readFile(t, "full.data")
+	extra := &key.Atom{MolTag: 3, AtomType: 1}
+
+	s := system{Atoms: map[int]*key.Atom{100: extra}}
+	if err := NewDecoder(strings.NewReader(full)).DecodeInto(&s); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Atoms) != 7 || s.Atoms[100] != extra || s.Atoms[1] == nil {
+		t.Errorf("Atoms = %v, want the 6 atoms decoded and atom 100", s.Atoms)
+	}
+	if len(s.Masses) != 2 || len(s.Bonds) != 4 {
+		t.Errorf("the nil maps are not replaced: %v, %v", s.Masses, s.Bonds)
+	}
+
+	s = system{Atoms: map[int]*key.Atom{1: extra}}
+	if err := NewDecoder(strings.NewReader(full)).DecodeInto(&s); err == nil {
+		t.Error("DecodeInto = nil with an identifier that already exists")
+	}
+	if len(s.Atoms) != 1 || s.Atoms[1] != extra {
+		t.Errorf("Atoms = %v is modified despite the error", s.Atoms)
+	}
+}
+
+func TestDecodeTitle(t *testing.T) {
+	full := readFile(t, "full.data")
+	body := strings.SplitN(full, "\n", 2)[1]
+	tests := []struct {
+		name  string
+		in    string
+		title string
+	}{
+		{"title", full, "LAMMPS data file"},
+		{"blank title", "\n" + body, ""},
+		{"spaces", "   \n" + body, "   "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			if err := decodeString(tt.in, &s); err != nil {
+				t.Fatal(err)
+			}
+			if s.Title != tt.title || len(s.Atoms) != 6 {
+				t.Errorf("Title = %q with %d atoms, want %q with 6 atoms", s.Title, len(s.Atoms), tt.title)
+			}
+		})
+	}
+}
+
+func TestDecodeTitleLines(t *testing.T) {
+	const banner = "LAMMPS data file\n# generated on 2024-01-01\n"
+	in := strings.Replace(readFile(t, "full.data"), "LAMMPS data file\n", banner, 1)
+	tests := []struct {
+		name  string
+		lines int
+		title string
+	}{
+		{"one line", 1, "LAMMPS data file"},
+		{"two lines", 2, "LAMMPS data file\n# generated on 2024-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			err := decodeString(in, &s, func(dec *Decoder) { dec.SetTitleLines(tt.lines) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Title != tt.title || len(s.Atoms) != 6 {
+				t.Errorf("Title = %q with %d atoms, want %q with 6 atoms", s.Title, len(s.Atoms), tt.title)
+			}
+		})
+	}
+
+	// the banner is written back as is.
+	var s system
+	if err := decodeString(in, &s, func(dec *Decoder) { dec.SetTitleLines(2) }); err != nil {
+		t.Fatal(err)
+	}
+	if out := encodeString(t, &s); !strings.HasPrefix(out, banner+"\n") {
+		t.Errorf("Encode = %q, want the prefix %q", out, banner+"\n")
+	}
+
+	// without a Title field, the banner is not decoded as headers.
+	var v struct {
+		AtomsNbr int               `lmpsdat:"atoms"`
+		Types    int               `lmpsdat:"atom types"`
+		Atoms    map[int]*key.Atom `lmpsdat:"Atoms, full"`
+	}
+	if err := decodeString("2 atoms\n1 atom types\n\n1 atoms\n2 atom types\n\n0 1 xlo xhi\n0 1 ylo yhi\n0 1 zlo zhi\n\nAtoms\n\n1 1 1 0 0 0 0\n", &v, func(dec *Decoder) { dec.SetTitleLines(2) }); err != nil {
+		t.Fatal(err)
+	}
+	if v.AtomsNbr != 1 || v.Types != 2 {
+		t.Errorf("%d atoms and %d types, want 1 and 2: the banner was decoded", v.AtomsNbr, v.Types)
+	}
+}
+
+func TestDecodeWithoutTitle(t *testing.T) {
+	// the first line is decoded as a header if there is no field tagged with
+	// NameTitle.
+	in := strings.TrimLeft(strings.SplitN(readFile(t, "full.data"), "\n", 2)[1], "\n")
+	var v struct {
+		AtomsNbr int               `lmpsdat:"atoms"`
+		Types    int               `lmpsdat:"atom types"`
+		Atoms    map[int]*key.Atom `lmpsdat:"Atoms, full"`
+	}
+	if err := decodeString(in, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.AtomsNbr != 6 || v.Types != 2 || len(v.Atoms) != 6 {
+		t.Errorf("%d atoms, %d types, %d atoms decoded, want 6, 2, 6", v.AtomsNbr, v.Types, len(v.Atoms))
+	}
+}
+
+func TestDecodeClass2(t *testing.T) {
+	type class2 struct {
+		system
+		ImpropersNbr     int               `lmpsdat:"impropers"`
+		ImproperTypes    int               `lmpsdat:"improper types"`
+		BondBondCoeffs   map[int][]float64 `lmpsdat:"BondBond Coeffs"`
+		BondAngleCoeffs  map[int][]float64 `lmpsdat:"BondAngle Coeffs"`
+		AngleAngleCoeffs map[int][]float64 `lmpsdat:"AngleAngle Coeffs"`
+	}
+	full := readFile(t, "full.data")
+	in := strings.Replace(full, "1 angle types\n", "1 angle types\n0 impropers\n2 improper types\n", 1) +
+		"\nBondBond Coeffs\n\n1 3.3 1.1 1.1\n" +
+		"\nBondAngle Coeffs\n\n1 4.4 4.4 1.1 1.1\n" +
+		"\nAngleAngle Coeffs\n\n1 0 0 0 110 110 110\n2 1 1 1 120 120 120\n"
+
+	var v class2
+	if err := decodeString(in, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ImproperTypes != 2 || len(v.AngleAngleCoeffs) != 2 || len(v.BondBondCoeffs) != 1 || len(v.BondAngleCoeffs[1]) != 4 {
+		t.Errorf("decoded = %+v", v)
+	}
+
+	out := encodeString(t, &v)
+	for _, want := range []string{"\n0 impropers\n", "\n2 improper types\n", "\nBondBond Coeffs\n\n1 3.3 1.1 1.1\n", "\nAngleAngle Coeffs\n\n1 0 0 0 110 110 110\n2 1 1 1 120 120 120\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Encode = %q does not contain %q", out, want)
+		}
+	}
+
+	// the cross-terms are keyed by the type of their parent interaction.
+	bad := strings.Replace(in, "2 improper types", "3 improper types", 1)
+	err := decodeString(bad, &v)
+	if !errors.Is(err, key.ErrTruncated) || !strings.Contains(err.Error(), string(key.NameAngleAngleCoeffs)) {
+		t.Errorf("Decode = %v, want %s truncated", err, key.NameAngleAngleCoeffs)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	full := readFile(t, "full.data")
+	t.Run("parse error", func(t *testing.T) {
+		tests := []struct {
+			name  string
+			old   string
+			new   string
+			want  key.ParseError
+			error string
+		}{
+			{"atoms", "1.8 1.5 1", "1.8 x 1", key.ParseError{Section: key.NameAtoms, Line: 2, Field: "Y", Func: "strconv.ParseFloat"},
+				`row = 2: strconv.ParseFloat Y: strconv.ParseFloat: parsing "x": invalid syntax`},
+			{"masses", "2 1.008", "2 1.0.08", key.ParseError{Section: key.NameMasses, Line: 2, Func: "strconv.ParseFloat"},
+				`row = 2: strconv.ParseFloat: strconv.ParseFloat: parsing "1.0.08": invalid syntax`},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				var s system
+				err := decodeString(strings.Replace(full, tt.old, tt.new, 1), &s)
+				var pe *key.ParseError
+				if !errors.As(err, &pe) {
+					t.Fatalf("err = %v, want a ParseError", err)
+				}
+				if pe.Section != tt.want.Section || pe.Line != tt.want.Line || pe.Field != tt.want.Field || pe.Func != tt.want.Func {
+					t.Errorf("ParseError = %+v, want %+v", *pe, tt.want)
+				}
+				if pe.Error() != tt.error {
+					t.Errorf("Error = %q, want %q", pe.Error(), tt.error)
+				}
+			})
+		}
+	})
+
+	t.Run("count mismatch", func(t *testing.T) {
+		// the duplicated identifier overwrites the first atom.
+		var s system
+		err := decodeString(strings.Replace(full, "\n2 1 2 ", "\n1 1 2 ", 1), &s)
+		var ce *key.CountMismatchError
+		if !errors.As(err, &ce) {
+			t.Fatalf("err = %v, want a CountMismatchError", err)
+		}
+		if ce.Section != key.NameAtoms || ce.Got != 5 || ce.Want != 6 {
+			t.Errorf("CountMismatchError = %+v, want Atoms, 5, 6", *ce)
+		}
+		const want = "number of assigned atoms = 5 is not equal to the number of expected atoms = 6"
+		if ce.Error() != want {
+			t.Errorf("Error = %q, want %q", ce.Error(), want)
+		}
+	})
+}
+
+func TestDecodeSparseAtomIDs(t *testing.T) {
+	// the atom 6 is renumbered 60 as LAMMPS accepts any unique identifier.
+	sparse := strings.NewReplacer("\n6 2 2 ", "\n60 2 2 ", "4 1 4 6\n", "4 1 4 60\n", "5 4 6\n", "5 4 60\n").Replace(readFile(t, "full.data"))
+	tests := []struct {
+		name       string
+		in         string
+		contiguous bool
+		wantErr    string
+	}{
+		{"sparse", sparse, false, ""},
+		{"sparse with ContiguousIDs", sparse, true, "identifier = 60 is invalid"},
+		{"missing atom", strings.Replace(sparse, "4 1 4 60\n", "4 1 4 6\n", 1), false, "atom = 6 of id = 4 does not exist"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			err := decodeString(tt.in, &s, func(dec *Decoder) { dec.SetContiguousIDs(tt.contiguous) })
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s.Atoms[60] == nil || s.Bonds[4].Atoms()[1] != 60 || s.Angles[2].Atoms()[2] != 60 {
+					t.Errorf("atoms = %v, bond 4 = %v, angle 2 = %v", s.Atoms, s.Bonds[4].Atoms(), s.Angles[2].Atoms())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeHook(t *testing.T) {
+	calls := make(map[key.Name]int)
+	rows := make(map[key.Name]int)
+	hook := func(name key.Name, n int, dur time.Duration) {
+		calls[name]++
+		rows[name] = n
+		if dur < 0 {
+			t.Errorf("duration of %s = %v is negative", name, dur)
+		}
+	}
+	var s system
+	if err := decodeString(readFile(t, "full.data"), &s, func(dec *Decoder) { dec.SetHook(hook) }); err != nil {
+		t.Fatal(err)
+	}
+	want := map[key.Name]int{
+		key.NameAtomsNbr: 1, key.NameAtomTypes: 1, key.NameBondsNbr: 1, key.NameBondTypes: 1,
+		key.NameAnglesNbr: 1, key.NameAngleTypes: 1, key.NameBoxX: 1, key.NameBoxY: 1, key.NameBoxZ: 1,
+		key.NameMasses: 2, key.NamePairCoeffs: 2, key.NameBondCoeffs: 1, key.NameAngleCoeffs: 1,
+		key.NameAtoms: 6, key.NameBonds: 4, key.NameAngles: 2,
+	}
+	for name, n := range want {
+		if calls[name] != 1 {
+			t.Errorf("hook called %d times for %s, want once", calls[name], name)
+		}
+		if rows[name] != n {
+			t.Errorf("rows of %s = %d, want %d", name, rows[name], n)
+		}
+	}
+	if len(calls) != len(want) {
+		t.Errorf("hook called for %v, want %d Keys", calls, len(want))
+	}
+}
+
+func TestDecodeFrames(t *testing.T) {
+	full := readFile(t, "full.data")
+	second := strings.Replace(full, "LAMMPS data file", "second frame", 1)
+	second = strings.Replace(second, "6 2 2 0.4238 4.2 5.5 5", "6 2 2 0.4238 4.2 5.5 6", 1)
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"separator", full + FrameSeparator + "\n" + second},
+		{"separator with spaces", full + "\n  " + FrameSeparator + "  \n\n" + second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.in))
+			var frames []system
+			for dec.More() {
+				var s system
+				if err := dec.Decode(&s); err != nil {
+					t.Fatalf("frame = %d: %v", len(frames)+1, err)
+				}
+				frames = append(frames, s)
+			}
+			if len(frames) != 2 {
+				t.Fatalf("%d frames decoded, want 2", len(frames))
+			}
+			if frames[0].Title != "LAMMPS data file" || frames[0].Atoms[6].Z != 5 {
+				t.Errorf("first frame = %q with Z = %g", frames[0].Title, frames[0].Atoms[6].Z)
+			}
+			if frames[1].Title != "second frame" || frames[1].Atoms[6].Z != 6 || len(frames[1].Bonds) != 4 {
+				t.Errorf("second frame = %q with Z = %g", frames[1].Title, frames[1].Atoms[6].Z)
+			}
+		})
+	}
+}
+
+func TestDecodeCaseInsensitive(t *testing.T) {
+	full := readFile(t, "full.data")
+	lower := strings.NewReplacer("Masses\n", "masses\n", "Bonds\n", "BONDS\n", "Pair Coeffs\n", "pair coeffs\n").Replace(full)
+	masses := "\nmasses\n\n1 15.9994\n2 1.008\n"
+	withoutMasses := strings.Replace(lower, masses, "", 1)
+	tests := []struct {
+		name    string
+		in      string
+		opts    func(dec *Decoder)
+		wantErr string
+		check   func(s *system) bool
+	}{
+		{"lowercase", lower, nil, "", func(s *system) bool {
+			return len(s.Masses) == 2 && len(s.PairCoeffs) == 2 && len(s.Bonds) == 4
+		}},
+		{"duplicate", lower + masses, nil, "duplicate section = Masses", nil},
+		{"strict order", withoutMasses + masses, func(dec *Decoder) { dec.SetStrictOrder(true) },
+			"table = Masses is after table = Angles", nil},
+		{"excluded table", lower, func(dec *Decoder) { dec.SetSections(key.NameAtoms, key.NameAngles) }, "", func(s *system) bool {
+			return len(s.Atoms) == 6 && len(s.Angles) == 2 && s.Bonds == nil && s.Masses == nil
+		}},
+		{"resync", strings.Replace(lower, "1.8 1.5 1", "1.8 x 1", 1), func(dec *Decoder) {
+			dec.SetBestEffort(func(key.Name, error) {})
+		}, "", func(s *system) bool {
+			return s.Atoms == nil && len(s.Bonds) == 4 && len(s.Angles) == 2
+		}},
+		{"impropers", strings.Replace(lower, "\nAngles\n", "\nimpropers\n\n1 1 1 2 3 4\n\nAngles\n", 1), nil, "", func(s *system) bool {
+			return len(s.Bonds) == 4 && len(s.Angles) == 2
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			err := decodeString(tt.in, &s, func(dec *Decoder) {
+				dec.SetCaseInsensitive(true)
+				if tt.opts != nil {
+					tt.opts(dec)
+				}
+			})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.check(&s) {
+				t.Errorf("unexpected fields = %+v", s)
+			}
+		})
+	}
+
+	var s system
+	if err := decodeString(lower, &s); err == nil {
+		t.Error("Decode = nil with the lowercase headers without SetCaseInsensitive")
+	}
+}
+
+func TestDecodeHeaderBlankLines(t *testing.T) {
+	full := readFile(t, "full.data")
+	tests := []struct {
+		name string
+		old  string
+		new  string
+	}{
+		{"between the counts", "6 atoms\n2 atom types\n", "6 atoms\n\n\n2 atom types\n  \n"},
+		{"between the box bounds", "0 10 ylo yhi\n", "\n0 10 ylo yhi\n\t\n"},
+		{"trailing whitespace", "4 bonds\n", "4 bonds \t\n\n"},
+		{"after the title", "LAMMPS data file\n", "LAMMPS data file\n\n\n\n"},
+	}
+	want := fullSystem(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			if err := decodeString(strings.Replace(full, tt.old, tt.new, 1), &s); err != nil {
+				t.Fatal(err)
+			}
+			if encodeString(t, &s) != encodeString(t, want) {
+				t.Errorf("decoded = %+v, want %+v", s, *want)
+			}
+		})
+	}
+}
+
+func TestDecodeZeroTypes(t *testing.T) {
+	const in = `title
+
+0 atoms
+0 atom types
+
+0 10 xlo xhi
+0 10 ylo yhi
+0 10 zlo zhi
+
+Masses
+
+1 12.011
+`
+	var a atomic
+	err := decodeString(in, &a)
+	if err == nil || !strings.Contains(err.Error(), "integer = 0 is lower than the minimum = 1") {
+		t.Errorf("Decode = %v, want an error as the Masses table requires an atom type", err)
+	}
+
+	// without the Masses table, zero atom types is accepted.
+	if err := decodeString(strings.Split(in, "Masses")[0], &a); err != nil {
+		t.Errorf("Decode = %v without the Masses table", err)
+	}
+}
+
+func TestDecodeHeaderOrder(t *testing.T) {
+	full := readFile(t, "full.data")
+	headers := "6 atoms\n2 atom types\n4 bonds\n1 bond types\n2 angles\n1 angle types\n\n0 10 xlo xhi\n0 10 ylo yhi\n0 10 zlo zhi\n"
+	tests := []struct {
+		name    string
+		headers string
+	}{
+		{"types before counts", "2 atom types\n1 bond types\n1 angle types\n6 atoms\n4 bonds\n2 angles\n\n0 10 xlo xhi\n0 10 ylo yhi\n0 10 zlo zhi\n"},
+		{"box before counts", "0 10 xlo xhi\n0 10 ylo yhi\n0 10 zlo zhi\n\n6 atoms\n2 atom types\n4 bonds\n1 bond types\n2 angles\n1 angle types\n"},
+		{"reversed", "0 10 zlo zhi\n0 10 ylo yhi\n0 10 xlo xhi\n1 angle types\n2 angles\n1 bond types\n4 bonds\n2 atom types\n6 atoms\n"},
+		{"interleaved", "0 10 ylo yhi\n4 bonds\n2 atom types\n0 10 zlo zhi\n1 angle types\n6 atoms\n0 10 xlo xhi\n2 angles\n1 bond types\n"},
+	}
+	want := encodeString(t, fullSystem(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := strings.Replace(full, headers, tt.headers, 1)
+			if in == full {
+				t.Fatal("headers not found")
+			}
+			var s system
+			if err := decodeString(in, &s); err != nil {
+				t.Fatal(err)
+			}
+			if got := encodeString(t, &s); got != want {
+				t.Errorf("Encode = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeBlankLinesInTables(t *testing.T) {
+	full := readFile(t, "full.data")
+	tests := []struct {
+		name string
+		old  string
+		new  string
+	}{
+		{"before the first atom", "Atoms\n\n", "Atoms\n\n\n\n"},
+		{"between the atoms", "1.8 1.5 1\n", "1.8 1.5 1\n\n  \n"},
+		{"between the bonds", "2 1 1 3\n", "2 1 1 3\n\n"},
+		{"between the masses", "1 15.9994\n", "1 15.9994\n\n"},
+		{"between the coefficients", "1 0.1553 3.166\n", "1 0.1553 3.166\n\t\n"},
+	}
+	want := encodeString(t, fullSystem(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			if err := decodeString(strings.Replace(full, tt.old, tt.new, 1), &s); err != nil {
+				t.Fatal(err)
+			}
+			if got := encodeString(t, &s); got != want {
+				t.Errorf("Encode = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeStrictOrder(t *testing.T) {
+	full := readFile(t, "full.data")
+	masses := "Masses\n\n1 15.9994\n2 1.008\n\n"
+	atoms := full[strings.Index(full, "Atoms\n"):strings.Index(full, "Bonds\n")]
+	tests := []struct {
+		name    string
+		in      string
+		wantErr string
+	}{
+		{"canonical", full, ""},
+		{"masses after the links", strings.Replace(full, masses, "", 1) + "\n" + masses, "table = Masses is after table = Angles"},
+		{"atoms after bonds", strings.Replace(full, atoms, "", 1) + "\n" + atoms, "table = Atoms is after table = Angles"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			if err := decodeString(tt.in, &s); err != nil {
+				t.Fatalf("Decode without SetStrictOrder = %v", err)
+			}
+			err := decodeString(tt.in, &s, func(dec *Decoder) { dec.SetStrictOrder(true) })
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Decode = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeLines(t *testing.T) {
+	const in = `title
+
+2 atoms
+1 atom types
+1 lines
+
+0 10 xlo xhi
+0 10 ylo yhi
+-0.5 0.5 zlo zhi
+
+Atoms # line
+
+1 1 1 1 1 0.5 0 0
+2 1 1 0 1 2 0 0
+
+Lines
+
+1 0 0 1 0
+`
+	var v lines
+	if err := decodeString(in, &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.Atoms[1].Flag != 1 || v.Atoms[2].Flag != 0 || len(v.Lines[1]) != 4 || v.Lines[1][2] != 1 {
+		t.Errorf("Atoms = %v, Lines = %v", v.Atoms, v.Lines)
+	}
+	out := encodeString(t, &v)
+	for _, want := range []string{"\n1 lines\n", "\nAtoms\n\n1 1 1 1 1 0.5 0 0\n2 1 1 0 1 2 0 0\n", "\nLines\n\n1 0 0 1 0\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Encode = %q does not contain %q", out, want)
+		}
+	}
+}
+
+func TestDecodeStyleCheck(t *testing.T) {
+	const head = "title\n\n2 atoms\n2 atom types\n\n0 20 xlo xhi\n0 20 ylo yhi\n0 20 zlo zhi\n\nAtoms\n\n"
+	tests := []struct {
+		name     string
+		rows     string
+		mismatch bool
+		silent   bool // decoded without error if the check is disabled
+	}{
+		{"atomic", "1 1 3 4 5\n2 2 6 7 8\n", true, false},
+		{"atomic with image flags", "1 1 3.5 4 5 0 0 0\n2 2 6 7 8 0 0 0\n", true, false},
+		// ellipsoid: the ellipsoid flag is read as the atom type.
+		{"ellipsoid", "1 1 0 1 3 4 5\n2 2 0 1 6 7 8\n", true, false},
+		// peri: the density is read as the charge and passes the Check.
+		{"peri", "1 1 1 12 3 4 5\n2 2 1 12 6 7 8\n", true, true},
+		{"full", "1 1 1 -0.8 3 4 5\n2 1 2 0.4 6 7 8\n", false, true},
+	}
+	type full struct {
+		AtomsNbr  int               `lmpsdat:"atoms"`
+		AtomTypes int               `lmpsdat:"atom types"`
+		X         [2]float64        `lmpsdat:"xlo xhi"`
+		Y         [2]float64        `lmpsdat:"ylo yhi"`
+		Z         [2]float64        `lmpsdat:"zlo zhi"`
+		Atoms     map[int]*key.Atom `lmpsdat:"Atoms, full"`
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v full
+			err := decodeString(head+tt.rows, &v, func(dec *Decoder) { dec.SetStyleCheck(true) })
+			if tt.mismatch != errors.Is(err, key.ErrStyleMismatch) {
+				t.Errorf("Decode = %v, want a style mismatch = %v", err, tt.mismatch)
+			}
+			if !tt.mismatch && err != nil {
+				t.Fatal(err)
+			}
+
+			// the check is opt-in.
+			if err := decodeString(head+tt.rows, &v); (err == nil) != tt.silent {
+				t.Errorf("Decode without the check = %v, want nil = %v", err, tt.silent)
+			}
+		})
+	}
+
+	// the parse error of the first row is kept.
+	var pe *key.ParseError
+	err := decodeString(head+"1 1 3.5 4 5 0 0 0\n2 2 6 7 8 0 0 0\n", &full{}, func(dec *Decoder) { dec.SetStyleCheck(true) })
+	if !errors.As(err, &pe) {
+		t.Errorf("Decode = %v, want a *key.ParseError", err)
+	}
+}
+
+func TestDecodeSplitBox(t *testing.T) {
+	const atoms = "\nMasses\n\n1 39.948\n\nAtoms\n\n1 1 0 0 0\n"
+	tests := []struct {
+		name    string
+		box     string
+		split   bool
+		want    [3][2]float64
+		wantErr string
+	}{
+		{"LAMMPS form", "-5 5 xlo xhi\n0 1 ylo yhi\n0 2 zlo zhi\n", false, [3][2]float64{{-5, 5}, {0, 1}, {0, 2}}, ""},
+		{"token first", "xlo -5\nxhi 5\nylo 0\nyhi 1\nzlo 0\nzhi 2\n", true, [3][2]float64{{-5, 5}, {0, 1}, {0, 2}}, ""},
+		{"token last", "-5 xlo\n5 xhi\n0 1 ylo yhi\n0 zlo\n2 zhi\n", true, [3][2]float64{{-5, 5}, {0, 1}, {0, 2}}, ""},
+		{"not consecutive", "xlo -5\nylo 0\nzlo 0\nxhi 5\nyhi 1\nzhi 2\n", true, [3][2]float64{{-5, 5}, {0, 1}, {0, 2}}, ""},
+		{"hi first", "xhi 5\nxlo -5\n0 1 ylo yhi\n0 2 zlo zhi\n", true, [3][2]float64{{-5, 5}, {0, 1}, {0, 2}}, ""},
+		// the split lines are not headers: the box keeps its zero value.
+		{"disabled", "xlo -5\nxhi 5\n0 1 ylo yhi\n0 2 zlo zhi\n", false, [3][2]float64{}, "the box has a zero length"},
+		{"missing hi", "xlo -5\n0 1 ylo yhi\n0 2 zlo zhi\n", true, [3][2]float64{}, "xhi of the box is missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a atomic
+			err := decodeString("title\n\n1 atoms\n1 atom types\n\n"+tt.box+atoms, &a, func(dec *Decoder) { dec.SetSplitBox(tt.split) })
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := [3][2]float64{a.X, a.Y, a.Z}; got != tt.want {
+				t.Errorf("box = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeImpropers(t *testing.T) {
+	full := readFile(t, "full.data")
+	const impropers = "Impropers\n\n1 1 1 2 3 4\n2 1 4 5 6 1\n\n"
+	const coeffs = "Improper Coeffs\n\n1 5 180\n\n"
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{"between Bonds and Angles", strings.Replace(full, "Angles\n\n1 1", impropers+"Angles\n\n1 1", 1)},
+		{"at the end", full + "\n" + impropers},
+		{"at the end without blank line", full + "\n" + strings.TrimSuffix(impropers, "\n\n")},
+		{"coeffs", strings.Replace(full, "Atoms\n\n", coeffs+"Atoms\n\n", 1)},
+		{"headers", strings.Replace(full, "1 angle types\n", "1 angle types\n2 impropers\n1 improper types\n", 1) + "\n" + coeffs + impropers},
+	}
+	want := encodeString(t, fullSystem(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			if err := decodeString(tt.in, &s); err != nil {
+				t.Fatalf("Decode = %v", err)
+			}
+			if got := encodeString(t, &s); got != want {
+				t.Errorf("Encode = %s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeStripTitleComment(t *testing.T) {
+	in := "# LAMMPS data file via write_data" + strings.TrimPrefix(readFile(t, "full.data"), "LAMMPS data file")
+	tests := []struct {
+		name  string
+		strip bool
+		title string
+	}{
+		{"verbatim", false, "# LAMMPS data file via write_data"},
+		{"stripped", true, "LAMMPS data file via write_data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			if err := decodeString(in, &s, func(dec *Decoder) { dec.SetStripTitleComment(tt.strip) }); err != nil {
+				t.Fatal(err)
+			}
+			if s.Title != tt.title || len(s.Atoms) != 6 {
+				t.Errorf("Title = %q with %d atoms, want %q with 6 atoms", s.Title, len(s.Atoms), tt.title)
+			}
+			// the title is encoded as decoded.
+			if out := encodeString(t, &s); !strings.HasPrefix(out, tt.title+"\n") {
+				t.Errorf("Encode begins with %q, want %q", strings.SplitN(out, "\n", 2)[0], tt.title)
+			}
+		})
+	}
+}
+
+func TestDecodeDuplicateSection(t *testing.T) {
+	full := readFile(t, "full.data")
+	const masses = "Masses\n\n1 15.9994\n2 1.008\n\n"
+	tests := []struct {
+		name    string
+		in      string
+		opts    func(dec *Decoder)
+		wantErr string
+	}{
+		{"consecutive Masses", strings.Replace(full, masses, masses+masses, 1), nil, "duplicate section = Masses"},
+		{"Masses appended", full + "\n" + masses, nil, "duplicate section = Masses"},
+		{"different Masses", strings.Replace(full, masses, masses+"Masses\n\n1 12.011\n2 1.008\n\n", 1), nil, "duplicate section = Masses"},
+		{"Atoms appended", full + "\nAtoms\n\n1 1 1 -0.8476 1 1 1\n", nil, "duplicate section = Atoms"},
+		{"excluded table", full + "\n" + masses, func(dec *Decoder) { dec.SetSections(key.NameAtoms) }, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			var opts []func(*Decoder)
+			if tt.opts != nil {
+				opts = append(opts, tt.opts)
+			}
+			err := decodeString(tt.in, &s, opts...)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Decode = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDecodeCountComments(t *testing.T) {
+	full := readFile(t, "full.data")
+	tests := []struct {
+		name string
+		from string
+		to   string
+	}{
+		{"comment", "6 atoms\n", "6 atoms # water\n"},
+		{"comment without space", "4 bonds\n", "4 bonds #O-H\n"},
+		{"descriptive words", "2 atom types\n", "2 atom types of water\n"},
+		{"comments everywhere", "6 atoms\n2 atom types\n4 bonds\n1 bond types\n", "6 atoms # 2 molecules\n2 atom types # O H\n4 bonds # O-H\n1 bond types # harmonic\n"},
+	}
+	want := encodeString(t, fullSystem(t))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := strings.Replace(full, tt.from, tt.to, 1)
+			if in == full {
+				t.Fatalf("%q not found in full.data", tt.from)
+			}
+			var s system
+			if err := decodeString(in, &s); err != nil {
+				t.Fatal(err)
+			}
+			if s.AtomsNbr != 6 || s.AtomTypes != 2 || s.BondsNbr != 4 || s.BondTypes != 1 {
+				t.Errorf("counts = %d atoms, %d atom types, %d bonds, %d bond types, want 6, 2, 4, 1", s.AtomsNbr, s.AtomTypes, s.BondsNbr, s.BondTypes)
+			}
+			if got := encodeString(t, &s); got != want {
+				t.Errorf("Encode = %s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDecodeStrictMasses(t *testing.T) {
+	in := strings.Replace(readFile(t, "full.data"), "\n2 1.008\n", "\n2 1.008 450\n", 1)
+	var s system
+	if err := decodeString(in, &s); err != nil || s.Masses[2] != 1.008 {
+		t.Errorf("Decode = %v with mass = %g, want nil and 1.008", err, s.Masses[2])
+	}
+	err := decodeString(in, &s, func(dec *Decoder) { dec.SetStrictMasses(true) })
+	if err == nil || !strings.Contains(err.Error(), "row = 2 has 3 fields") {
+		t.Errorf("Decode = %v, want an error containing %q", err, "row = 2 has 3 fields")
+	}
+}
+
+func TestDecodeBestEffort(t *testing.T) {
+	full := readFile(t, "full.data")
+	tests := []struct {
+		name    string
+		in      string
+		skipped []key.Name
+		check   func(s *system) bool
+	}{
+		{"corrupt Bonds", strings.Replace(full, "3 1 4 5\n", "3 1 4 x\n", 1), []key.Name{key.NameBonds}, func(s *system) bool {
+			return len(s.Masses) == 2 && len(s.Atoms) == 6 && s.Bonds == nil && len(s.Angles) == 2
+		}},
+		{"truncated Bonds", strings.Replace(full, "3 1 4 5\n4 1 4 6\n", "", 1), []key.Name{key.NameBonds}, func(s *system) bool {
+			return len(s.Masses) == 2 && len(s.Atoms) == 6 && s.Bonds == nil && len(s.Angles) == 2
+		}},
+		{"corrupt last table", strings.Replace(full, "2 1 5 4 6\n", "2 1 5 4\n", 1), []key.Name{key.NameAngles}, func(s *system) bool {
+			return len(s.Masses) == 2 && len(s.Atoms) == 6 && len(s.Bonds) == 4 && s.Angles == nil
+		}},
+		{"corrupt Masses and Bonds", strings.NewReplacer("2 1.008\n", "2 x\n", "3 1 4 5\n", "3 1 4 x\n").Replace(full), []key.Name{key.NameMasses, key.NameBonds}, func(s *system) bool {
+			return s.Masses == nil && len(s.Atoms) == 6 && s.Bonds == nil && len(s.Angles) == 2
+		}},
+		{"valid", full, nil, func(s *system) bool {
+			return len(s.Masses) == 2 && len(s.Atoms) == 6 && len(s.Bonds) == 4 && len(s.Angles) == 2
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// without the best-effort decoding, the corrupt table is an error.
+			var s system
+			if err := decodeString(tt.in, &s); (err == nil) != (tt.skipped == nil) {
+				t.Fatalf("Decode = %v", err)
+			}
+
+			var called []key.Name
+			dec := NewDecoder(strings.NewReader(tt.in))
+			dec.SetBestEffort(func(name key.Name, err error) {
+				if err == nil {
+					t.Errorf("fn called without error for %s", name)
+				}
+				called = append(called, name)
+			})
+			s = system{}
+			stats, err := dec.DecodeWithStats(&s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(called, tt.skipped) || !reflect.DeepEqual(stats.Skipped, tt.skipped) {
+				t.Errorf("fn called for %v and Skipped = %v, want %v", called, stats.Skipped, tt.skipped)
+			}
+			if !tt.check(&s) {
+				t.Errorf("decoded %d masses, %d atoms, %d bonds, %d angles", len(s.Masses), len(s.Atoms), len(s.Bonds), len(s.Angles))
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package lmpsdat
+
+import (
+	"math"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// DedupAtoms removes the atoms that are within a distance tol of another atom,
+// e.g. after merging two systems. The atoms are processed in increasing order
+// of identifier: an atom is kept if no kept atom is within tol, otherwise it is
+// removed from atoms. The periodic images are not considered. If tol is not
+// greater than zero, only the atoms having exactly the same coordinates are
+// removed.
+//
+// It returns the identifiers of the removed atoms in increasing order and a map
+// linking each of them to the identifier of the kept atom it overlaps. This map
+// can be used to update the links referencing the removed atoms.
+//
+// The atoms are sorted into a grid whose cells have a length of tol: each atom
+// is only compared to the atoms of the neighboring cells.
+func DedupAtoms(atoms map[int]*key.Atom, tol float64) (removed []int, remap map[int]int) {
+	remap = make(map[int]int)
+	grid := make(map[[3]int64][]int)
+	exact := make(map[[3]float64]int)
+
+	for _, id := range atomIDs(atoms) {
+		atom := atoms[id]
+		if atom == nil {
+			continue
+		}
+		pos := [3]float64{atom.X, atom.Y, atom.Z}
+
+		if !(tol > 0) {
+			if kept, ok := exact[pos]; ok {
+				remap[id] = kept
+				removed = append(removed, id)
+				continue
+			}
+			exact[pos] = id
+			continue
+		}
+
+		var c [3]int64
+		for i, v := range pos {
+			c[i] = int64(math.Floor(v / tol))
+		}
+		kept, ok := neighbor(atoms, grid, c, pos, tol)
+		if ok {
+			remap[id] = kept
+			removed = append(removed, id)
+			continue
+		}
+		grid[c] = append(grid[c], id)
+	}
+
+	for _, id := range removed {
+		delete(atoms, id)
+	}
+	return removed, remap
+}
+
+// neighbor returns the identifier of a kept atom of grid within tol of pos. Only
+// the cell c and its neighbors are searched.
+func neighbor(atoms map[int]*key.Atom, grid map[[3]int64][]int, c [3]int64, pos [3]float64, tol float64) (int, bool) {
+	for dx := int64(-1); dx <= 1; dx++ {
+		for dy := int64(-1); dy <= 1; dy++ {
+			for dz := int64(-1); dz <= 1; dz++ {
+				for _, id := range grid[[3]int64{c[0] + dx, c[1] + dy, c[2] + dz}] {
+					a := atoms[id]
+					d := math.Sqrt((a.X-pos[0])*(a.X-pos[0]) + (a.Y-pos[1])*(a.Y-pos[1]) + (a.Z-pos[2])*(a.Z-pos[2]))
+					if d <= tol {
+						return id, true
+					}
+				}
+			}
+		}
+	}
+	return 0, false
+}
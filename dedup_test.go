@@ -0,0 +1,78 @@
+package lmpsdat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestDedupAtoms(t *testing.T) {
+	tests := []struct {
+		name    string
+		atoms   map[int]*key.Atom
+		tol     float64
+		removed []int
+		remap   map[int]int
+	}{
+		{"coincident", map[int]*key.Atom{
+			1: {X: 1, Y: 1, Z: 1},
+			2: {X: 5, Y: 5, Z: 5},
+			3: {X: 1, Y: 1, Z: 1},
+		}, 0, []int{3}, map[int]int{3: 1}},
+		{"within tolerance", map[int]*key.Atom{
+			4: {X: 1, Y: 1, Z: 1},
+			2: {X: 1.05, Y: 1, Z: 0.98},
+			7: {X: 1.2, Y: 1, Z: 1},
+		}, 0.1, []int{4}, map[int]int{4: 2}},
+		{"across a cell", map[int]*key.Atom{
+			1: {X: 0.99, Y: 0, Z: 0},
+			2: {X: 1.01, Y: 0, Z: 0},
+		}, 0.5, []int{2}, map[int]int{2: 1}},
+		{"distinct", map[int]*key.Atom{
+			1: {X: 0, Y: 0, Z: 0},
+			2: {X: 1, Y: 0, Z: 0},
+		}, 0.5, nil, map[int]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := len(tt.atoms)
+			removed, remap := DedupAtoms(tt.atoms, tt.tol)
+			if !reflect.DeepEqual(removed, tt.removed) || !reflect.DeepEqual(remap, tt.remap) {
+				t.Errorf("DedupAtoms = %v, %v, want %v, %v", removed, remap, tt.removed, tt.remap)
+			}
+			if len(tt.atoms) != n-len(tt.removed) {
+				t.Errorf("%d atoms left, want %d", len(tt.atoms), n-len(tt.removed))
+			}
+		})
+	}
+}
+
+// lattice returns n*n*n atoms on a cubic lattice of spacing 1.
+func lattice(n int) map[int]*key.Atom {
+	atoms := make(map[int]*key.Atom, n*n*n)
+	for i := 0; i < n*n*n; i++ {
+		atoms[i+1] = &key.Atom{X: float64(i / (n * n)), Y: float64(i / n % n), Z: float64(i % n)}
+	}
+	return atoms
+}
+
+func TestDedupAtomsLattice(t *testing.T) {
+	// the grid keeps the number of comparisons proportional to the number of
+	// atoms: 64000 atoms are deduplicated quickly.
+	atoms := lattice(40)
+	atoms[0] = &key.Atom{X: 20.1, Y: 20, Z: 20}
+	removed, _ := DedupAtoms(atoms, 0.5)
+	if !reflect.DeepEqual(removed, []int{20*1600 + 20*40 + 20 + 1}) {
+		t.Errorf("removed = %v, want the atom at 20 20 20", removed)
+	}
+}
+
+func BenchmarkDedupAtoms(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		atoms := lattice(30)
+		b.StartTimer()
+		DedupAtoms(atoms, 0.5)
+	}
+}
@@ -0,0 +1,91 @@
+package lmpsdat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// baseBox is embedded by the structures of TestDecodeEmbedded.
+type baseBox struct {
+	X [2]float64 `lmpsdat:"xlo xhi"`
+	Y [2]float64 `lmpsdat:"ylo yhi"`
+	Z [2]float64 `lmpsdat:"zlo zhi"`
+}
+
+// counts embeds baseBox at a second level.
+type counts struct {
+	baseBox
+	AtomsNbr  int `lmpsdat:"atoms"`
+	AtomTypes int `lmpsdat:"atom types"`
+}
+
+func TestDecodeEmbedded(t *testing.T) {
+	const in = "title\n\n1 atoms\n1 atom types\n\n0 10 xlo xhi\n0 20 ylo yhi\n0 30 zlo zhi\n\nMasses\n\n1 39.948\n\nAtoms\n\n1 1 1 2 3\n"
+	want := baseBox{X: [2]float64{0, 10}, Y: [2]float64{0, 20}, Z: [2]float64{0, 30}}
+
+	t.Run("one level", func(t *testing.T) {
+		var v struct {
+			baseBox
+			AtomsNbr  int               `lmpsdat:"atoms"`
+			AtomTypes int               `lmpsdat:"atom types"`
+			Masses    map[int]float64   `lmpsdat:"Masses"`
+			Atoms     map[int]*key.Atom `lmpsdat:"Atoms, atomic"`
+		}
+		if err := decodeString(in, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.baseBox != want || len(v.Atoms) != 1 {
+			t.Errorf("box = %+v with %d atoms, want %+v with 1 atom", v.baseBox, len(v.Atoms), want)
+		}
+		if out := encodeString(t, &v); !strings.Contains(out, "\n0 10 xlo xhi\n0 20 ylo yhi\n0 30 zlo zhi\n") {
+			t.Errorf("Encode = %q does not contain the embedded box", out)
+		}
+	})
+
+	t.Run("two levels", func(t *testing.T) {
+		var v struct {
+			counts
+			Masses map[int]float64   `lmpsdat:"Masses"`
+			Atoms  map[int]*key.Atom `lmpsdat:"Atoms, atomic"`
+		}
+		if err := decodeString(in, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.baseBox != want || v.AtomsNbr != 1 || v.AtomTypes != 1 {
+			t.Errorf("counts = %+v, want the box %+v and 1 atom of 1 type", v.counts, want)
+		}
+	})
+
+	t.Run("shadowed", func(t *testing.T) {
+		// the field tagged at a shallower depth takes precedence.
+		var v struct {
+			baseBox
+			X         [2]float64        `lmpsdat:"xlo xhi"`
+			AtomsNbr  int               `lmpsdat:"atoms"`
+			AtomTypes int               `lmpsdat:"atom types"`
+			Masses    map[int]float64   `lmpsdat:"Masses"`
+			Atoms     map[int]*key.Atom `lmpsdat:"Atoms, atomic"`
+		}
+		if err := decodeString(in, &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.X != want.X || v.baseBox.X != [2]float64{} || v.baseBox.Y != want.Y {
+			t.Errorf("X = %v and baseBox = %+v, want X = %v and baseBox.X unset", v.X, v.baseBox, want.X)
+		}
+	})
+
+	t.Run("pointer ignored", func(t *testing.T) {
+		var v struct {
+			*baseBox
+			AtomsNbr int `lmpsdat:"atoms"`
+		}
+		if err := decodeString("1 atoms\n\n0 10 xlo xhi\n", &v); err != nil {
+			t.Fatal(err)
+		}
+		if v.baseBox != nil || v.AtomsNbr != 1 {
+			t.Errorf("baseBox = %v with %d atoms, want nil with 1 atom", v.baseBox, v.AtomsNbr)
+		}
+	})
+}
@@ -5,13 +5,21 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"sort"
+	"strings"
 
 	"github.com/kpotier/lmpsdat/key"
 )
 
 // Encoder writes LAMMPS data values to an input stream.
 type Encoder struct {
-	w io.Writer
+	w          io.Writer
+	opts       key.Options
+	comments   map[key.Name]string
+	skipCheck  bool
+	checkAtoms bool
+	elements   map[int]string // comments of the masses if not nil
+	step       *step          // not nil during a step-wise encoding
 }
 
 // NewEncoder returns a new encoder that writes to w.
@@ -21,96 +29,328 @@ func NewEncoder(w io.Writer) *Encoder {
 	}
 }
 
-// Encode writes the LAMMPS data of v to the stream.
-func (enc *Encoder) Encode(v interface{}) error {
-	ptr := reflect.TypeOf(v)
-	if ptr.Kind() != reflect.Ptr {
-		return fmt.Errorf("interface passed is not a pointer")
-	}
+// SetFlatZ enables or disables the acceptance of a box whose zlo is equal to
+// zhi, as written by some tools for 2D systems. It is disabled by default.
+func (enc *Encoder) SetFlatZ(b bool) {
+	enc.opts.FlatZ = b
+}
 
-	val := reflect.ValueOf(v).Elem()
-	typ := ptr.Elem()
-	if typ.Kind() != reflect.Struct {
-		return fmt.Errorf("interface passed is not a pointer of a struct")
+// SetFormat sets the format (see the fmt package) used to write the column c
+// of the Atoms table, e.g. "%.10f" for key.ColumnX. An empty format restores
+// the default one: %d for the integers and %g for the floats.
+func (enc *Encoder) SetFormat(c key.Column, format string) {
+	if enc.opts.Formats == nil {
+		enc.opts.Formats = make(map[key.Column]string)
+	}
+	if format == "" {
+		delete(enc.opts.Formats, c)
+		return
 	}
+	enc.opts.Formats[c] = format
+}
+
+// SetBoxFormat sets the format (see the fmt package) used to write each bound
+// of the box. Use key.WriteDataBoxFormat to write the box as the LAMMPS
+// write_data command does, e.g. "-5.0000000000000000e+00
+// 5.0000000000000000e+00 xlo xhi". An empty format restores the default one:
+// %g.
+func (enc *Encoder) SetBoxFormat(format string) {
+	enc.opts.BoxFormat = format
+}
+
+// SetTrailingZero enables or disables the writing of the whole floats with a
+// trailing ".0" (e.g. 0.0 instead of 0) in the Atoms, Masses, Coeffs, Lines,
+// and Triangles tables and in the box. See key.Options.TrailingZero. It is
+// disabled by default: the floats are written with %g.
+func (enc *Encoder) SetTrailingZero(b bool) {
+	enc.opts.TrailingZero = b
+}
+
+// SetSkipCheck enables or disables the skipping of the Check method of the
+// Keys before encoding. It is useful to write intermediate files of a system
+// under construction (e.g. before the masses are filled in). When enabled, the
+// output may not be a valid LAMMPS data file. It is disabled by default.
+func (enc *Encoder) SetSkipCheck(b bool) {
+	enc.skipCheck = b
+}
+
+// SetCheckAtoms enables or disables the verification that every atom referenced
+// by the Bonds, Angles, and Dihedrals tables exists in the Atoms table. The
+// Check method of the Links only verifies that the atoms are between one and
+// the number of atoms. Unlike the Check methods, this verification is done even
+// if SetSkipCheck is enabled: it is useful to write intermediate files whose
+// identifiers are not contiguous (see Renumber) without dangling links. It is
+// done only if the structure has a field tagged with NameAtoms. It is disabled
+// by default.
+func (enc *Encoder) SetCheckAtoms(b bool) {
+	enc.checkAtoms = b
+}
 
-	nFields, keys := createNames(typ)
+// SetElements sets the element symbol of each atom type (e.g. "C" for type 1)
+// written as a comment after its mass in the Masses table, e.g. "1 12.011 #
+// C". The types that are not in e have no comment. A nil map, the default,
+// writes the masses without comment.
+func (enc *Encoder) SetElements(e map[int]string) {
+	enc.elements = e
+}
 
-	for n, f := range nFields {
-		field := val.Field(f).Interface()
-		k := keys[n]
-		if err := k.Set(field); err != nil {
-			return fmt.Errorf("k.Set for Key = %s: %w", n, err)
+// SetComment attaches a comment to the table or the header whose Name is name.
+// The comment is written right before the table or the header, each of its
+// lines being preceded by "# ". An empty comment removes the previous one. The
+// comments attached to NameTitle are ignored as the title must be the first
+// line.
+func (enc *Encoder) SetComment(name key.Name, comment string) {
+	if enc.comments == nil {
+		enc.comments = make(map[key.Name]string)
+	}
+	if comment == "" {
+		delete(enc.comments, name)
+		return
+	}
+	enc.comments[name] = comment
+}
+
+// encodeKey writes the comment attached to the Key followed by the Key.
+func (enc *Encoder) encodeKey(k key.Key) error {
+	if c, ok := enc.comments[k.Name()]; ok && k.Name() != key.NameTitle {
+		for _, l := range strings.Split(c, "\n") {
+			if _, err := fmt.Fprintf(enc.w, "# %s\n", l); err != nil {
+				return fmt.Errorf("fmt.Fprintf comment for Key = %s: %w", k.Name(), err)
+			}
 		}
-		if err := k.SetKeysVal(); err != nil && !errors.Is(err, key.ErrUnsupported) {
-			return fmt.Errorf("k.SetKeysVal for Key = %s: %w", n, err)
+	}
+	if err := k.Encode(enc.w); err != nil {
+		return fmt.Errorf("k.Encode for Key = %s: %w", k.Name(), err)
+	}
+	return nil
+}
+
+// setKeys returns the Keys filled with the fields of v. The values of the
+// Keys are checked with the Check method unless SetSkipCheck is enabled.
+func (enc *Encoder) setKeys(v interface{}) (map[key.Name]key.Key, error) {
+	keys, err := enc.fillKeys(v)
+	if err != nil {
+		return nil, err
+	}
+	if err := inferTypes(keys); err != nil {
+		return nil, err
+	}
+	if err := enc.checkKeys(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// fillKeys returns the Keys filled with the fields of v. The headers are set
+// from the length of the tables (see key.Key.SetKeysVal): the fields of the
+// headers are therefore set before the tables, whose length takes precedence.
+// A nil table (e.g. a nil map) does not modify its headers, so that they can be
+// set without the table (see WriteHeader).
+func (enc *Encoder) fillKeys(v interface{}) (map[key.Name]key.Key, error) {
+	val, err := structOf(v)
+	if err != nil {
+		return nil, err
+	}
+
+	nFields, keys := createNames(val.Type(), nil)
+	key.SetOptions(keys, &enc.opts)
+
+	for _, headers := range []bool{true, false} {
+		for n, f := range nFields {
+			k := keys[n]
+			if key.IsHeader(k) != headers {
+				continue
+			}
+			fv := val.FieldByIndex(f)
+			if err := k.Set(valueOf(fv, reflect.TypeOf(k.Get()))); err != nil {
+				return nil, fmt.Errorf("k.Set for Key = %s: %w", n, err)
+			}
+			if isNil(fv) {
+				continue
+			}
+			if err := k.SetKeysVal(); err != nil && !errors.Is(err, key.ErrUnsupported) {
+				return nil, fmt.Errorf("k.SetKeysVal for Key = %s: %w", n, err)
+			}
 		}
 	}
+	if m, ok := keys[key.NameMasses].(*key.Masses); ok && enc.elements != nil {
+		m.SetComments(enc.elements)
+	}
+	return keys, nil
+}
 
+// checkKeys verifies the Keys with the Check method unless SetSkipCheck is
+// enabled, and verifies the atoms of the links if SetCheckAtoms is enabled.
+func (enc *Encoder) checkKeys(keys map[key.Name]key.Key) error {
+	if enc.checkAtoms {
+		if err := checkLinkAtoms(keys); err != nil {
+			return err
+		}
+	}
+	if enc.skipCheck {
+		return nil
+	}
 	for _, k := range keys {
 		err := k.Check()
 		if err != nil {
 			return fmt.Errorf("k.Check for Key = %s: %w", k.Name(), err)
 		}
 	}
+	return nil
+}
 
-	var title string
-	if k, ok := keys[key.NameTitle]; ok {
-		title = k.Get().(string)
+// checkLinkAtoms verifies that the atoms referenced by the Links tables exist
+// in the Atoms table. Nothing is verified if there is no Atoms table.
+func checkLinkAtoms(keys map[key.Name]key.Key) error {
+	k, ok := keys[key.NameAtoms]
+	if !ok {
+		return nil
 	}
-	fmt.Fprintf(enc.w, "%s\n\n", title) // errors are omitted and will appear when using k.Encode
-
-	set := false
-	nbr := []key.Name{key.NameAtomsNbr, key.NameBondsNbr, key.NameAnglesNbr, key.NameDihedralsNbr}
-	for _, n := range nbr {
-		if k, ok := keys[n]; ok {
-			if err := k.Encode(enc.w); err != nil {
-				return fmt.Errorf("k.Encode for Key = %s: %w", n, err)
+	atoms := reflect.ValueOf(k.Get()) // map[int]*key.Atom or map[int]*key.AtomF32
+	for _, name := range linkNames {
+		k, ok := keys[name]
+		if !ok {
+			continue
+		}
+		links, _ := k.Get().(map[int]*key.Link)
+		ids := make([]int, 0, len(links))
+		for id := range links {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		for _, id := range ids {
+			for _, atom := range links[id].Atoms() {
+				if !atoms.MapIndex(reflect.ValueOf(atom)).IsValid() {
+					return fmt.Errorf("atom = %d of id = %d of %s does not exist in %s", atom, id, name, key.NameAtoms)
+				}
 			}
-			set = true
 		}
 	}
-	if set {
-		fmt.Fprint(enc.w, "\n")
-	}
+	return nil
+}
 
-	set = false
-	types := []key.Name{key.NameAtomTypes, key.NameBondTypes, key.NameAngleTypes, key.NameDihedralTypes}
-	for _, n := range types {
-		if k, ok := keys[n]; ok {
-			if err := k.Encode(enc.w); err != nil {
-				return fmt.Errorf("k.Encode for Key = %s: %w", n, err)
-			}
-			set = true
+// typesOf links the Names of the tables using types to the Names of the Headers
+// containing the number of types.
+var typesOf = map[key.Name]key.Name{
+	key.NameAtoms:     key.NameAtomTypes,
+	key.NameBonds:     key.NameBondTypes,
+	key.NameAngles:    key.NameAngleTypes,
+	key.NameDihedrals: key.NameDihedralTypes,
+}
+
+// inferTypes sets the number of types to the largest type used in the Atoms and
+// Links tables if it is equal to zero, i.e. if no Masses or Coeffs table
+// provides it.
+func inferTypes(keys map[key.Name]key.Key) error {
+	type maxTyper interface {
+		MaxType() int
+	}
+	for n, t := range typesOf {
+		k, ok := keys[n].(maxTyper)
+		if !ok {
+			continue
+		}
+		h, ok := keys[t]
+		if !ok || h.Get().(int) != 0 {
+			continue
 		}
+		if err := h.Set(k.MaxType()); err != nil {
+			return fmt.Errorf("h.Set for Key = %s: %w", t, err)
+		}
+	}
+	return nil
+}
+
+// EncodeSection writes only the table or the header of v whose Name is name to
+// the stream. The other fields of v are still used to set and check the Keys.
+func (enc *Encoder) EncodeSection(v interface{}, name key.Name) error {
+	keys, err := enc.setKeys(v)
+	if err != nil {
+		return err
 	}
-	if set {
-		fmt.Fprint(enc.w, "\n")
+	k, ok := keys[name]
+	if !ok {
+		return fmt.Errorf("Key = %s is not a field of the struct", name)
 	}
+	return enc.encodeKey(k)
+}
+
+// lener is implemented by the Keys of the tables.
+type lener interface {
+	Len() int
+}
 
-	set = false
-	box := []key.Name{key.NameBoxX, key.NameBoxY, key.NameBoxZ}
-	for _, n := range box {
+// Encode writes the LAMMPS data of v to the stream.
+//
+// The headers containing the number of values (e.g. NameAtomsNbr) are set from
+// the length of the tables (see key.Key.SetKeysVal), and a number of types
+// equal to zero is set to the largest type used by the table (e.g. the atom
+// types of the Atoms table). The Keys are then checked unless SetSkipCheck is
+// enabled.
+//
+// A header is written if v has a field tagged with its Name or if a table of v
+// requires it (e.g. NameBondsNbr for NameBonds), even if its value is zero. A
+// field tagged with a count header (e.g. `lmpsdat:"bonds"`) therefore writes
+// "0 bonds" without a Bonds table. The empty tables are not written.
+func (enc *Encoder) Encode(v interface{}) error {
+	keys, err := enc.setKeys(v)
+	if err != nil {
+		return err
+	}
+
+	if err := enc.encodeHeaders(keys); err != nil {
+		return err
+	}
+	for _, n := range key.ListSections {
 		if k, ok := keys[n]; ok {
-			if err := k.Encode(enc.w); err != nil {
-				return fmt.Errorf("k.Encode for Key = %s: %w", n, err)
+			if err := enc.encodeTable(k); err != nil {
+				return err
 			}
-			set = true
 		}
 	}
-	if set {
-		fmt.Fprint(enc.w, "\n")
+	return nil
+}
+
+// encodeHeaders writes the title and the headers of keys.
+func (enc *Encoder) encodeHeaders(keys map[key.Name]key.Key) error {
+	var title string
+	if k, ok := keys[key.NameTitle]; ok {
+		title = k.Get().(string)
 	}
+	fmt.Fprintf(enc.w, "%s\n\n", title) // errors are omitted and will appear when using k.Encode
 
-	tables := []key.Name{key.NameMasses, key.NamePairCoeffs, key.NameBondCoeffs, key.NameAngleCoeffs, key.NameDihedralCoeffs, key.NameAtoms, key.NameBonds, key.NameAngles, key.NameDihedrals}
-	for _, n := range tables {
-		if k, ok := keys[n]; ok {
-			if err := k.Encode(enc.w); err != nil {
-				return fmt.Errorf("k.Encode for Key = %s: %w", n, err)
+	groups := [][]key.Name{
+		{key.NameAtomsNbr, key.NameBondsNbr, key.NameAnglesNbr, key.NameDihedralsNbr, key.NameImpropersNbr,
+			key.NameLinesNbr, key.NameTrianglesNbr},
+		{key.NameAtomTypes, key.NameBondTypes, key.NameAngleTypes, key.NameDihedralTypes, key.NameImproperTypes},
+		{key.NameBoxX, key.NameBoxY, key.NameBoxZ},
+	}
+	for _, g := range groups {
+		set := false
+		for _, n := range g {
+			if k, ok := keys[n]; ok {
+				if err := enc.encodeKey(k); err != nil {
+					return err
+				}
+				set = true
 			}
+		}
+		if set {
 			fmt.Fprint(enc.w, "\n")
 		}
 	}
+	return nil
+}
 
+// encodeTable writes the table k followed by a blank line. Nothing is written
+// for an empty table.
+func (enc *Encoder) encodeTable(k key.Key) error {
+	if l, ok := k.(lener); ok && l.Len() == 0 {
+		return nil
+	}
+	if err := enc.encodeKey(k); err != nil {
+		return err
+	}
+	fmt.Fprint(enc.w, "\n")
 	return nil
 }
@@ -0,0 +1,407 @@
+package lmpsdat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestEncodeSection(t *testing.T) {
+	s := fullSystem(t)
+	tests := []struct {
+		name    key.Name
+		want    string
+		wantErr bool
+	}{
+		{key.NameBonds, "Bonds\n\n1 1 1 2\n2 1 1 3\n3 1 4 5\n4 1 4 6\n", false},
+		{key.NameMasses, "Masses\n\n1 15.9994\n2 1.008\n", false},
+		{key.NameAtomsNbr, "6 atoms\n", false},
+		{key.NameBoxY, "0 10 ylo yhi\n", false},
+		{key.NameDihedrals, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.name), func(t *testing.T) {
+			var b bytes.Buffer
+			err := NewEncoder(&b).EncodeSection(s, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncodeSection = %v, want error = %v", err, tt.wantErr)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("EncodeSection = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeInferTypes(t *testing.T) {
+	s := fullSystem(t)
+	v := struct {
+		AtomTypes int               `lmpsdat:"atom types"`
+		BondTypes int               `lmpsdat:"bond types"`
+		X         [2]float64        `lmpsdat:"xlo xhi"`
+		Y         [2]float64        `lmpsdat:"ylo yhi"`
+		Z         [2]float64        `lmpsdat:"zlo zhi"`
+		Atoms     map[int]*key.Atom `lmpsdat:"Atoms, full"`
+		Bonds     map[int]*key.Link `lmpsdat:"Bonds"`
+	}{X: s.X, Y: s.Y, Z: s.Z, Atoms: s.Atoms, Bonds: s.Bonds}
+	v.Bonds[4].SetType(3)
+
+	out := encodeString(t, &v)
+	for _, want := range []string{"\n6 atoms\n4 bonds\n", "\n2 atom types\n3 bond types\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Encode = %q does not contain %q", out, want)
+		}
+	}
+	if v.AtomTypes != 0 || v.BondTypes != 0 {
+		t.Errorf("the fields of v are modified: %d atom types, %d bond types", v.AtomTypes, v.BondTypes)
+	}
+
+	v.AtomTypes = 5 // a header set by the caller is kept
+	if out := encodeString(t, &v); !strings.Contains(out, "\n5 atom types\n") {
+		t.Errorf("Encode = %q does not contain the atom types set", out)
+	}
+}
+
+func TestEncodeComment(t *testing.T) {
+	s := fullSystem(t)
+	out := encodeString(t, s, func(enc *Encoder) {
+		enc.SetComment(key.NameAtoms, "water\nTIP3P")
+		enc.SetComment(key.NameAtomsNbr, "counts")
+		enc.SetComment(key.NameTitle, "ignored")
+		enc.SetComment(key.NameBonds, "removed")
+		enc.SetComment(key.NameBonds, "")
+	})
+	for _, want := range []string{"\n# water\n# TIP3P\nAtoms\n", "\n\n# counts\n6 atoms\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Encode = %q does not contain %q", out, want)
+		}
+	}
+	if !strings.HasPrefix(out, s.Title+"\n") || strings.Contains(out, "ignored") || strings.Contains(out, "removed") {
+		t.Errorf("Encode = %q", out)
+	}
+
+	var s2 system
+	if err := decodeString(out, &s2); err != nil {
+		t.Fatalf("Decode of the commented file: %v", err)
+	}
+	if len(s2.Atoms) != 6 || s2.AtomsNbr != 6 {
+		t.Errorf("Decode = %d atoms, header = %d, want 6", len(s2.Atoms), s2.AtomsNbr)
+	}
+}
+
+func TestEncodeCommentKeyword(t *testing.T) {
+	// the comments begin with the keyword of a Key.
+	tests := []struct {
+		name    key.Name
+		comment string
+	}{
+		{key.NameBondsNbr, "bonds generated by the tool"},
+		{key.NameAtomTypes, "atom types of the water"},
+		{key.NameBoxX, "xlo xhi in angstroms"},
+		{key.NameMasses, "Masses of O and H"},
+		{key.NameAtoms, "Atoms # full"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.name), func(t *testing.T) {
+			s := fullSystem(t)
+			out := encodeString(t, s, func(enc *Encoder) { enc.SetComment(tt.name, tt.comment) })
+			if !strings.Contains(out, "# "+tt.comment+"\n") {
+				t.Fatalf("Encode = %q does not contain the comment", out)
+			}
+			var s2 system
+			if err := decodeString(out, &s2); err != nil {
+				t.Fatalf("Decode of the commented file: %v", err)
+			}
+			if out2 := encodeString(t, &s2); out2 != encodeString(t, s) {
+				t.Errorf("Decode of the commented file = %q", out2)
+			}
+		})
+	}
+}
+
+func TestEncodeEmptyTables(t *testing.T) {
+	s := fullSystem(t)
+	s.Angles = map[int]*key.Link{}
+	s.AngleCoeffs = map[int][]float64{}
+	s.AngleTypes = 0
+	s.Bonds = nil // the header is kept
+	s.BondsNbr = 0
+	s.BondCoeffs = nil
+	s.BondTypes = 0
+
+	out := encodeString(t, s)
+	for _, table := range []string{"Angles", "Angle Coeffs", "Bonds", "Bond Coeffs"} {
+		if strings.Contains(out, table+"\n") {
+			t.Errorf("Encode = %q contains the empty table = %s", out, table)
+		}
+	}
+	if strings.Contains(out, "\n\n\n") {
+		t.Errorf("Encode = %q contains a stray blank line", out)
+	}
+	for _, want := range []string{"\n0 bonds\n", "\n0 angles\n", "\nAtoms\n"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Encode = %q does not contain %q", out, want)
+		}
+	}
+	var s2 system
+	if err := decodeString(out, &s2); err != nil {
+		t.Errorf("Decode of the encoded file: %v", err)
+	}
+
+	s.BondsNbr = 4
+	if err := NewEncoder(&strings.Builder{}).Encode(s); err == nil {
+		t.Error("Encode = nil with 4 bonds and a nil Bonds table")
+	}
+}
+
+func TestEncodeCountHeaders(t *testing.T) {
+	a := atomic{Title: "argon", AtomsNbr: 1, AtomTypes: 1, X: [2]float64{0, 1}, Y: [2]float64{0, 1}, Z: [2]float64{0, 1},
+		Masses: map[int]float64{1: 39.948}, Atoms: map[int]*key.Atom{1: {AtomType: 1}}}
+	tests := []struct {
+		name    string
+		v       interface{}
+		want    []string
+		notWant []string
+	}{
+		{"no count field", &a, nil, []string{"bonds", "angles"}},
+		{"bonds", &struct {
+			atomic
+			BondsNbr int `lmpsdat:"bonds"`
+		}{atomic: a}, []string{"\n1 atoms\n", "\n0 bonds\n"}, []string{"Bonds", "bond types"}},
+		{"bonds and angles", &struct {
+			atomic
+			BondsNbr  int `lmpsdat:"bonds"`
+			BondTypes int `lmpsdat:"bond types"`
+			AnglesNbr int `lmpsdat:"angles"`
+		}{atomic: a}, []string{"\n0 bonds\n", "\n0 bond types\n", "\n0 angles\n"}, []string{"Bonds", "Angles", "Bond Coeffs"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := encodeString(t, tt.v)
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("Encode = %q does not contain %q", out, want)
+				}
+			}
+			for _, s := range tt.notWant {
+				if strings.Contains(out, s) {
+					t.Errorf("Encode = %q contains %q", out, s)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeZeroTypes(t *testing.T) {
+	a := atomic{
+		X: [2]float64{0, 10}, Y: [2]float64{0, 10}, Z: [2]float64{0, 10},
+		Masses: map[int]float64{1: 12.011},
+		Atoms:  map[int]*key.Atom{1: {AtomType: 1}},
+	}
+	// the number of atom types is set from the Masses table.
+	if out := encodeString(t, &a); !strings.Contains(out, "\n1 atom types\n") {
+		t.Errorf("Encode = %q does not contain 1 atom types", out)
+	}
+
+	// the headers written by WriteHeader cannot be changed by the table.
+	enc := NewEncoder(&strings.Builder{})
+	if err := enc.WriteHeader(&atomic{AtomsNbr: 1, X: a.X, Y: a.Y, Z: a.Z}); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.WriteSection(&a, key.NameMasses); err == nil {
+		t.Error("WriteSection = nil for a Masses table after 0 atom types")
+	}
+}
+
+func TestEncodeSkipCheck(t *testing.T) {
+	s := fullSystem(t)
+	delete(s.Masses, 2) // the mass of the hydrogen is not filled in yet
+
+	if err := NewEncoder(&bytes.Buffer{}).Encode(s); err == nil {
+		t.Fatal("Encode = nil with a missing mass")
+	}
+	out := encodeString(t, s, func(enc *Encoder) { enc.SetSkipCheck(true) })
+	for _, want := range []string{"\nMasses\n\n1 15.9994\n\n", "\n6 atoms\n", "\nAtoms"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Encode = %q does not contain %q", out, want)
+		}
+	}
+}
+
+func TestEncodeFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		formats map[key.Column]string
+		want    string
+	}{
+		{"default", nil, "\n2 1 2 0.4238 1.8 1.5 1\n"},
+		{"coordinates and charge", map[key.Column]string{
+			key.ColumnX: "%.10f", key.ColumnY: "%.10f", key.ColumnZ: "%.10f", key.ColumnQ: "%.2f",
+		}, "\n2 1 2 0.42 1.8000000000 1.5000000000 1.0000000000\n"},
+		{"molecule tag", map[key.Column]string{key.ColumnMolTag: "%03d"}, "\n2 001 2 0.4238 1.8 1.5 1\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := encodeString(t, fullSystem(t), func(enc *Encoder) {
+				for c, f := range tt.formats {
+					enc.SetFormat(c, f)
+				}
+			})
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("Encode = %q does not contain %q", out, tt.want)
+			}
+		})
+	}
+
+	// an empty format restores the default one.
+	out := encodeString(t, fullSystem(t), func(enc *Encoder) {
+		enc.SetFormat(key.ColumnX, "%.3f")
+		enc.SetFormat(key.ColumnX, "")
+	})
+	if !strings.Contains(out, "\n2 1 2 0.4238 1.8 1.5 1\n") {
+		t.Errorf("Encode = %q does not use the default format", out)
+	}
+}
+
+func TestEncodeCheckAtoms(t *testing.T) {
+	tests := []struct {
+		name       string
+		remove     int // atom removed from the system, 0 for none
+		checkAtoms bool
+		wantErr    string
+	}{
+		{"complete", 0, true, ""},
+		{"dangling bond", 3, true, "atom = 3 of id = 2 of Bonds does not exist in Atoms"},
+		{"dangling bond of the second molecule", 5, true, "atom = 5 of id = 3 of Bonds does not exist in Atoms"},
+		{"disabled", 3, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			delete(s.Atoms, tt.remove)
+			// the identifiers are not contiguous: only SetCheckAtoms can
+			// catch the dangling links.
+			enc := NewEncoder(&bytes.Buffer{})
+			enc.SetSkipCheck(true)
+			enc.SetCheckAtoms(tt.checkAtoms)
+			err := enc.Encode(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Encode = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEncodeElements(t *testing.T) {
+	out := encodeString(t, fullSystem(t), func(enc *Encoder) {
+		enc.SetElements(map[int]string{1: "O", 2: "H"})
+	})
+	golden(t, "elements.data", out)
+
+	// the annotated file is decoded as the original one.
+	var s system
+	if err := decodeString(out, &s); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := encodeString(t, &s), encodeString(t, fullSystem(t)); got != want {
+		t.Errorf("decoded = %s\nwant:\n%s", got, want)
+	}
+
+	tests := []struct {
+		name     string
+		elements map[int]string
+		want     string
+	}{
+		{"partial", map[int]string{1: "O"}, "\nMasses\n\n1 15.9994 # O\n2 1.008\n\n"},
+		{"unused type", map[int]string{3: "C"}, "\nMasses\n\n1 15.9994\n2 1.008\n\n"},
+		{"nil", nil, "\nMasses\n\n1 15.9994\n2 1.008\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := encodeString(t, fullSystem(t), func(enc *Encoder) { enc.SetElements(tt.elements) })
+			if !strings.Contains(out, tt.want) {
+				t.Errorf("Encode = %q does not contain %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeBoxFormat(t *testing.T) {
+	a := atomic{
+		Title:     "LAMMPS data file via write_data",
+		AtomsNbr:  1,
+		AtomTypes: 1,
+		X:         [2]float64{-5, 5},
+		Y:         [2]float64{-2.5, 12.345678901234567},
+		Z:         [2]float64{0, 1e-3},
+		Masses:    map[int]float64{1: 39.948},
+		Atoms:     map[int]*key.Atom{1: {AtomType: 1}},
+	}
+	out := encodeString(t, &a, func(enc *Encoder) { enc.SetBoxFormat(key.WriteDataBoxFormat) })
+	golden(t, "writedata_box.data", out)
+
+	// the bounds are decoded without loss.
+	var a2 atomic
+	if err := decodeString(out, &a2); err != nil {
+		t.Fatal(err)
+	}
+	if a2.X != a.X || a2.Y != a.Y || a2.Z != a.Z {
+		t.Errorf("box = %v %v %v, want %v %v %v", a2.X, a2.Y, a2.Z, a.X, a.Y, a.Z)
+	}
+
+	// an empty format restores %g.
+	out = encodeString(t, &a, func(enc *Encoder) {
+		enc.SetBoxFormat(key.WriteDataBoxFormat)
+		enc.SetBoxFormat("")
+	})
+	if !strings.Contains(out, "\n-5 5 xlo xhi\n-2.5 12.345678901234567 ylo yhi\n0 0.001 zlo zhi\n") {
+		t.Errorf("Encode = %q does not use the default format", out)
+	}
+}
+
+func TestEncodeTrailingZero(t *testing.T) {
+	tests := []struct {
+		name   string
+		zero   bool
+		golden string
+	}{
+		{"default", false, "wholefloats.data"},
+		{"trailing zero", true, "trailingzero.data"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			s.Masses[1] = 16
+			out := encodeString(t, s, func(enc *Encoder) { enc.SetTrailingZero(tt.zero) })
+			golden(t, tt.golden, out)
+
+			// the floats are decoded to the same values.
+			var s2 system
+			if err := decodeString(out, &s2); err != nil {
+				t.Fatal(err)
+			}
+			if got := encodeString(t, &s2); got != encodeString(t, s) {
+				t.Errorf("Encode of the decoded file = %s\nwant:\n%s", got, encodeString(t, s))
+			}
+		})
+	}
+
+	// the formats of Formats and BoxFormat are not changed.
+	s := fullSystem(t)
+	out := encodeString(t, s, func(enc *Encoder) {
+		enc.SetTrailingZero(true)
+		enc.SetFormat(key.ColumnX, "%.2f")
+		enc.SetBoxFormat("%.1f")
+	})
+	if !strings.Contains(out, "\n0.0 10.0 xlo xhi\n") || !strings.Contains(out, "\n1 1 1 -0.8476 1.00 1.0 1.0\n") {
+		t.Errorf("Encode = %s", out)
+	}
+}
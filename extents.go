@@ -0,0 +1,37 @@
+package lmpsdat
+
+import "github.com/kpotier/lmpsdat/key"
+
+// Extents returns the minimum and maximum coordinates of the atoms for the x,
+// y, and z coordinates, for all the atoms and for each atom type. It is useful
+// to spot an atom that is far from the others. The extents are zero if there is
+// no atom.
+func Extents(atoms map[int]*key.Atom) (all BoxDims, byType map[int]BoxDims) {
+	byType = make(map[int]BoxDims)
+	first := true
+	for _, atom := range atoms {
+		if atom == nil {
+			continue
+		}
+		pos := [3]float64{atom.X, atom.Y, atom.Z}
+		t, ok := byType[atom.AtomType]
+		byType[atom.AtomType] = extend(t, pos, !ok)
+		all = extend(all, pos, first)
+		first = false
+	}
+	return all, byType
+}
+
+// extend returns the extents b enlarged to contain pos. If init is true, the
+// extents are only made of pos.
+func extend(b BoxDims, pos [3]float64, init bool) BoxDims {
+	for i, v := range pos {
+		if init || v < b[i][0] {
+			b[i][0] = v
+		}
+		if init || v > b[i][1] {
+			b[i][1] = v
+		}
+	}
+	return b
+}
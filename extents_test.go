@@ -0,0 +1,31 @@
+package lmpsdat
+
+import (
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestExtents(t *testing.T) {
+	all, byType := Extents(fullSystem(t).Atoms)
+	if want := (BoxDims{{0.2, 5.8}, {1, 5.5}, {1, 5}}); all != want {
+		t.Errorf("extents = %v, want %v", all, want)
+	}
+	want := map[int]BoxDims{
+		1: {{1, 5}, {1, 5}, {1, 5}},
+		2: {{0.2, 5.8}, {1.5, 5.5}, {1, 5}},
+	}
+	if len(byType) != len(want) {
+		t.Fatalf("extents by type = %v, want %v", byType, want)
+	}
+	for typ, w := range want {
+		if byType[typ] != w {
+			t.Errorf("extents of type = %d = %v, want %v", typ, byType[typ], w)
+		}
+	}
+
+	all, byType = Extents(map[int]*key.Atom{})
+	if all != (BoxDims{}) || len(byType) != 0 {
+		t.Errorf("extents without atom = %v, %v, want zero", all, byType)
+	}
+}
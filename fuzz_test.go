@@ -0,0 +1,125 @@
+package lmpsdat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// fuzzSystem extends system with the tables that are not in
+// testdata/full.data.
+type fuzzSystem struct {
+	system
+
+	DihedralsNbr     int               `lmpsdat:"dihedrals"`
+	DihedralTypes    int               `lmpsdat:"dihedral types"`
+	ImproperTypes    int               `lmpsdat:"improper types"`
+	DihedralCoeffs   map[int][]float64 `lmpsdat:"Dihedral Coeffs"`
+	BondBondCoeffs   map[int][]float64 `lmpsdat:"BondBond Coeffs"`
+	AngleAngleCoeffs map[int][]float64 `lmpsdat:"AngleAngle Coeffs"`
+	Dihedrals        map[int]*key.Link `lmpsdat:"Dihedrals"`
+}
+
+// fuzzSeeds returns testdata/full.data and its truncated and garbled variants.
+func fuzzSeeds(t testing.TB) []string {
+	full := readFile(t, "full.data")
+	seeds := []string{
+		full,
+		"",
+		"\n",
+		"title\n\n1 atoms\n",
+		cut(t, full, "Atoms"),
+		cut(t, full, "3 1 2 0.4238 0.2 1.5 1"),
+		cut(t, full, "2 1 1 3"),
+		strings.Replace(full, "6 atoms", "-6 atoms", 1),
+		strings.Replace(full, "6 atoms", "99999999999999999999 atoms", 1),
+		strings.Replace(full, "2 atom types", "0 atom types", 1),
+		strings.Replace(full, "1 1 1 2", "1 1 1", 1),
+		strings.Replace(full, "1 1 1 2", "1 1 1 7", 1),
+		strings.Replace(full, "1 1 2 1 3", "1 1 2 1 3 # c", 1),
+		strings.Replace(full, "2 1.008", "2", 1),
+		strings.Replace(full, "1 450 1", "1", 1),
+		strings.Replace(full, "1 1 1 -0.8476 1 1 1", "1 1 1 -0.8476 1 1 1 0 0", 1),
+		strings.Replace(full, "1 1 1 -0.8476 1 1 1", "1 1 1 -0.8476 1 1 1 0 0 x", 1),
+		strings.Replace(full, "0 10 xlo xhi", "10 0 xlo xhi", 1),
+		strings.Replace(full, "0 10 xlo xhi", "xlo 0\nxhi 10", 1),
+		strings.Replace(full, "Pair Coeffs", "Pair Coeffs # hybrid", 1),
+		strings.Replace(full, "1 0.1553 3.166", "* 0.1553 3.166", 1),
+		strings.Replace(strings.Replace(full, "1 0.1553 3.166", "* 0.1553 3.166", 1), "2 atom types", "300000000 atom types", 1),
+		strings.Replace(full, "Atoms", "Atoms # atomic", 1),
+		strings.Replace(full, "Masses", "masses", 1),
+		strings.Replace(full, "Bonds\n", "Impropers\n\n1 1 1 2 3 4\n\nBonds\n", 1),
+		strings.Replace(full, "Angles\n", "Masses\n\n1 1\n2 1\n\nAngles\n", 1),
+		full + "\n" + FrameSeparator + "\n" + full,
+		full + "\nDihedrals\n\n1 1 1 2 3 4\n",
+		full + "\nBondBond Coeffs\n\n1 1 1 1\n",
+	}
+	return seeds
+}
+
+// fuzzOptions configures dec from the bits of opts.
+func fuzzOptions(dec *Decoder, opts uint16) {
+	dec.SetFortranExponent(opts&(1<<0) != 0)
+	dec.SetExtraColumns(opts&(1<<1) != 0)
+	dec.SetDetectAtomStyle(opts&(1<<2) != 0)
+	dec.SetContiguousIDs(opts&(1<<3) != 0)
+	dec.SetCaseInsensitive(opts&(1<<4) != 0)
+	dec.SetFlatZ(opts&(1<<5) != 0)
+	dec.SetSplitBox(opts&(1<<6) != 0)
+	dec.SetWildcard(opts&(1<<7) != 0)
+	dec.SetStrictMasses(opts&(1<<8) != 0)
+	dec.SetMixedImageFlags(opts&(1<<9) != 0)
+	dec.SetUniformColumns(opts&(1<<10) != 0)
+	dec.SetStyleCheck(opts&(1<<11) != 0)
+	dec.SetThousandsSeparator(opts&(1<<12) != 0)
+	dec.SetStrictOrder(opts&(1<<13) != 0)
+	if opts&(1<<14) != 0 {
+		dec.SetBestEffort(func(key.Name, error) {})
+	}
+	if opts&(1<<15) != 0 {
+		dec.SetTitleLines(2)
+	}
+}
+
+// FuzzDecode verifies that Decode returns an error instead of panicking on
+// malformed input. A decoded value must be encoded without error and the
+// output must be decoded again.
+func FuzzDecode(f *testing.F) {
+	for _, s := range fuzzSeeds(f) {
+		f.Add([]byte(s), uint16(0))
+		f.Add([]byte(s), uint16(0xffff))
+	}
+	f.Fuzz(func(t *testing.T, data []byte, opts uint16) {
+		var v fuzzSystem
+		dec := NewDecoder(bytes.NewReader(data))
+		fuzzOptions(dec, opts)
+		if err := dec.Decode(&v); err != nil {
+			return
+		}
+		for dec.More() {
+			var frame fuzzSystem
+			if err := dec.Decode(&frame); err != nil {
+				break
+			}
+		}
+
+		var b bytes.Buffer
+		enc := NewEncoder(&b)
+		enc.SetFlatZ(opts&(1<<5) != 0)
+		if err := enc.Encode(&v); err != nil {
+			return // e.g. an Atoms table detected as atomic
+		}
+		var v2 fuzzSystem
+		dec = NewDecoder(&b)
+		dec.SetFlatZ(opts&(1<<5) != 0)
+		dec.SetExtraColumns(opts&(1<<1) != 0)
+		if opts&(1<<15) != 0 {
+			dec.SetTitleLines(2)
+		}
+		if err := dec.Decode(&v2); err != nil {
+			t.Fatalf("Decode of the encoded value: %v\n%s", err, b.String())
+		}
+	})
+}
@@ -1,3 +1,5 @@
 module github.com/kpotier/lmpsdat
 
-go 1.15
+go 1.22
+
+require github.com/klauspost/compress v1.18.0
@@ -0,0 +1,132 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// BondGraph returns the bond graph as adjacency lists: each atom identifier of
+// atoms is mapped to the identifiers of the atoms it is bonded to, sorted in
+// increasing order and without duplicates. An atom without bonds is mapped to
+// an empty list. The bonds that are nil, that do not have two atoms, or that
+// reference an atom that does not exist in atoms are ignored.
+func BondGraph(atoms map[int]*key.Atom, bonds map[int]*key.Link) map[int][]int {
+	neighbors := make(map[int]map[int]bool, len(atoms))
+	for id := range atoms {
+		neighbors[id] = make(map[int]bool)
+	}
+	for _, bond := range bonds {
+		if bond == nil {
+			continue
+		}
+		a := bond.Atoms()
+		if len(a) != 2 || a[0] == a[1] {
+			continue
+		}
+		n1, ok1 := neighbors[a[0]]
+		n2, ok2 := neighbors[a[1]]
+		if !ok1 || !ok2 {
+			continue
+		}
+		n1[a[1]] = true
+		n2[a[0]] = true
+	}
+
+	graph := make(map[int][]int, len(neighbors))
+	for id, n := range neighbors {
+		adj := make([]int, 0, len(n))
+		for a := range n {
+			adj = append(adj, a)
+		}
+		sort.Ints(adj)
+		graph[id] = adj
+	}
+	return graph
+}
+
+// Components returns the connected components of graph (see BondGraph), i.e.
+// the groups of atoms linked by a chain of bonds such as the molecules. The
+// identifiers of each component are sorted in increasing order and the
+// components are sorted by their first identifier. An isolated atom is a
+// component on its own.
+func Components(graph map[int][]int) [][]int {
+	ids := make([]int, 0, len(graph))
+	for id := range graph {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	seen := make(map[int]bool, len(graph))
+	var components [][]int
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		component := []int{id}
+		for i := 0; i < len(component); i++ {
+			for _, a := range graph[component[i]] {
+				if _, ok := graph[a]; ok && !seen[a] {
+					seen[a] = true
+					component = append(component, a)
+				}
+			}
+		}
+		sort.Ints(component)
+		components = append(components, component)
+	}
+	return components
+}
+
+// ValidateMolecules verifies that the molecule tags of the atoms (see
+// Atom.MolTag) match the bond connectivity: the two atoms of each bond must
+// have the same molecule tag, and the atoms having the same molecule tag must
+// be connected by a chain of bonds (see Components). The atoms whose molecule
+// tag is zero (i.e. not part of a molecule) are not verified.
+//
+// The first bond crossing two molecules is reported in increasing order of
+// identifier, then the first molecule split into several components.
+func ValidateMolecules(atoms map[int]*key.Atom, bonds map[int]*key.Link) error {
+	for id, atom := range atoms {
+		if atom == nil {
+			return fmt.Errorf("atom = %d is nil", id)
+		}
+	}
+	ids := make([]int, 0, len(bonds))
+	for id := range bonds {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		bond := bonds[id]
+		if bond == nil {
+			return fmt.Errorf("bond = %d is nil", id)
+		}
+		a := bond.Atoms()
+		if len(a) != 2 {
+			return fmt.Errorf("bond = %d has %d atoms, want 2", id, len(a))
+		}
+		a1, a2 := atoms[a[0]], atoms[a[1]]
+		if a1 == nil || a2 == nil {
+			return fmt.Errorf("atoms = %d, %d of bond = %d do not exist", a[0], a[1], id)
+		}
+		if a1.MolTag != a2.MolTag {
+			return fmt.Errorf("bond = %d links atom = %d of molecule = %d to atom = %d of molecule = %d", id, a[0], a1.MolTag, a[1], a2.MolTag)
+		}
+	}
+
+	first := make(map[int]int) // first atom of each molecule tag, by component
+	for _, component := range Components(BondGraph(atoms, bonds)) {
+		tag := atoms[component[0]].MolTag
+		if tag == 0 {
+			continue
+		}
+		if atom, ok := first[tag]; ok {
+			return fmt.Errorf("atoms = %d and %d of molecule = %d are not connected by bonds", atom, component[0], tag)
+		}
+		first[tag] = component[0]
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package lmpsdat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestBondGraph(t *testing.T) {
+	s := fullSystem(t)
+	tests := []struct {
+		name       string
+		bonds      map[int]*key.Link
+		graph      map[int][]int
+		components [][]int
+	}{
+		{"water", s.Bonds,
+			map[int][]int{1: {2, 3}, 2: {1}, 3: {1}, 4: {5, 6}, 5: {4}, 6: {4}},
+			[][]int{{1, 2, 3}, {4, 5, 6}}},
+		{"without bonds", nil,
+			map[int][]int{1: {}, 2: {}, 3: {}, 4: {}, 5: {}, 6: {}},
+			[][]int{{1}, {2}, {3}, {4}, {5}, {6}}},
+		{"ignored bonds", map[int]*key.Link{
+			1: key.NewLink(1, 1, 2),
+			2: key.NewLink(1, 2, 1), // duplicate
+			3: key.NewLink(1, 3, 3), // same atom
+			4: key.NewLink(1, 4, 7), // missing atom
+			5: nil,
+			6: key.NewLink(1, 4, 5, 6), // not 2 atoms
+		},
+			map[int][]int{1: {2}, 2: {1}, 3: {}, 4: {}, 5: {}, 6: {}},
+			[][]int{{1, 2}, {3}, {4}, {5}, {6}}},
+		{"chain across molecules", map[int]*key.Link{
+			1: key.NewLink(1, 1, 2),
+			2: key.NewLink(1, 2, 3),
+			3: key.NewLink(1, 3, 6),
+			4: key.NewLink(1, 6, 5),
+		},
+			map[int][]int{1: {2}, 2: {1, 3}, 3: {2, 6}, 4: {}, 5: {6}, 6: {3, 5}},
+			[][]int{{1, 2, 3, 5, 6}, {4}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			graph := BondGraph(s.Atoms, tt.bonds)
+			if !reflect.DeepEqual(graph, tt.graph) {
+				t.Errorf("BondGraph = %v, want %v", graph, tt.graph)
+			}
+			if got := Components(graph); !reflect.DeepEqual(got, tt.components) {
+				t.Errorf("Components = %v, want %v", got, tt.components)
+			}
+		})
+	}
+}
+
+func TestValidateMolecules(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(s *system)
+		wantErr string
+	}{
+		{"valid", func(s *system) {}, ""},
+		{"mis-tagged atom", func(s *system) { s.Atoms[3].MolTag = 2 },
+			"bond = 2 links atom = 1 of molecule = 1 to atom = 3 of molecule = 2"},
+		{"cross-molecule bond", func(s *system) { s.Bonds[5] = key.NewLink(1, 3, 4) },
+			"bond = 5 links atom = 3 of molecule = 1 to atom = 4 of molecule = 2"},
+		{"molecule not connected", func(s *system) {
+			for _, id := range []int{4, 5, 6} {
+				s.Atoms[id].MolTag = 1
+			}
+		}, "atoms = 1 and 4 of molecule = 1 are not connected by bonds"},
+		{"atom without molecule", func(s *system) { s.Atoms[7] = &key.Atom{AtomType: 1} }, ""},
+		{"missing atom", func(s *system) { s.Bonds[5] = key.NewLink(1, 3, 7) }, "atoms = 3, 7 of bond = 5 do not exist"},
+		{"nil bond", func(s *system) { s.Bonds[5] = nil }, "bond = 5 is nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			tt.modify(s)
+			err := ValidateMolecules(s.Atoms, s.Bonds)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateMolecules = %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ValidateMolecules = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// CheckImageFlags verifies that the image flags of the atoms are equal to zero
+// for the dimensions that are not periodic. The periodicity of the x, y, and z
+// dimensions is given by periodic as it is not stored in the LAMMPS data file.
+// The atoms without image flags (N set to false) are ignored.
+func CheckImageFlags(atoms map[int]*key.Atom, periodic [3]bool) error {
+	for _, id := range atomIDs(atoms) {
+		atom := atoms[id]
+		if atom == nil || !atom.N {
+			continue
+		}
+		for i, n := range [3]int{atom.NX, atom.NY, atom.NZ} {
+			if !periodic[i] && n != 0 {
+				return fmt.Errorf("atom = %d has image flag = %d in the non-periodic dimension %c", id, n, "xyz"[i])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package lmpsdat
+
+import (
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestCheckImageFlags(t *testing.T) {
+	atoms := map[int]*key.Atom{
+		1: {AtomType: 1, N: true, NX: 1, NY: 0, NZ: 0},
+		2: {AtomType: 1, N: true, NX: 0, NY: 0, NZ: -2},
+		3: {AtomType: 1, NY: 5}, // no image flags
+	}
+	tests := []struct {
+		name     string
+		periodic [3]bool
+		wantErr  bool
+	}{
+		{"periodic", [3]bool{true, true, true}, false},
+		{"slab", [3]bool{true, true, false}, true},
+		{"wire", [3]bool{true, false, true}, false},
+		{"non-periodic", [3]bool{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CheckImageFlags(atoms, tt.periodic); (err != nil) != tt.wantErr {
+				t.Errorf("CheckImageFlags = %v, want error = %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package key
+
+import "strconv"
+
+// AtomF32 is an Atom whose floats (e.g. the coordinates and the charge) are
+// stored as float32 to reduce the memory used by very large systems. The
+// precision is about 7 significant digits: it is suited to read-only analyses.
+//
+// The Atoms are decoded into AtomF32 if Options.Float32 is true. The field of
+// the structure must then be map[int]*key.AtomF32. Each row is decoded into an
+// Atom that is immediately converted, so that every atom style is supported.
+// The Atom is reused for each row if the atom style implements IntoDecoder.
+type AtomF32 struct {
+	MolTag   int
+	AtomType int
+	Q        float32
+	X        float32
+	Y        float32
+	Z        float32
+
+	// See Atom.
+	Mass    float32
+	Volume  float32
+	Density float32
+	Flag    int
+	Spin    int
+	ERadius float32
+	ETag    int
+	CSRe    float32
+	CSIm    float32
+
+	// if N is set to true, NX, NY, and NZ must be specified.
+	N  bool
+	NX int
+	NY int
+	NZ int
+}
+
+// NewAtomF32 returns a copy of atom whose floats are converted to float32. It
+// returns nil if atom is nil.
+func NewAtomF32(atom *Atom) *AtomF32 {
+	if atom == nil {
+		return nil
+	}
+	a := new(AtomF32)
+	a.set(atom)
+	return a
+}
+
+// set assigns to a the values of atom whose floats are converted to float32.
+func (a *AtomF32) set(atom *Atom) {
+	*a = AtomF32{
+		MolTag:   atom.MolTag,
+		AtomType: atom.AtomType,
+		Q:        float32(atom.Q),
+		X:        float32(atom.X),
+		Y:        float32(atom.Y),
+		Z:        float32(atom.Z),
+		Mass:     float32(atom.Mass),
+		Volume:   float32(atom.Volume),
+		Density:  float32(atom.Density),
+		Flag:     atom.Flag,
+		Spin:     atom.Spin,
+		ERadius:  float32(atom.ERadius),
+		ETag:     atom.ETag,
+		CSRe:     float32(atom.CSRe),
+		CSIm:     float32(atom.CSIm),
+		N:        atom.N,
+		NX:       atom.NX,
+		NY:       atom.NY,
+		NZ:       atom.NZ,
+	}
+}
+
+// Atom returns a copy of a whose floats are converted to float64. Each float is
+// the shortest decimal representation of the float32 (e.g. 0.1 and not
+// 0.10000000149011612) so that the atom is encoded as it was decoded. It
+// returns nil if a is nil.
+func (a *AtomF32) Atom() *Atom {
+	if a == nil {
+		return nil
+	}
+	atom := new(Atom)
+	a.atomInto(atom)
+	return atom
+}
+
+// atomInto works like Atom but the values are assigned to atom.
+func (a *AtomF32) atomInto(atom *Atom) {
+	*atom = Atom{
+		MolTag:   a.MolTag,
+		AtomType: a.AtomType,
+		Q:        float64of32(a.Q),
+		X:        float64of32(a.X),
+		Y:        float64of32(a.Y),
+		Z:        float64of32(a.Z),
+		Mass:     float64of32(a.Mass),
+		Volume:   float64of32(a.Volume),
+		Density:  float64of32(a.Density),
+		Flag:     a.Flag,
+		Spin:     a.Spin,
+		ERadius:  float64of32(a.ERadius),
+		ETag:     a.ETag,
+		CSRe:     float64of32(a.CSRe),
+		CSIm:     float64of32(a.CSIm),
+		N:        a.N,
+		NX:       a.NX,
+		NY:       a.NY,
+		NZ:       a.NZ,
+	}
+}
+
+// float64of32 converts f into the float64 having the same shortest decimal
+// representation.
+func float64of32(f float32) float64 {
+	var buf [32]byte
+	v, err := strconv.ParseFloat(string(strconv.AppendFloat(buf[:0], float64(f), 'g', -1, 32)), 64)
+	if err != nil {
+		return float64(f)
+	}
+	return v
+}
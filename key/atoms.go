@@ -2,9 +2,10 @@ package key
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
-	"strings"
+	"math"
 )
 
 // Atom contains information about a particular atom. For instance, it has the
@@ -14,7 +15,8 @@ import (
 // This structure is used by Atoms. A map where the keys are the identifiers of
 // the atoms and the values are a pointer of Atom can be obtained or set with
 // the Set or Get methods. Decode and Encode methods of Atoms make use of this
-// map to encode/decode a table to/from a LAMMPS data file.
+// map to encode/decode a table to/from a LAMMPS data file. The identifiers are
+// int: a 64-bit platform is required for identifiers greater than 2^31-1.
 //
 // The meaning of each term can be found in the LAMMPS documentation.
 type Atom struct {
@@ -25,6 +27,31 @@ type Atom struct {
 	Y        float64
 	Z        float64
 
+	// Mass is only used by the atom styles having a mass column (see
+	// ColumnMass).
+	Mass float64
+
+	// Volume and Density are only used by the atom styles having a volume
+	// and a density column such as AtomStylePeri.
+	Volume  float64
+	Density float64
+
+	// Flag is only used by AtomStyleLine and AtomStyleTri. It is the lineflag
+	// or the triangleflag: 1 if the atom has bonus data in the Lines or
+	// Triangles table, 0 otherwise.
+	Flag int
+
+	// Spin and ERadius are only used by AtomStyleElectron and
+	// AtomStyleWavepacket: the spin of the electron (0 for a nucleus) and its
+	// radius. ETag, CSRe, and CSIm are only used by AtomStyleWavepacket: the
+	// electron the wave packet belongs to and the real and imaginary parts of
+	// its split coefficient.
+	Spin    int
+	ERadius float64
+	ETag    int
+	CSRe    float64
+	CSIm    float64
+
 	// if N is set to true, NX, NY, and NZ must be specified.
 	N  bool
 	NX int
@@ -43,24 +70,53 @@ type Atoms struct {
 	atomStyle AtomStyle
 	atomsNbr  *Header
 	atomTypes *Header
+	opts      *Options
 	v         map[int]*Atom
+	v32       map[int]*AtomF32 // used instead of v if Options.Float32 is true
+	filled    int              // number of image flags set by fillImageFlags
 }
 
-// NewAtoms returns an instance of Atoms with a specific atom style. It panics
-// if the Atom Style does not exist.
+// NewAtoms returns an instance of Atoms with a specific atom style. If as is
+// nil, the atom style is not specified: AtomStyleFull is used unless the atom
+// style is detected by the Decode method (see Options.DetectAtomStyle).
 func NewAtoms(as AtomStyle) *Atoms {
 	return &Atoms{atomStyle: as}
 }
 
+// AtomStyle returns the atom style used to encode and decode the atoms.
+func (a *Atoms) AtomStyle() AtomStyle {
+	if a.atomStyle == nil {
+		return AtomStyleFull
+	}
+	return a.atomStyle
+}
+
+// detectAtomStyle returns the atom style named by the comment of the header of
+// the table s (e.g. "Atoms # atomic"). If there is no such comment, it returns
+// the atom style guessed from the number of columns of the first value f: 5 or
+// 8 columns for AtomStyleAtomic, AtomStyleFull otherwise.
+func detectAtomStyle(s []byte, f []string) AtomStyle {
+	if as := NewAtomStyle(headerComment(s)); as != nil {
+		return as
+	}
+	switch len(f) {
+	case 5, 8:
+		return AtomStyleAtomic
+	default:
+		return AtomStyleFull
+	}
+}
+
 // Name returns NameAtoms. It corresponds to the header of the table.
 func (a *Atoms) Name() Name {
 	return NameAtoms
 }
 
 // Keyword tests whether the byte slice s begins with Name after trimming the
-// spaces. Keyword is useful to detect the header of the Atoms table.
+// spaces. Keyword is useful to detect the header of the Atoms table. The case
+// is ignored if Options.CaseInsensitive is true.
 func (a *Atoms) Keyword(s []byte) bool {
-	return keyword(s, []byte(a.Name()))
+	return a.opts.keyword(s, a.Name())
 }
 
 // SetKeys assigns one or more Keys to Atoms. This method only accepts *Header
@@ -83,33 +139,53 @@ func (a *Atoms) SetKeys(k ...Key) error {
 	return nil
 }
 
+// SetOptions assigns the Options used by the Keyword, Encode, and Decode
+// methods. o can be nil.
+func (a *Atoms) SetOptions(o *Options) {
+	a.opts = o
+}
+
 // Encode writes a table containing the header, a blank line and each value (= 1
-// line) (atom) into a writer.
+// line) (atom) into a writer. The columns are written with Options.Formats if
+// the atom style implements FormatEncoder.
 //
 // This method does not check the integrity and correctness of each value. To do
 // so, use the Check method.
 func (a *Atoms) Encode(w io.Writer) error {
-	if a.v == nil {
+	if a.v == nil && a.v32 == nil {
 		return fmt.Errorf("map[int]*Atom is nil: use the Decode or Set methods")
 	}
-	if len(a.v) == 0 {
+	if a.Len() == 0 {
 		return nil
 	}
 
-	keys := sortIntsMap(a.v)
+	keys := sortIntsMap(a.Get())
 	fmt.Fprint(w, a.Name(), "\n\n")
+	var scratch Atom
 	for _, k := range keys {
 		var err error
-		var v = a.v[k]
+		var v = a.atom(k, &scratch)
+		if v == nil {
+			return fmt.Errorf("atom = %d is nil", k)
+		}
 
 		_, err = fmt.Fprintf(w, "%d ", k)
 		if err != nil {
 			return fmt.Errorf("fmt.Fprintf id: %w", err)
 		}
 
-		err = a.atomStyle.Encode(v, w)
+		fe, ok := a.AtomStyle().(FormatEncoder)
+		cols := Columns(a.AtomStyle())
+		switch {
+		case a.opts != nil && a.opts.TrailingZero && cols != nil:
+			err = encodeColumns(v, w, cols, a.opts.Formats, true)
+		case ok && a.opts != nil && len(a.opts.Formats) > 0:
+			err = fe.EncodeFormat(v, w, a.opts.Formats)
+		default:
+			err = a.AtomStyle().Encode(v, w)
+		}
 		if err != nil {
-			return fmt.Errorf("a.atomStyle.Encode named %s: %w", a.atomStyle.Name(), err)
+			return fmt.Errorf("a.atomStyle.Encode named %s: %w", a.AtomStyle().Name(), err)
 		}
 
 		if v.N {
@@ -132,60 +208,334 @@ func (a *Atoms) Encode(w io.Writer) error {
 // This method needs a Key in order to work. This Key is an instance of Header
 // with Name equal to NameAtomsNbr. Use the Set method to assign this Key.
 //
+// The blank lines between the values are skipped and are not counted.
+//
 // Moreover, this method does not check the integrity and corectness of the
 // values decoded. To do so, use the Check method.
 //
-// Decode method does not return io.EOF error. The use of the Check method after
-// Decode is therefore highly recommended.
+// If Options.MixedImageFlags is true and some atoms have the image flags, the
+// image flags of the other atoms are set to 0 0 0.
+//
+// If Options.StyleCheck is true, the atoms are inspected once decoded and an
+// error wrapping ErrStyleMismatch is returned if their values suggest that the
+// atom style does not match the file. The error of the first row, e.g. a wrong
+// number of columns, also wraps ErrStyleMismatch.
+//
+// If Options.Float32 is true, the atoms are stored as AtomF32: the Get method
+// returns a map[int]*AtomF32.
+//
+// Decode method does not return io.EOF error. If the input ends before the
+// number of expected atoms is read, an error wrapping ErrTruncated is returned.
 func (a *Atoms) Decode(s []byte, r *bufio.Scanner) error {
+	hdr := append([]byte(nil), s...) // s is overwritten by r.Scan
 	if a.atomsNbr == nil {
 		return fmt.Errorf("Key that is an instance of *Header with Name equal to NameAtomsNbr is nil: use the Set method")
 	}
 
+	atomsNbr := a.atomsNbr.Get().(int)
 	if ok := r.Scan(); !ok {
 		if r.Err() != nil {
 			return fmt.Errorf("r.Scan first line: %w", r.Err())
 		}
+		if atomsNbr > 0 {
+			return truncated(a.Name(), 0, atomsNbr)
+		}
 		return nil
 	}
 
-	a.v = make(map[int]*Atom)
-	atomsNbr := a.atomsNbr.Get().(int)
-	for i := 0; i < atomsNbr && r.Scan(); i++ {
+	a.v, a.v32 = make(map[int]*Atom), nil
+	if a.opts != nil && a.opts.Float32 {
+		a.v, a.v32 = nil, make(map[int]*AtomF32)
+	}
+	var scratch Atom // reused for each row if Options.Float32 is true
+	i, width := 0, 0
+	for ; i < atomsNbr && scanValue(r); i++ {
 		s := delComments(r.Bytes())
-		f := strings.Fields(string(s))
-		id, atom, err := a.atomStyle.Decode(f)
+		f := a.opts.fields(string(s))
+		if i == 0 {
+			width = len(f)
+		} else if a.opts != nil && !a.opts.MixedImageFlags {
+			if err := a.opts.width(a.Name(), i+1, len(f), width); err != nil {
+				return err
+			}
+		}
+		if i == 0 && a.atomStyle == nil && a.opts != nil && a.opts.DetectAtomStyle {
+			a.atomStyle = detectAtomStyle(hdr, f)
+		}
+		a.integers(f)
+		var id int
+		var atom *Atom
+		var err error
+		if into, ok := a.AtomStyle().(IntoDecoder); ok && a.v32 != nil {
+			id, err = into.DecodeInto(f, &scratch)
+			atom = &scratch
+		} else {
+			id, atom, err = a.AtomStyle().Decode(f)
+		}
 		if err != nil {
+			var pe *ParseError
+			if errors.As(err, &pe) {
+				err = setLine(err, a.Name(), i+1)
+			} else {
+				err = fmt.Errorf("row = %d: %w", i+1, err)
+			}
+			if i == 0 && a.opts != nil && a.opts.StyleCheck {
+				return fmt.Errorf("%w: the atom style = %s may not match the file: %w", ErrStyleMismatch, a.AtomStyle().Name(), err)
+			}
 			return err
 		}
-		a.v[id] = atom
+		if a.v32 != nil {
+			a.v32[id] = NewAtomF32(atom)
+		} else {
+			a.v[id] = atom
+		}
 	}
 	if r.Err() != nil {
 		return fmt.Errorf("r.Scan: %w", r.Err())
 	}
+	if i < atomsNbr {
+		return truncated(a.Name(), i, atomsNbr)
+	}
+	if a.opts != nil && a.opts.MixedImageFlags {
+		a.fillImageFlags()
+	}
+	if a.opts != nil && a.opts.StyleCheck {
+		return a.checkStyle()
+	}
 	return nil
 }
 
-// Set puts a custom map[int]*Atom.
+// integers removes the thousands separators of the fields f of a row that are
+// integers for the atom style (see Options.ThousandsSeparator): the identifier,
+// the columns decoded into an int of Atom, and the image flags. Only the
+// identifier is modified if the columns of the atom style are not known.
+func (a *Atoms) integers(f []string) {
+	if a.opts == nil || !a.opts.ThousandsSeparator || len(f) == 0 {
+		return
+	}
+	f[0] = a.opts.integer(f[0])
+	cols := Columns(a.AtomStyle())
+	if cols == nil {
+		return
+	}
+	for i, c := range cols {
+		switch c {
+		case ColumnMolTag, ColumnAtomType, ColumnLineFlag, ColumnTriangleFlag, ColumnSpin, ColumnETag:
+			if i+1 < len(f) {
+				f[i+1] = a.opts.integer(f[i+1])
+			}
+		}
+	}
+	if n := 1 + len(cols); len(f) == n+3 {
+		for j := n; j < len(f); j++ {
+			f[j] = a.opts.integer(f[j])
+		}
+	}
+}
+
+// maxCharge is the largest absolute charge expected by checkStyle. A larger
+// charge is likely a coordinate read from the wrong column.
+const maxCharge = 10
+
+// checkStyle looks for the values suggesting that the atom style does not
+// match the file: an atom type lower than one or greater than the number of
+// atom types, or a charge greater than maxCharge in absolute value if the atom
+// style has a charge column. It returns an error wrapping ErrStyleMismatch for
+// the first suspicious atom in increasing order of identifier.
+func (a *Atoms) checkStyle() error {
+	var types int
+	if a.atomTypes != nil {
+		types = a.atomTypes.Get().(int)
+	}
+	hasQ := false
+	for _, c := range Columns(a.AtomStyle()) {
+		hasQ = hasQ || c == ColumnQ
+	}
+	var scratch Atom
+	for _, id := range sortIntsMap(a.Get()) {
+		atom := a.atom(id, &scratch)
+		if atom == nil {
+			continue
+		}
+		if atom.AtomType < 1 || (types > 0 && atom.AtomType > types) {
+			return fmt.Errorf("%w: atom = %d has type = %d but there are %d atom types: the atom style = %s may not match the file", ErrStyleMismatch, id, atom.AtomType, types, a.AtomStyle().Name())
+		}
+		if hasQ && math.Abs(atom.Q) > maxCharge {
+			return fmt.Errorf("%w: atom = %d has charge = %g that looks like a coordinate: the atom style = %s may not match the file", ErrStyleMismatch, id, atom.Q, a.AtomStyle().Name())
+		}
+	}
+	return nil
+}
+
+// fillImageFlags sets the image flags of the atoms that do not have them to 0 0
+// 0 if at least one atom has them.
+func (a *Atoms) fillImageFlags() {
+	a.filled = 0
+	n := false
+	for _, atom := range a.v {
+		n = n || atom.N
+	}
+	for _, atom := range a.v32 {
+		n = n || atom.N
+	}
+	if !n {
+		return
+	}
+	for _, atom := range a.v {
+		if !atom.N {
+			atom.N = true
+			atom.NX, atom.NY, atom.NZ = 0, 0, 0
+			a.filled++
+		}
+	}
+	for _, atom := range a.v32 {
+		if !atom.N {
+			atom.N = true
+			atom.NX, atom.NY, atom.NZ = 0, 0, 0
+			a.filled++
+		}
+	}
+}
+
+// Set puts a custom map[int]*Atom or map[int]*AtomF32.
 //
 // This method does not check the integrity or correctness of the passed data.
 // The use of the Check method after Set is therefore highly recommended.
 func (a *Atoms) Set(v interface{}) error {
-	var ok bool
-	a.v, ok = v.(map[int]*Atom)
-	if !ok {
-		return fmt.Errorf("type assertion error: value is not map[int]*Atom)")
+	switch v := v.(type) {
+	case map[int]*Atom:
+		a.v, a.v32 = v, nil
+	case map[int]*AtomF32:
+		a.v, a.v32 = nil, v
+	default:
+		return fmt.Errorf("type assertion error: value is not map[int]*Atom or map[int]*AtomF32")
 	}
 	return nil
 }
 
-// Get returns a map[int]*Atom where the keys are the identifiers of the atoms.
-// As this method returns an interface, it must be useful to perform a type
-// assertion after calling this method.
+// Get returns a map[int]*Atom where the keys are the identifiers of the atoms,
+// or a map[int]*AtomF32 if the atoms were decoded with Options.Float32 or set
+// as AtomF32. As this method returns an interface, it must be useful to perform
+// a type assertion after calling this method.
 func (a *Atoms) Get() interface{} {
+	if a.v32 != nil {
+		return a.v32
+	}
 	return a.v
 }
 
+// has returns true if there is an atom whose identifier is id.
+func (a *Atoms) has(id int) bool {
+	if _, ok := a.v[id]; ok {
+		return true
+	}
+	_, ok := a.v32[id]
+	return ok
+}
+
+// each calls fn for each atom until fn returns an error. An AtomF32 is
+// converted into an Atom that is reused for each call: fn must not keep it.
+func (a *Atoms) each(fn func(id int, atom *Atom) error) error {
+	for id, atom := range a.v {
+		if err := fn(id, atom); err != nil {
+			return err
+		}
+	}
+	var scratch Atom
+	for id := range a.v32 {
+		if err := fn(id, a.atom(id, &scratch)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Map returns the map[int]*Atom where the keys are the identifiers of the
+// atoms. Unlike Get, no type assertion is required. It returns nil if the atoms
+// are stored as AtomF32 (see MapF32). The map is not copied: modifying it
+// modifies the values of Atoms.
+func (a *Atoms) Map() map[int]*Atom {
+	return a.v
+}
+
+// MapF32 works like Map for the atoms stored as AtomF32 (see
+// Options.Float32). It returns nil if the atoms are stored as Atom.
+func (a *Atoms) MapF32() map[int]*AtomF32 {
+	return a.v32
+}
+
+// atom returns the atom whose identifier is id. An AtomF32 is converted into
+// scratch that is returned, so that no Atom is allocated for each call. It
+// returns nil if there is no atom.
+func (a *Atoms) atom(id int, scratch *Atom) *Atom {
+	if a.v32 != nil {
+		v := a.v32[id]
+		if v == nil {
+			return nil
+		}
+		v.atomInto(scratch)
+		return scratch
+	}
+	return a.v[id]
+}
+
+// MaxType returns the largest atom type used by the atoms. It returns zero if
+// there is no atom.
+func (a *Atoms) MaxType() int {
+	var max int
+	for _, atom := range a.v {
+		if atom != nil && atom.AtomType > max {
+			max = atom.AtomType
+		}
+	}
+	for _, atom := range a.v32 {
+		if atom != nil && atom.AtomType > max {
+			max = atom.AtomType
+		}
+	}
+	return max
+}
+
+// UsedTypes returns the distinct atom types used by the atoms sorted in
+// increasing order. The declared types that are not used are not returned.
+func (a *Atoms) UsedTypes() []int {
+	used := make(map[int]bool)
+	a.each(func(id int, atom *Atom) error {
+		if atom != nil {
+			used[atom.AtomType] = true
+		}
+		return nil
+	})
+	return sortIntsMap(used)
+}
+
+// FilledImageFlags returns the number of atoms whose missing image flags were
+// set to 0 0 0 by the Decode method (see Options.MixedImageFlags). Without
+// this option, the Check method would have rejected these atoms.
+func (a *Atoms) FilledImageFlags() int {
+	return a.filled
+}
+
+// ImageFlags returns true if at least one atom has the image flags.
+func (a *Atoms) ImageFlags() bool {
+	for _, atom := range a.v {
+		if atom != nil && atom.N {
+			return true
+		}
+	}
+	for _, atom := range a.v32 {
+		if atom != nil && atom.N {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of atoms.
+func (a *Atoms) Len() int {
+	return len(a.v) + len(a.v32)
+}
+
 // Check verifies the integrity and correctness of the data decoded with the
 // Decode method or set with the Set method.
 //
@@ -200,21 +550,28 @@ func (a *Atoms) Check() error {
 	atomsNbr := a.atomsNbr.Get().(int)
 	atomsTypes := a.atomTypes.Get().(int)
 
-	if len(a.v) != atomsNbr {
-		return fmt.Errorf("number of assigned atoms = %d is not equal to the number of expected atoms = %d", len(a.v), atomsNbr)
+	if a.Len() != atomsNbr {
+		return countMismatch(a.Name(), a.Len(), atomsNbr, "number of assigned atoms = %d is not equal to the number of expected atoms = %d")
 	}
-	if len(a.v) == 0 {
+	if a.Len() == 0 {
 		return nil
 	}
 
 	first := true
 	n := false
-	for typ, atom := range a.v {
+	contiguous := a.opts != nil && a.opts.ContiguousIDs
+	return a.each(func(typ int, atom *Atom) error {
+		if atom == nil {
+			return fmt.Errorf("atom = %d is nil", typ)
+		}
 		if first {
 			n = atom.N // the first value is the reference
 			first = false
 		}
-		if typ < 1 || typ > atomsNbr {
+		if typ < 1 {
+			return fmt.Errorf("identifier = %d is invalid: it must be greater than zero", typ)
+		}
+		if contiguous && typ > atomsNbr {
 			return fmt.Errorf("identifier = %d is invalid: it must be greater than zero and lower or equal than the number of atoms = %d", typ, atomsNbr)
 		}
 		//if atom.MolTag < 1 {
@@ -226,8 +583,8 @@ func (a *Atoms) Check() error {
 		if atom.N != n {
 			return fmt.Errorf("n defined to %v but atom %d has n set to %v", n, typ, atom.N)
 		}
-	}
-	return nil
+		return nil
+	})
 }
 
 // SetKeysVal assigns to the NameAtomsNbr Key the number of atoms based on the
@@ -239,5 +596,5 @@ func (a *Atoms) SetKeysVal() error {
 	if a.atomsNbr == nil {
 		return fmt.Errorf("Key that is an instance of *Header with Name equal to NameAtomsNbr is nil: use the Set method")
 	}
-	return a.atomsNbr.Set(len(a.v))
+	return a.atomsNbr.Set(a.Len())
 }
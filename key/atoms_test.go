@@ -0,0 +1,236 @@
+package key
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newAtoms returns Atoms of atom style as with nbr atoms and 2 atom types.
+func newAtoms(as AtomStyle, nbr int, opts *Options) *Atoms {
+	a := NewAtoms(as)
+	a.SetKeys(header(NameAtomsNbr, nbr), header(NameAtomTypes, 2))
+	a.SetOptions(opts)
+	return a
+}
+
+func TestAtomsDetectAtomStyle(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		row    string
+		style  string
+		want   Atom
+	}{
+		{"atomic", "Atoms", "1 2 0.5 1.5 2.5", "atomic", Atom{AtomType: 2, X: 0.5, Y: 1.5, Z: 2.5}},
+		{"atomic with image flags", "Atoms", "1 2 0.5 1.5 2.5 0 1 0", "atomic", Atom{AtomType: 2, X: 0.5, Y: 1.5, Z: 2.5, N: true, NY: 1}},
+		{"full", "Atoms", "1 3 2 -0.8 0.5 1.5 2.5", "full", Atom{MolTag: 3, AtomType: 2, Q: -0.8, X: 0.5, Y: 1.5, Z: 2.5}},
+		{"full with image flags", "Atoms", "1 3 2 -0.8 0.5 1.5 2.5 1 0 0", "full", Atom{MolTag: 3, AtomType: 2, Q: -0.8, X: 0.5, Y: 1.5, Z: 2.5, N: true, NX: 1}},
+		{"header comment", "Atoms # line", "1 3 2 1 0.5 0.5 1.5 2.5", "line", Atom{MolTag: 3, AtomType: 2, Flag: 1, Density: 0.5, X: 0.5, Y: 1.5, Z: 2.5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAtoms(nil, 1, &Options{DetectAtomStyle: true})
+			if err := decode(t, a, tt.header+"\n\n"+tt.row+"\n"); err != nil {
+				t.Fatal(err)
+			}
+			if got := a.AtomStyle().Name(); got != tt.style {
+				t.Errorf("AtomStyle = %s, want %s", got, tt.style)
+			}
+			if got := *a.Map()[1]; got != tt.want {
+				t.Errorf("atom = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// without the detection, an atomic file is decoded with the full atom
+	// style: the x coordinate is read as the atom type.
+	a := newAtoms(nil, 1, nil)
+	if err := decode(t, a, "Atoms\n\n1 2 0.5 1.5 2.5 0 1 0\n"); err == nil {
+		t.Errorf("Decode = nil without the detection, want an error")
+	}
+}
+
+func TestAtomsLargeIDs(t *testing.T) {
+	if strconv.IntSize < 64 {
+		t.Skip("the identifiers greater than 2^31-1 require a 64-bit platform")
+	}
+	const in = "Atoms\n\n3000000000 1 2 -0.8 0.5 1.5 2.5\n2147483648 1 1 0.4 0 0 0\n"
+	a := newAtoms(AtomStyleFull, 2, nil)
+	if err := decode(t, a, in); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{3000000000, 2147483648} {
+		if a.Map()[id] == nil {
+			t.Errorf("atom = %d is missing", id)
+		}
+	}
+	const out = "Atoms\n\n2147483648 1 1 0.4 0 0 0\n3000000000 1 2 -0.8 0.5 1.5 2.5\n"
+	if got := encode(t, a); got != out {
+		t.Errorf("Encode = %q, want %q", got, out)
+	}
+
+	l := newLinks(NameBonds, 2, 1, nil)
+	if err := decode(t, l, "Bonds\n\n4294967296 1 3000000000 2147483648\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Map()[4294967296].Atoms(); len(got) != 2 || got[0] != 3000000000 || got[1] != 2147483648 {
+		t.Errorf("Atoms = %v, want [3000000000 2147483648]", got)
+	}
+}
+
+func TestAtomsCheckIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       string
+		contiguous bool
+		wantErr    string
+	}{
+		{"contiguous", "1 1 1 0 0 0 0\n2 1 2 0 0 0 0\n", false, ""},
+		{"sparse", "60 1 1 0 0 0 0\n2 1 2 0 0 0 0\n", false, ""},
+		{"sparse with ContiguousIDs", "60 1 1 0 0 0 0\n2 1 2 0 0 0 0\n", true, "identifier = 60 is invalid"},
+		{"contiguous with ContiguousIDs", "2 1 1 0 0 0 0\n1 1 2 0 0 0 0\n", true, ""},
+		{"zero", "0 1 1 0 0 0 0\n2 1 2 0 0 0 0\n", false, "identifier = 0 is invalid"},
+		{"duplicate", "60 1 1 0 0 0 0\n60 1 2 0 0 0 0\n", false, "number of assigned atoms = 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAtoms(AtomStyleFull, 2, &Options{ContiguousIDs: tt.contiguous})
+			if err := decode(t, a, "Atoms\n\n"+tt.rows); err != nil {
+				t.Fatal(err)
+			}
+			err := a.Check()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAtomsMixedImageFlags(t *testing.T) {
+	const in = "Atoms\n\n1 1 1 -0.8 0 0 0 1 -1 0\n2 1 2 0.4 1 1 1\n3 1 2 0.4 2 2 2\n"
+	tests := []struct {
+		name   string
+		opts   *Options
+		filled int
+		ok     bool
+	}{
+		{"strict", nil, 0, false},
+		{"mixed", &Options{MixedImageFlags: true}, 2, true},
+		{"mixed float32", &Options{MixedImageFlags: true, Float32: true}, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAtoms(AtomStyleFull, 3, tt.opts)
+			if err := decode(t, a, in); err != nil {
+				t.Fatal(err)
+			}
+			err := a.Check()
+			if !tt.ok {
+				if err == nil {
+					t.Error("Check = nil with mixed image flags")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.FilledImageFlags() != tt.filled {
+				t.Errorf("FilledImageFlags = %d, want %d", a.FilledImageFlags(), tt.filled)
+			}
+			const out = "Atoms\n\n1 1 1 -0.8 0 0 0 1 -1 0\n2 1 2 0.4 1 1 1 0 0 0\n3 1 2 0.4 2 2 2 0 0 0\n"
+			if got := encode(t, a); got != out {
+				t.Errorf("Encode = %q, want %q", got, out)
+			}
+		})
+	}
+}
+
+func TestAtomsFloat32(t *testing.T) {
+	const in = "Atoms\n\n1 1 1 -0.8 0.1 1.5 2.5 0 1 0\n2 1 2 0.4 1.3 1 1 0 0 0\n"
+	a := newAtoms(AtomStyleFull, 2, &Options{Float32: true})
+	if err := decode(t, a, in); err != nil {
+		t.Fatal(err)
+	}
+	if a.Map() != nil || len(a.MapF32()) != 2 {
+		t.Fatalf("Map = %v and MapF32 = %v, want only 2 AtomF32", a.Map(), a.MapF32())
+	}
+	want := AtomF32{MolTag: 1, AtomType: 1, Q: -0.8, X: 0.1, Y: 1.5, Z: 2.5, N: true, NY: 1}
+	if got := *a.MapF32()[1]; got != want {
+		t.Errorf("atom = %+v, want %+v", got, want)
+	}
+	if a.MapF32()[1] == a.MapF32()[2] {
+		t.Error("the atoms share the same AtomF32")
+	}
+	if err := a.Check(); err != nil {
+		t.Fatal(err)
+	}
+	const out = "Atoms\n\n1 1 1 -0.8 0.1 1.5 2.5 0 1 0\n2 1 2 0.4 1.3 1 1 0 0 0\n"
+	if got := encode(t, a); got != out {
+		t.Errorf("Encode = %q, want %q", got, out)
+	}
+}
+
+// BenchmarkAtomsDecode reports the memory used to decode 10000 atoms with and
+// without Options.Float32.
+func BenchmarkAtomsDecode(b *testing.B) {
+	const n = 10000
+	var sb strings.Builder
+	sb.WriteString("Atoms\n\n")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "%d %d 1 -0.8 %g %g %g\n", i, i/3+1, float64(i)*0.1, float64(i)*0.2, float64(i)*0.3)
+	}
+	in := sb.String()
+	for _, bb := range []struct {
+		name string
+		opts *Options
+	}{
+		{"float64", nil},
+		{"float32", &Options{Float32: true}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				a := newAtoms(AtomStyleFull, n, bb.opts)
+				if err := decode(b, a, in); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestAtomsUsedTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *Options
+		rows string
+		want []int
+	}{
+		{"unused type", nil, "1 1 3 0 0 0 0\n2 1 1 0 1 1 1\n3 2 3 0 2 2 2\n", []int{1, 3}},
+		{"unused type float32", &Options{Float32: true}, "1 1 3 0 0 0 0\n2 1 1 0 1 1 1\n3 2 3 0 2 2 2\n", []int{1, 3}},
+		{"single type", nil, "1 1 2 0 0 0 0\n2 1 2 0 1 1 1\n3 2 2 0 2 2 2\n", []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAtoms(AtomStyleFull, 3, tt.opts)
+			if err := decode(t, a, "Atoms\n\n"+tt.rows); err != nil {
+				t.Fatal(err)
+			}
+			if got := a.UsedTypes(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UsedTypes = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := newAtoms(AtomStyleFull, 0, nil).UsedTypes(); len(got) != 0 {
+		t.Errorf("UsedTypes without atom = %v, want none", got)
+	}
+}
@@ -15,17 +15,44 @@ type AtomStyle interface {
 	Decode(f []string) (int, *Atom, error)
 }
 
+// FormatEncoder is implemented by the atom styles that can write each column
+// with a specific format (see Options.Formats). All the atom styles of this
+// package implement it.
+type FormatEncoder interface {
+	EncodeFormat(atom *Atom, w io.Writer, formats map[Column]string) error
+}
+
+// IntoDecoder is implemented by the atom styles that can decode a row into an
+// existing Atom. It lets Atoms reuse the same Atom for each row if
+// Options.Float32 is true. All the atom styles of this package implement it.
+type IntoDecoder interface {
+	DecodeInto(f []string, atom *Atom) (int, error)
+}
+
 // The atom_style below are supported by this program. By default, the atom
 // style is full (AtomStyleFull).
 var (
 	AtomStyleFull   AtomStyle = atomStyleFull("full")
 	AtomStyleAtomic AtomStyle = atomStyleAtomic("atomic")
+	AtomStylePeri   AtomStyle = &atomStyleColumns{name: "peri", cols: []Column{ColumnAtomType, ColumnVolume, ColumnDensity, ColumnX, ColumnY, ColumnZ}}
+	AtomStyleLine   AtomStyle = &atomStyleColumns{name: "line", cols: []Column{ColumnMolTag, ColumnAtomType, ColumnLineFlag, ColumnDensity, ColumnX, ColumnY, ColumnZ}}
+	AtomStyleTri    AtomStyle = &atomStyleColumns{name: "tri", cols: []Column{ColumnMolTag, ColumnAtomType, ColumnTriangleFlag, ColumnDensity, ColumnX, ColumnY, ColumnZ}}
+
+	// AtomStyleElectron and AtomStyleWavepacket are used by the eFF and AWPMD
+	// simulations.
+	AtomStyleElectron   AtomStyle = &atomStyleColumns{name: "electron", cols: []Column{ColumnAtomType, ColumnQ, ColumnSpin, ColumnERadius, ColumnX, ColumnY, ColumnZ}}
+	AtomStyleWavepacket AtomStyle = &atomStyleColumns{name: "wavepacket", cols: []Column{ColumnAtomType, ColumnQ, ColumnSpin, ColumnERadius, ColumnETag, ColumnCSRe, ColumnCSIm, ColumnX, ColumnY, ColumnZ}}
 )
 
 // ListAtomStyles is a list containing all the atom styles.
 var ListAtomStyles []AtomStyle = []AtomStyle{
 	AtomStyleFull,
 	AtomStyleAtomic,
+	AtomStylePeri,
+	AtomStyleLine,
+	AtomStyleTri,
+	AtomStyleElectron,
+	AtomStyleWavepacket,
 }
 
 type atomStyleFull string
@@ -41,61 +68,58 @@ func (a atomStyleFull) Encode(atom *Atom, w io.Writer) error {
 	return err
 }
 
+// EncodeFormat works like Encode but the columns are written with the formats
+// given by formats.
+func (a atomStyleFull) EncodeFormat(atom *Atom, w io.Writer, formats map[Column]string) error {
+	return encodeColumns(atom, w, Columns(a), formats, false)
+}
+
 // Decode converts each column into a number (float64 or int) for the AtomStyleFull.
-func (a atomStyleFull) Decode(f []string) (id int, atom *Atom, err error) {
+func (a atomStyleFull) Decode(f []string) (int, *Atom, error) {
+	atom := new(Atom)
+	id, err := a.DecodeInto(f, atom)
+	return id, atom, err
+}
+
+// DecodeInto works like Decode but the columns are decoded into atom.
+func (a atomStyleFull) DecodeInto(f []string, atom *Atom) (id int, err error) {
+	*atom = Atom{}
 	if len(f) < 7 {
 		err = fmt.Errorf("not enough fields = %d, want >= 7", len(f))
 		return
 	}
 
-	if id, err = strconv.Atoi(f[0]); err != nil {
-		err = fmt.Errorf("strconv.Atoi id: %w", err)
+	if id, err = parseID(f[0]); err != nil {
+		err = parseError("strconv.ParseInt", "id", err)
 		return
 	}
 
-	atom = &Atom{}
 	if atom.MolTag, err = strconv.Atoi(f[1]); err != nil {
-		err = fmt.Errorf("strconv.Atoi MolTag: %w", err)
+		err = parseError("strconv.Atoi", "MolTag", err)
 		return
 	}
 	if atom.AtomType, err = strconv.Atoi(f[2]); err != nil {
-		err = fmt.Errorf("strconv.Atoi AtomType: %w", err)
+		err = parseError("strconv.Atoi", "AtomType", err)
 		return
 	}
 	if atom.Q, err = strconv.ParseFloat(f[3], 64); err != nil {
-		err = fmt.Errorf("strconv.ParseFloat Q: %w", err)
+		err = parseError("strconv.ParseFloat", "Q", err)
 		return
 	}
 	if atom.X, err = strconv.ParseFloat(f[4], 64); err != nil {
-		err = fmt.Errorf("strconv.ParseFloat X: %w", err)
+		err = parseError("strconv.ParseFloat", "X", err)
 		return
 	}
 	if atom.Y, err = strconv.ParseFloat(f[5], 64); err != nil {
-		err = fmt.Errorf("strconv.ParseFloat Y: %w", err)
+		err = parseError("strconv.ParseFloat", "Y", err)
 		return
 	}
 	if atom.Z, err = strconv.ParseFloat(f[6], 64); err != nil {
-		err = fmt.Errorf("strconv.ParseFloat Z: %w", err)
+		err = parseError("strconv.ParseFloat", "Z", err)
 		return
 	}
 
-	atom.N = false
-	if len(f) == 10 {
-		atom.N = true
-		if atom.NX, err = strconv.Atoi(f[7]); err != nil {
-			err = fmt.Errorf("strconv.Atoi NX: %w", err)
-			return
-		}
-		if atom.NY, err = strconv.Atoi(f[8]); err != nil {
-			err = fmt.Errorf("strconv.Atoi NY: %w", err)
-			return
-		}
-		if atom.NZ, err = strconv.Atoi(f[9]); err != nil {
-			err = fmt.Errorf("strconv.Atoi NZ: %w", err)
-			return
-		}
-	}
-
+	err = decodeImageFlags(atom, f, 7)
 	return
 }
 
@@ -112,53 +136,269 @@ func (a atomStyleAtomic) Encode(atom *Atom, w io.Writer) error {
 	return err
 }
 
+// EncodeFormat works like Encode but the columns are written with the formats
+// given by formats.
+func (a atomStyleAtomic) EncodeFormat(atom *Atom, w io.Writer, formats map[Column]string) error {
+	return encodeColumns(atom, w, Columns(a), formats, false)
+}
+
 // Decode converts each column into a number (float64 or int) for the atomStyleAtomic.
-func (a atomStyleAtomic) Decode(f []string) (id int, atom *Atom, err error) {
+func (a atomStyleAtomic) Decode(f []string) (int, *Atom, error) {
+	atom := new(Atom)
+	id, err := a.DecodeInto(f, atom)
+	return id, atom, err
+}
+
+// DecodeInto works like Decode but the columns are decoded into atom.
+func (a atomStyleAtomic) DecodeInto(f []string, atom *Atom) (id int, err error) {
+	*atom = Atom{}
 	if len(f) < 5 {
 		err = fmt.Errorf("not enough fields = %d, want >= 5", len(f))
 		return
 	}
 
-	if id, err = strconv.Atoi(f[0]); err != nil {
-		err = fmt.Errorf("strconv.Atoi id: %w", err)
+	if id, err = parseID(f[0]); err != nil {
+		err = parseError("strconv.ParseInt", "id", err)
 		return
 	}
 
-	atom = &Atom{}
 	if atom.AtomType, err = strconv.Atoi(f[1]); err != nil {
-		err = fmt.Errorf("strconv.Atoi AtomType: %w", err)
+		err = parseError("strconv.Atoi", "AtomType", err)
 		return
 	}
 
 	if atom.X, err = strconv.ParseFloat(f[2], 64); err != nil {
-		err = fmt.Errorf("strconv.ParseFloat X: %w", err)
+		err = parseError("strconv.ParseFloat", "X", err)
 		return
 	}
 	if atom.Y, err = strconv.ParseFloat(f[3], 64); err != nil {
-		err = fmt.Errorf("strconv.ParseFloat Y: %w", err)
+		err = parseError("strconv.ParseFloat", "Y", err)
 		return
 	}
 	if atom.Z, err = strconv.ParseFloat(f[4], 64); err != nil {
-		err = fmt.Errorf("strconv.ParseFloat Z: %w", err)
+		err = parseError("strconv.ParseFloat", "Z", err)
 		return
 	}
 
+	err = decodeImageFlags(atom, f, 5)
+	return
+}
+
+// decodeImageFlags decodes the image flags NX, NY, and NZ of atom if f has
+// three fields after the base fields of the atom style (including the
+// identifier). It returns an error if f has one or two fields after them.
+func decodeImageFlags(atom *Atom, f []string, base int) error {
 	atom.N = false
-	if len(f) == 8 {
-		atom.N = true
-		if atom.NX, err = strconv.Atoi(f[5]); err != nil {
-			err = fmt.Errorf("strconv.Atoi NX: %w", err)
-			return
+	switch n := len(f) - base; {
+	case n <= 0:
+		return nil
+	case n < 3:
+		return fmt.Errorf("unexpected number of columns = %d: want %d, or %d with the image flags", len(f), base, base+3)
+	}
+
+	atom.N = true
+	for i, v := range []*int{&atom.NX, &atom.NY, &atom.NZ} {
+		var err error
+		if *v, err = strconv.Atoi(f[base+i]); err != nil {
+			return parseError("strconv.Atoi", fmt.Sprintf("image flag N%c = %q", 'X'+i, f[base+i]), err)
 		}
-		if atom.NY, err = strconv.Atoi(f[6]); err != nil {
-			err = fmt.Errorf("strconv.Atoi NY: %w", err)
-			return
+	}
+	return nil
+}
+
+// Column is a column of the Atoms table that follows the identifier of the
+// atom. It is used to define an atom style with the NewAtomStyleColumns
+// function.
+type Column string
+
+// The columns below are supported by NewAtomStyleColumns. The names are the
+// ones used in the LAMMPS documentation of the read_data command.
+const (
+	ColumnMolTag   Column = "molecule-ID"
+	ColumnAtomType Column = "atom-type"
+	ColumnQ        Column = "q"
+	ColumnX        Column = "x"
+	ColumnY        Column = "y"
+	ColumnZ        Column = "z"
+	ColumnMass     Column = "mass"
+	ColumnVolume   Column = "volume"
+	ColumnDensity  Column = "density"
+
+	// ColumnLineFlag and ColumnTriangleFlag are decoded into Atom.Flag.
+	ColumnLineFlag     Column = "lineflag"
+	ColumnTriangleFlag Column = "triangleflag"
+
+	// The columns below are used by AtomStyleElectron and AtomStyleWavepacket.
+	ColumnSpin    Column = "espin"
+	ColumnERadius Column = "eradius"
+	ColumnETag    Column = "etag"
+	ColumnCSRe    Column = "cs_re"
+	ColumnCSIm    Column = "cs_im"
+)
+
+// atomStyleColumns is an atom style defined by a list of columns.
+type atomStyleColumns struct {
+	name string
+	cols []Column
+}
+
+// NewAtomStyleColumns returns an atom style whose columns (after the identifier
+// of the atom) are cols. It is useful for the atom styles that are not
+// supported by this package, for instance a custom style with a per-atom mass:
+//
+//	as := NewAtomStyleColumns("mymass", ColumnAtomType, ColumnMass, ColumnX, ColumnY, ColumnZ)
+//
+// The image flags NX, NY, and NZ are decoded if three additional columns are
+// present. To use the atom style with the lmpsdat tags, it must be appended to
+// ListAtomStyles. It returns an error if a column is not supported.
+func NewAtomStyleColumns(name string, cols ...Column) (AtomStyle, error) {
+	for _, c := range cols {
+		switch c {
+		case ColumnMolTag, ColumnAtomType, ColumnQ, ColumnX, ColumnY, ColumnZ, ColumnMass, ColumnVolume, ColumnDensity,
+			ColumnLineFlag, ColumnTriangleFlag, ColumnSpin, ColumnERadius, ColumnETag, ColumnCSRe, ColumnCSIm:
+		default:
+			return nil, fmt.Errorf("column = %s is not supported", c)
+		}
+	}
+	return &atomStyleColumns{name: name, cols: cols}, nil
+}
+
+func (a *atomStyleColumns) Name() string {
+	return a.name
+}
+
+// Encode encodes the data for each column. It doesn't encode the N image sets.
+func (a *atomStyleColumns) Encode(atom *Atom, w io.Writer) error {
+	return encodeColumns(atom, w, a.cols, nil, false)
+}
+
+// EncodeFormat works like Encode but the columns are written with the formats
+// given by formats.
+func (a *atomStyleColumns) EncodeFormat(atom *Atom, w io.Writer, formats map[Column]string) error {
+	return encodeColumns(atom, w, a.cols, formats, false)
+}
+
+// encodeColumns writes the columns cols of atom separated by a space. The
+// format of a column is given by formats. If formats does not contain the
+// column, %d is used for the integers and %g for the floats. The whole floats
+// written with %g get a trailing ".0" if zero is true (see formatFloat).
+func encodeColumns(atom *Atom, w io.Writer, cols []Column, formats map[Column]string, zero bool) error {
+	for i, c := range cols {
+		if i > 0 {
+			if _, err := fmt.Fprint(w, " "); err != nil {
+				return err
+			}
+		}
+		var v interface{}
+		switch c {
+		case ColumnMolTag:
+			v = atom.MolTag
+		case ColumnAtomType:
+			v = atom.AtomType
+		case ColumnQ:
+			v = atom.Q
+		case ColumnX:
+			v = atom.X
+		case ColumnY:
+			v = atom.Y
+		case ColumnZ:
+			v = atom.Z
+		case ColumnMass:
+			v = atom.Mass
+		case ColumnVolume:
+			v = atom.Volume
+		case ColumnDensity:
+			v = atom.Density
+		case ColumnLineFlag, ColumnTriangleFlag:
+			v = atom.Flag
+		case ColumnSpin:
+			v = atom.Spin
+		case ColumnERadius:
+			v = atom.ERadius
+		case ColumnETag:
+			v = atom.ETag
+		case ColumnCSRe:
+			v = atom.CSRe
+		case ColumnCSIm:
+			v = atom.CSIm
+		}
+		format, ok := formats[c]
+		if !ok {
+			format = "%g"
+			if _, isInt := v.(int); isInt {
+				format = "%d"
+			} else if zero {
+				format, v = "%s", formatFloat(v.(float64), true)
+			}
+		}
+		if _, err := fmt.Fprintf(w, format, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode converts each column into a number (float64 or int).
+func (a *atomStyleColumns) Decode(f []string) (int, *Atom, error) {
+	atom := new(Atom)
+	id, err := a.DecodeInto(f, atom)
+	return id, atom, err
+}
+
+// DecodeInto works like Decode but the columns are decoded into atom.
+func (a *atomStyleColumns) DecodeInto(f []string, atom *Atom) (id int, err error) {
+	*atom = Atom{}
+	want := len(a.cols) + 1
+	if len(f) < want {
+		err = fmt.Errorf("not enough fields = %d, want >= %d", len(f), want)
+		return
+	}
+
+	if id, err = parseID(f[0]); err != nil {
+		err = parseError("strconv.ParseInt", "id", err)
+		return
+	}
+
+	for i, c := range a.cols {
+		s := f[i+1]
+		switch c {
+		case ColumnMolTag:
+			atom.MolTag, err = strconv.Atoi(s)
+		case ColumnAtomType:
+			atom.AtomType, err = strconv.Atoi(s)
+		case ColumnQ:
+			atom.Q, err = strconv.ParseFloat(s, 64)
+		case ColumnX:
+			atom.X, err = strconv.ParseFloat(s, 64)
+		case ColumnY:
+			atom.Y, err = strconv.ParseFloat(s, 64)
+		case ColumnZ:
+			atom.Z, err = strconv.ParseFloat(s, 64)
+		case ColumnMass:
+			atom.Mass, err = strconv.ParseFloat(s, 64)
+		case ColumnVolume:
+			atom.Volume, err = strconv.ParseFloat(s, 64)
+		case ColumnDensity:
+			atom.Density, err = strconv.ParseFloat(s, 64)
+		case ColumnLineFlag, ColumnTriangleFlag:
+			atom.Flag, err = strconv.Atoi(s)
+		case ColumnSpin:
+			atom.Spin, err = strconv.Atoi(s)
+		case ColumnERadius:
+			atom.ERadius, err = strconv.ParseFloat(s, 64)
+		case ColumnETag:
+			atom.ETag, err = strconv.Atoi(s)
+		case ColumnCSRe:
+			atom.CSRe, err = strconv.ParseFloat(s, 64)
+		case ColumnCSIm:
+			atom.CSIm, err = strconv.ParseFloat(s, 64)
 		}
-		if atom.NZ, err = strconv.Atoi(f[7]); err != nil {
-			err = fmt.Errorf("strconv.Atoi NZ: %w", err)
+		if err != nil {
+			err = parseError("column", string(c), err)
 			return
 		}
 	}
 
+	err = decodeImageFlags(atom, f, want)
 	return
 }
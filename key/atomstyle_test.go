@@ -0,0 +1,198 @@
+package key
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// FuzzAtomStyleDecode verifies that the Decode method of each atom style
+// returns an error instead of panicking whatever the number of fields.
+func FuzzAtomStyleDecode(f *testing.F) {
+	for _, s := range []string{
+		"",
+		"1",
+		"1 1 1 -0.8476 1 1 1",
+		"1 1 1 -0.8476 1 1 1 0 0 0",
+		"1 1 1 -0.8476 1 1 1 0 0",
+		"1 1 1 -0.8476 1 1 1 0 x 0",
+		"1 1 1 1",
+		"1 1 1 1 1 1 1 1 1 1 1 1 1 1",
+		"x 1 1 1 1",
+		"99999999999999999999 1 1 1 1",
+	} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		f := strings.Fields(s)
+		for _, as := range ListAtomStyles {
+			id, atom, err := as.Decode(f)
+			if err != nil {
+				continue
+			}
+			if atom == nil {
+				t.Fatalf("atom style = %s: Decode(%q) = %d, nil, nil", as.Name(), s, id)
+			}
+		}
+	})
+}
+
+// encodeAtom returns atom written by the Encode method of as.
+func encodeAtom(t testing.TB, as AtomStyle, atom *Atom) string {
+	t.Helper()
+	var b strings.Builder
+	if err := as.Encode(atom, &b); err != nil {
+		t.Fatalf("Encode for atom style = %s: %v", as.Name(), err)
+	}
+	return b.String()
+}
+
+func TestNewAtomStyleColumns(t *testing.T) {
+	as, err := NewAtomStyleColumns("mymass", ColumnAtomType, ColumnMass, ColumnX, ColumnY, ColumnZ)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		row  string
+		want Atom
+	}{
+		{"7 2 12.011 1 2 3", Atom{AtomType: 2, Mass: 12.011, X: 1, Y: 2, Z: 3}},
+		{"7 2 12.011 1 2 3 0 -1 1", Atom{AtomType: 2, Mass: 12.011, X: 1, Y: 2, Z: 3, N: true, NY: -1, NZ: 1}},
+	}
+	for _, tt := range tests {
+		id, atom, err := as.Decode(strings.Fields(tt.row))
+		if err != nil {
+			t.Fatalf("Decode(%q) = %v", tt.row, err)
+		}
+		if id != 7 || *atom != tt.want {
+			t.Errorf("Decode(%q) = %d, %+v, want 7, %+v", tt.row, id, *atom, tt.want)
+		}
+	}
+	if got := encodeAtom(t, as, &tests[0].want); got != "2 12.011 1 2 3" {
+		t.Errorf("Encode = %q", got)
+	}
+	if _, _, err := as.Decode([]string{"7", "2", "12.011", "1", "2"}); err == nil {
+		t.Error("Decode = nil with a missing column")
+	}
+	if _, err := NewAtomStyleColumns("bad", ColumnAtomType, Column("spin")); err == nil {
+		t.Error("NewAtomStyleColumns = nil with an unsupported column")
+	}
+	if got := Columns(as); len(got) != 5 || got[1] != ColumnMass {
+		t.Errorf("Columns = %v", got)
+	}
+}
+
+func TestAtomStylePeri(t *testing.T) {
+	tests := []struct {
+		name string
+		row  string
+		want Atom
+	}{
+		{"peri", "3 1 0.001 7.8 0.5 1.5 2.5", Atom{AtomType: 1, Volume: 0.001, Density: 7.8, X: 0.5, Y: 1.5, Z: 2.5}},
+		{"peri with image flags", "3 1 0.001 7.8 0.5 1.5 2.5 0 -1 2", Atom{AtomType: 1, Volume: 0.001, Density: 7.8, X: 0.5, Y: 1.5, Z: 2.5, N: true, NY: -1, NZ: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAtoms(AtomStylePeri, 1, nil)
+			if err := decode(t, a, "Atoms # peri\n\n"+tt.row+"\n"); err != nil {
+				t.Fatal(err)
+			}
+			if got := *a.Map()[3]; got != tt.want {
+				t.Errorf("atom = %+v, want %+v", got, tt.want)
+			}
+			if got, want := encode(t, a), "Atoms\n\n"+tt.row+"\n"; got != want {
+				t.Errorf("Encode = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestAtomStyleImageFlagsColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		as      AtomStyle
+		row     string
+		wantErr string
+	}{
+		{"full", AtomStyleFull, "1 1 1 0 0 0 0", ""},
+		{"full with image flags", AtomStyleFull, "1 1 1 0 0 0 0 1 0 -1", ""},
+		{"full with 8 columns", AtomStyleFull, "1 1 1 0 0 0 0 1", "unexpected number of columns = 8: want 7, or 10 with the image flags"},
+		{"full with 9 columns", AtomStyleFull, "1 1 1 0 0 0 0 1 0", "unexpected number of columns = 9: want 7, or 10 with the image flags"},
+		{"atomic with 6 columns", AtomStyleAtomic, "1 1 0 0 0 1", "unexpected number of columns = 6: want 5, or 8 with the image flags"},
+		{"non-integer image flag", AtomStyleFull, "1 1 1 0 0 0 0 0.5 0 0", `image flag NX = "0.5"`},
+		{"non-integer last image flag", AtomStyleAtomic, "1 1 0 0 0 0 0 z", `image flag NZ = "z"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.as.Decode(strings.Fields(tt.row))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Decode = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// the row of the atom is given by the Atoms table.
+	a := newAtoms(AtomStyleFull, 2, nil)
+	err := decode(t, a, "Atoms\n\n1 1 1 0 0 0 0 0 0 0\n2 1 1 0 0 0 0 0.5 0 0\n")
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Line != 2 || pe.Section != NameAtoms {
+		t.Errorf("Decode = %v, want a ParseError at row = 2", err)
+	}
+}
+
+func TestAtomStyleElectron(t *testing.T) {
+	tests := []struct {
+		name string
+		as   AtomStyle
+		rows string
+		want map[int]Atom
+	}{
+		{"electron", AtomStyleElectron, "1 1 6 0 0 0 0 0 0 0 0\n2 2 -1 1 0.5 0.1 -0.2 0.3 0 0 1\n", map[int]Atom{
+			1: {AtomType: 1, Q: 6, N: true},
+			2: {AtomType: 2, Q: -1, Spin: 1, ERadius: 0.5, X: 0.1, Y: -0.2, Z: 0.3, N: true, NZ: 1},
+		}},
+		{"wavepacket", AtomStyleWavepacket, "1 1 6 0 0 0 0 0 0 0 0\n2 2 -1 -1 0.5 1 0.75 -0.25 0.1 -0.2 0.3\n", map[int]Atom{
+			1: {AtomType: 1, Q: 6},
+			2: {AtomType: 2, Q: -1, Spin: -1, ERadius: 0.5, ETag: 1, CSRe: 0.75, CSIm: -0.25, X: 0.1, Y: -0.2, Z: 0.3},
+		}},
+	}
+	for _, tt := range tests {
+		for _, f32 := range []bool{false, true} {
+			name := tt.name
+			if f32 {
+				name += " float32"
+			}
+			t.Run(name, func(t *testing.T) {
+				a := newAtoms(tt.as, 2, &Options{Float32: f32})
+				if err := decode(t, a, "Atoms # "+tt.name+"\n\n"+tt.rows); err != nil {
+					t.Fatal(err)
+				}
+				for id, want := range tt.want {
+					got := a.Map()[id]
+					if f32 {
+						got = a.MapF32()[id].Atom()
+					}
+					if *got != want {
+						t.Errorf("atom %d = %+v, want %+v", id, *got, want)
+					}
+				}
+				if got := encode(t, a); got != "Atoms\n\n"+tt.rows {
+					t.Errorf("Encode = %q, want %q", got, "Atoms\n\n"+tt.rows)
+				}
+			})
+		}
+	}
+
+	// the atom styles are registered.
+	for _, as := range []AtomStyle{AtomStyleElectron, AtomStyleWavepacket} {
+		if !IsAtomStyle(as.Name()) {
+			t.Errorf("IsAtomStyle(%q) = false", as.Name())
+		}
+	}
+}
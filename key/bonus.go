@@ -0,0 +1,236 @@
+package key
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Bonus is used to encode and/or decode a table containing the bonus data of
+// some atoms (e.g. Lines, Triangles) from a LAMMPS data file. This table has a
+// header where a blank line separate the values from it. Each value (= 1 line)
+// has the identifier of an atom followed by a fixed number of floats (e.g. 4
+// for Lines: x1 y1 x2 y2). More information about the structure of this table
+// can be found in the LAMMPS documentation.
+//
+// Bonus can be instanced by using the NewBonus function.
+type Bonus struct {
+	name     Name
+	cols     int
+	nbr      *Header
+	atomsNbr *Header
+	opts     *Options
+	v        map[int][]float64
+}
+
+// NewBonus returns an instance of Bonus. cols is the number of floats that
+// follow the identifier of the atom. The recommended Names are NameLines (4
+// columns) and NameTriangles (9 columns).
+func NewBonus(name Name, cols int) *Bonus {
+	return &Bonus{name: name, cols: cols}
+}
+
+// Name returns the Name passed in NewBonus. It corresponds to the header of the
+// table.
+func (b *Bonus) Name() Name {
+	return b.name
+}
+
+// Keyword tests whether the byte slice s begins with Name after trimming the
+// spaces. Keyword is useful to detect the header of the Bonus table. The case
+// is ignored if Options.CaseInsensitive is true.
+func (b *Bonus) Keyword(s []byte) bool {
+	return b.opts.keyword(s, b.Name())
+}
+
+// SetKeys assigns one or more Keys to Bonus. This method only accepts *Header.
+// One key must have a Name equal to NameAtomsNbr, the other is considered as
+// the number of values (e.g. NameLinesNbr).
+func (b *Bonus) SetKeys(k ...Key) error {
+	for _, key := range k {
+		header, ok := key.(*Header)
+		if !ok {
+			return fmt.Errorf("type assertion error: key provided is not *Header")
+		}
+		if header.Name() == NameAtomsNbr {
+			b.atomsNbr = header
+		} else {
+			b.nbr = header
+		}
+	}
+	return nil
+}
+
+// SetOptions assigns the Options used by the Keyword and Decode methods. o can
+// be nil.
+func (b *Bonus) SetOptions(o *Options) {
+	b.opts = o
+}
+
+// SetKeysVal assigns to the NamexxxNbr Key (e.g. NameLinesNbr) the number of
+// values based on the length of the map that is created via the Set or Decode
+// methods.
+func (b *Bonus) SetKeysVal() error {
+	if b.nbr == nil {
+		return fmt.Errorf("Key that is an instance of *Header that represent the number of values is nil: use the Set method")
+	}
+	return b.nbr.Set(len(b.v))
+}
+
+// Encode writes a table containing the header, a blank line and each value (= 1
+// line) into a writer.
+//
+// This method does not check the integrity and correctness of each value. To do
+// so, use the Check method.
+func (b *Bonus) Encode(w io.Writer) error {
+	if b.v == nil {
+		return fmt.Errorf("map[int][]float64 is nil: use the Decode or Set methods")
+	}
+	if len(b.v) == 0 {
+		return nil
+	}
+
+	keys := sortIntsMap(b.v)
+	fmt.Fprint(w, b.Name(), "\n\n")
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%d", k); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+		for _, v := range b.v[k] {
+			if _, err := fmt.Fprintf(w, " %s", b.opts.float(v)); err != nil {
+				return fmt.Errorf("fmt.Fprintf value: %w", err)
+			}
+		}
+		if _, err := fmt.Fprint(w, "\n"); err != nil {
+			return fmt.Errorf("fmt.Fprintf newline: %w", err)
+		}
+	}
+	return nil
+}
+
+// Decode reads a reader where the offset is after the header of the table (at
+// the beginning of the blank line). It reads each value (= 1 line) and creates
+// a slice of float64s that is put into a map where the keys are the identifiers
+// of the atoms.
+//
+// This method needs a Key in order to work. This Key is an instance of Header
+// that represent the number of values (e.g. NameLinesNbr). Use the Set method to
+// assign this Key.
+//
+// The blank lines between the values are skipped and are not counted.
+//
+// Moreover, this method does not check the integrity and corectness of the
+// values decoded. To do so, use the Check method.
+//
+// Decode method does not return io.EOF error. If the input ends before the
+// number of expected values is read, an error wrapping ErrTruncated is returned.
+func (b *Bonus) Decode(s []byte, r *bufio.Scanner) error {
+	if b.nbr == nil {
+		return fmt.Errorf("Key that is an instance of *Header that represent the number of values is nil: use the Set method")
+	}
+
+	nbr := b.nbr.Get().(int)
+	b.v = make(map[int][]float64)
+
+	if ok := r.Scan(); !ok {
+		if r.Err() != nil {
+			return fmt.Errorf("r.Scan first line: %w", r.Err())
+		}
+		if nbr > 0 {
+			return truncated(b.Name(), 0, nbr)
+		}
+		return nil
+	}
+
+	i := 0
+	for ; i < nbr && scanValue(r); i++ {
+		f := b.opts.fields(string(delComments(r.Bytes())))
+		if len(f) < b.cols+1 {
+			return fmt.Errorf("row = %d has not enough fields = %d, want >= %d", i+1, len(f), b.cols+1)
+		}
+		id, err := parseID(strings.TrimSpace(f[0]))
+		if err != nil {
+			return setLine(parseError("strconv.ParseInt", "id", err), b.Name(), i+1)
+		}
+		values := make([]float64, b.cols)
+		for j := range values {
+			if values[j], err = strconv.ParseFloat(f[j+1], 64); err != nil {
+				return setLine(parseError("strconv.ParseFloat", "", err), b.Name(), i+1)
+			}
+		}
+		b.v[id] = values
+	}
+	if r.Err() != nil {
+		return fmt.Errorf("r.Scan: %w", r.Err())
+	}
+	if i < nbr {
+		return truncated(b.Name(), i, nbr)
+	}
+	return nil
+}
+
+// Set puts a custom map[int][]float64.
+//
+// This method does not check the integrity or correctness of the passed data.
+// The use of the Check method after Set is therefore highly recommended.
+func (b *Bonus) Set(v interface{}) error {
+	var ok bool
+	b.v, ok = v.(map[int][]float64)
+	if !ok {
+		return fmt.Errorf("type assertion error: value is not map[int][]float64")
+	}
+	return nil
+}
+
+// Get returns a map[int][]float64 where the keys are the identifiers of the
+// atoms. As this method returns an interface, it must be useful to perform a
+// type assertion after calling this method.
+func (b *Bonus) Get() interface{} {
+	return b.v
+}
+
+// Values returns the map[int][]float64 where the keys are the identifiers of
+// the atoms. Unlike Get, no type assertion is required. The map is not copied:
+// modifying it modifies the values of Bonus.
+func (b *Bonus) Values() map[int][]float64 {
+	return b.v
+}
+
+// Len returns the number of values.
+func (b *Bonus) Len() int {
+	return len(b.v)
+}
+
+// Check verifies the integrity and correctness of the data decoded with the
+// Decode method or set with the Set method.
+//
+// This method needs two Keys in order to work. The first Key is the number of
+// atoms and the second is the number of values.
+func (b *Bonus) Check() error {
+	if b.atomsNbr == nil || b.nbr == nil {
+		return fmt.Errorf("one or more Keys are nil: use the Set method")
+	}
+
+	nbr := b.nbr.Get().(int)
+	atomsNbr := b.atomsNbr.Get().(int)
+	if len(b.v) != nbr {
+		return countMismatch(b.Name(), len(b.v), nbr, "number of assigned values = %d is not equal to the number of expected values = %d")
+	}
+	for id, values := range b.v {
+		if id < 1 || id > atomsNbr {
+			return fmt.Errorf("atom = %d is invalid: it must be greater than zero and lower or equal than the number of atoms = %d", id, atomsNbr)
+		}
+		if len(values) != b.cols {
+			return fmt.Errorf("atom = %d has %d values, want %d", id, len(values), b.cols)
+		}
+		for i, v := range values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("value = %g of atom = %d at column = %d is not finite", v, id, i+1)
+			}
+		}
+	}
+	return nil
+}
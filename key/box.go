@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
 	"unicode"
 )
@@ -25,13 +26,17 @@ type Box struct {
 	vBytes [2][]byte
 	vlo    float64
 	vhi    float64
+	opts   *Options
+
+	half int     // bound matched by Keyword on a split line, -1 otherwise
+	seen [2]bool // bounds decoded from the split lines
 }
 
 // NewBox returns an instance of Box. The recommended Names are NameBoxX,
 // NameBoxY, and NameBoxZ.
 func NewBox(name Name) *Box {
 	nameSep := bytes.Fields([]byte(name))
-	return &Box{name: name, nameSep: nameSep}
+	return &Box{name: name, nameSep: nameSep, half: -1}
 }
 
 // Name returns the Name passed in NewBox. It corresponds to the keyword that
@@ -43,7 +48,14 @@ func (b *Box) Name() Name {
 
 // Keyword tests whether the byte slice s ends with the Name after two float64s.
 // Keyword is useful to detect if Box can correctly decode the two float64s.
+//
+// If Options.SplitBox is true, Keyword also accepts a line containing only one
+// bound and its token, e.g. "xlo -5" or "-5 xlo" for the lo of NameBoxX.
 func (b *Box) Keyword(s []byte) bool {
+	b.half = -1
+	if b.opts != nil && b.opts.SplitBox && b.splitKeyword(s) {
+		return true
+	}
 	for i := 0; i < 2; i++ {
 		s = bytes.TrimLeftFunc(s, unicode.IsSpace)
 		idx := bytes.IndexFunc(s, unicode.IsSpace)
@@ -56,40 +68,100 @@ func (b *Box) Keyword(s []byte) bool {
 	return keywordHeader(s, b.nameSep)
 }
 
+// splitKeyword tests whether s contains one bound and its token (e.g. "xlo -5"
+// or "-5 xlo"). The bound is stored in vBytes at the index of the token.
+func (b *Box) splitKeyword(s []byte) bool {
+	f := bytes.Fields(delComments(s))
+	if len(f) != 2 || len(b.nameSep) != 2 {
+		return false
+	}
+	for i, tok := range b.nameSep {
+		for j := range f {
+			if bytes.Equal(f[j], tok) {
+				b.half = i
+				b.vBytes[i] = f[1-j]
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Partial returns true if Options.SplitBox is true and only one of the two
+// bounds was decoded: the Box then expects the line of the other bound.
+func (b *Box) Partial() bool {
+	return b.seen[0] != b.seen[1]
+}
+
 // SetKeys assigns one or more Keys to Box. This method always return
 // ErrUnsupported as it is unsupported by Box.
 func (b *Box) SetKeys(k ...Key) error {
 	return ErrUnsupported
 }
 
+// SetOptions assigns the Options used by the Keyword, Encode, Decode, and Check
+// methods. o can be nil.
+func (b *Box) SetOptions(o *Options) {
+	b.opts = o
+}
+
 // SetKeysVal returns ErrUnsupported as it is unsupported by Box.
 func (b *Box) SetKeysVal() error {
 	return ErrUnsupported
 }
 
+// WriteDataBoxFormat is the format of the bounds of the box written by the
+// LAMMPS write_data command, e.g. "-5.0000000000000000e+00". See
+// Options.BoxFormat.
+const WriteDataBoxFormat = "%-1.16e"
+
 // Encode writes the box size followed by the Name into a writer. For instance,
-// it writes, "%float64% %float64% xlo xhi" if Name is NameBoxX.
+// it writes, "%float64% %float64% xlo xhi" if Name is NameBoxX. The bounds are
+// written with Options.BoxFormat if it is not empty, with %g otherwise (see
+// Options.TrailingZero).
 //
 // This method does not check the integrity and correctness of each value. To do
 // so, use the Check method.
 func (b *Box) Encode(w io.Writer) error {
-	_, err := fmt.Fprintf(w, "%g %g %s\n", b.vlo, b.vhi, b.Name())
+	if b.opts != nil && b.opts.BoxFormat != "" {
+		format := b.opts.BoxFormat
+		_, err := fmt.Fprintf(w, format+" "+format+" %s\n", b.vlo, b.vhi, b.Name())
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%s %s %s\n", b.opts.float(b.vlo), b.opts.float(b.vhi), b.Name())
 	return err
 }
 
 // Decode converts the box size for a specific coordinate into two float64s.
 // This method will return errors if Keyword was not called before.
 //
+// The bounds can be written as integers (e.g. 10), with a sign (e.g. +10), or
+// with an exponent (e.g. 1e1). The D or d exponent (e.g. 1D1) is accepted if
+// Options.FortranExponent is true.
+//
+// If Keyword matched a split line (see Options.SplitBox), only the bound of the
+// line is decoded. The other bound is decoded from its own line.
+//
 // This method does not check the integrity or correctness of the passed data.
 // The use of the Check method after Decode is therefore highly recommended.
 func (b *Box) Decode(s []byte, r *bufio.Scanner) error {
-	var err error
-	b.vlo, err = strconv.ParseFloat(string(b.vBytes[0]), 64)
-	if err != nil {
-		return fmt.Errorf("strconv.ParseFloat lo: %w", err)
+	dst := [2]*float64{&b.vlo, &b.vhi}
+	for i, field := range [2]string{"lo", "hi"} {
+		if b.half != -1 && b.half != i {
+			continue
+		}
+		f := string(b.vBytes[i])
+		if b.opts != nil && b.opts.FortranExponent {
+			f = fortranExponent(f)
+		}
+		var err error
+		*dst[i], err = strconv.ParseFloat(f, 64)
+		if err != nil {
+			return setLine(parseError("strconv.ParseFloat", field, err), b.Name(), 0)
+		}
+		b.seen[i] = true
 	}
-	b.vhi, err = strconv.ParseFloat(string(b.vBytes[1]), 64)
-	return err
+	return nil
 }
 
 // Set puts a custom [2]float64.
@@ -114,11 +186,34 @@ func (b *Box) Get() interface{} {
 	return [2]float64{b.vlo, b.vhi}
 }
 
+// Bounds returns the point where the box begins and the point where it ends.
+// Unlike Get, no type assertion is required.
+func (b *Box) Bounds() [2]float64 {
+	return [2]float64{b.vlo, b.vhi}
+}
+
 // Check verifies the integrity and correctness of the data decoded with the
-// Decode method or set with the Set method.
+// Decode method or set with the Set method. lo must be lower than hi as LAMMPS
+// rejects a box of zero volume. For NameBoxZ, lo can be equal to hi if
+// Options.FlatZ is true.
 func (b *Box) Check() error {
+	if b.Partial() {
+		missing := b.nameSep[0]
+		if b.seen[0] {
+			missing = b.nameSep[1]
+		}
+		return fmt.Errorf("%s of the box is missing", missing)
+	}
+	for _, v := range [2]float64{b.vlo, b.vhi} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("bound = %g is not finite", v)
+		}
+	}
 	if b.vlo > b.vhi {
 		return fmt.Errorf("lo = %g is greater than hi = %g", b.vlo, b.vhi)
 	}
+	if b.vlo == b.vhi && !(b.name == NameBoxZ && b.opts != nil && b.opts.FlatZ) {
+		return fmt.Errorf("lo = %g is equal to hi: the box has a zero length", b.vlo)
+	}
 	return nil
 }
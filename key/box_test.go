@@ -0,0 +1,82 @@
+package key
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBoxCheckDegenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		box     Name
+		bounds  [2]float64
+		flatZ   bool
+		wantErr string
+	}{
+		{"x", NameBoxX, [2]float64{0, 10}, false, ""},
+		{"degenerate x", NameBoxX, [2]float64{5, 5}, false, "lo = 5 is equal to hi: the box has a zero length"},
+		{"degenerate x with FlatZ", NameBoxX, [2]float64{5, 5}, true, "lo = 5 is equal to hi: the box has a zero length"},
+		{"degenerate z", NameBoxZ, [2]float64{0, 0}, false, "lo = 0 is equal to hi: the box has a zero length"},
+		{"flat z", NameBoxZ, [2]float64{0, 0}, true, ""},
+		{"inverted z with FlatZ", NameBoxZ, [2]float64{1, 0}, true, "lo = 1 is greater than hi = 0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBox(tt.box)
+			b.SetOptions(&Options{FlatZ: tt.flatZ})
+			b.Set(tt.bounds)
+			err := b.Check()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBoxDecodeFormats(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		fortran bool
+		want    [2]float64
+		wantErr bool
+	}{
+		{"integers", "0 10 xlo xhi", false, [2]float64{0, 10}, false},
+		{"negative integers", "-10 10 xlo xhi", false, [2]float64{-10, 10}, false},
+		{"decimals", "-0.5 10.25 xlo xhi", false, [2]float64{-0.5, 10.25}, false},
+		{"plus sign", "+0 +10 xlo xhi", false, [2]float64{0, 10}, false},
+		{"exponent", "-1e1 1e1 xlo xhi", false, [2]float64{-10, 10}, false},
+		{"signed exponent", "-1.5E+01 +1.5e+01 xlo xhi", false, [2]float64{-15, 15}, false},
+		{"write_data", "-5.0000000000000000e+00 5.0000000000000000e+00 xlo xhi", false, [2]float64{-5, 5}, false},
+		{"fortran exponent", "-1D1 1.5d+01 xlo xhi", true, [2]float64{-10, 15}, false},
+		{"fortran exponent disabled", "-1D1 1.5d+01 xlo xhi", false, [2]float64{}, true},
+		{"not a number", "0 ten xlo xhi", false, [2]float64{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := NewBox(NameBoxX)
+			b.SetOptions(&Options{FortranExponent: tt.fortran})
+			err := decode(t, b, tt.line)
+			if tt.wantErr {
+				var pe *ParseError
+				if !errors.As(err, &pe) {
+					t.Errorf("Decode = %v, want a *ParseError", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := b.Get().([2]float64); got != tt.want {
+				t.Errorf("bounds = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
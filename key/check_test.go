@@ -0,0 +1,60 @@
+package key
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCheckFinite(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	tests := []struct {
+		name    string
+		key     func() Key
+		wantErr string
+	}{
+		{"coeffs", func() Key {
+			c := NewCoeffs(NamePairCoeffs)
+			c.SetKeys(header(NameAtomTypes, 2))
+			c.Set(map[int][]float64{1: {0.1, 3.4}, 2: {0.2, nan}})
+			return c
+		}, "coefficient = NaN of type = 2 at column = 2 is not finite"},
+		{"infinite coeffs", func() Key {
+			c := NewCoeffs(NameBondCoeffs)
+			c.SetKeys(header(NameBondTypes, 1))
+			c.Set(map[int][]float64{1: {inf, 1}})
+			return c
+		}, "coefficient = +Inf of type = 1 at column = 1 is not finite"},
+		{"masses", func() Key {
+			m := new(Masses)
+			m.SetKeys(header(NameAtomTypes, 1))
+			m.Set(map[int]float64{1: nan})
+			return m
+		}, "mass of type = 1 is not finite = NaN"},
+		{"box", func() Key {
+			b := NewBox(NameBoxX)
+			b.Set([2]float64{0, inf})
+			return b
+		}, "bound = +Inf is not finite"},
+		{"finite", func() Key {
+			c := NewCoeffs(NamePairCoeffs)
+			c.SetKeys(header(NameAtomTypes, 1))
+			c.Set(map[int][]float64{1: {0.1, 3.4}})
+			return c
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.key().Check()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package key
+
+import "strings"
+
+// coeffColumns contains the number of coefficients written in the Coeffs tables
+// of a LAMMPS data file for the common styles. The keys are the kind of the
+// interaction followed by the name of the style.
+var coeffColumns = map[string]int{
+	"pair lj/cut":                2, // epsilon, sigma
+	"pair lj/cut/coul/cut":       2,
+	"pair lj/cut/coul/long":      2,
+	"pair lj/cut/coul/debye":     2,
+	"pair lj/cut/tip4p/long":     2,
+	"pair lj/charmm/coul/long":   2, // epsilon, sigma (epsilon14 and sigma14 are optional)
+	"pair lj/charmm/coul/charmm": 2,
+	"pair lj96/cut":              2,
+	"pair mie/cut":               4, // epsilon, sigma, gammaR, gammaA
+	"pair buck":                  3, // A, rho, C
+	"pair buck/coul/long":        3,
+	"pair born":                  5, // A, rho, sigma, C, D
+	"pair morse":                 3, // D0, alpha, r0
+	"pair soft":                  1, // A
+	"pair yukawa":                1, // A
+
+	"bond harmonic":    2, // K, r0
+	"bond morse":       3, // D0, alpha, r0
+	"bond fene":        4, // K, R0, epsilon, sigma
+	"bond fene/expand": 5, // K, R0, epsilon, sigma, delta
+	"bond nonlinear":   3, // epsilon, r0, lamda
+	"bond quartic":     5, // K, B1, B2, Rc, U0
+	"bond class2":      4, // r0, K2, K3, K4
+	"bond gromos":      2, // K, r0
+
+	"angle harmonic":        2, // K, theta0
+	"angle cosine":          1, // K
+	"angle cosine/squared":  2, // K, theta0
+	"angle cosine/periodic": 3, // C, B, n
+	"angle charmm":          4, // K, theta0, K_ub, r_ub
+	"angle class2":          4, // theta0, K2, K3, K4
+
+	"dihedral harmonic":       3, // K, d, n
+	"dihedral charmm":         4, // K, n, d, weighting factor
+	"dihedral opls":           4, // K1, K2, K3, K4
+	"dihedral multi/harmonic": 5, // A1, A2, A3, A4, A5
+	"dihedral quadratic":      2, // K, phi0
+	"dihedral class2":         6, // K1, phi1, K2, phi2, K3, phi3
+
+	"improper harmonic": 2, // K, chi
+	"improper cvff":     3, // K, d, n
+	"improper umbrella": 2, // K, omega0
+	"improper class2":   2, // K, chi0
+}
+
+// CoeffColumns returns the number of coefficients (i.e. the columns after the
+// type) expected in the Coeffs tables for style. style is the kind of the
+// interaction (pair, bond, angle, dihedral, or improper) followed by the name
+// of the style, e.g. "bond harmonic" or "pair lj/cut", as the same name may be
+// used by several kinds with a different number of coefficients. It returns
+// false if the style is not known.
+//
+// The optional coefficients (e.g. the cutoff of pair lj/cut) are not counted.
+// Only the styles whose number of coefficients is fixed are known: the hybrid
+// styles or the styles reading a file (e.g. pair table) are not.
+func CoeffColumns(style string) (int, bool) {
+	n, ok := coeffColumns[strings.Join(strings.Fields(style), " ")]
+	return n, ok
+}
@@ -2,10 +2,11 @@ package key
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
-	"strings"
 )
 
 // Coeffs is used to encode and/or decode a table containing the coefficients
@@ -14,19 +15,34 @@ import (
 // 1 type) has 2 or more columns. More information about the structure of this
 // table can be found in the LAMMPS documentation.
 //
-// Coeffs can be instanced by using the NewCoeffs method.
+// Coeffs can be instanced by using the NewCoeffs or NewCoeffsHybrid methods.
 type Coeffs struct {
-	name  Name
-	types *Header
-	v     map[int][]float64
+	name   Name
+	hybrid bool
+	types  *Header
+	opts   *Options
+	v      map[int][]float64
+	styles map[int]string
 }
 
+// maxWildcardTypes is the largest number of types into which a wildcard is
+// expanded by Decode. It prevents a corrupt header from exhausting the memory.
+const maxWildcardTypes = 1 << 16
+
 // NewCoeffs returns an instance of Coeffs. The recommended Names are
 // NameBondCoeffs, NamePairCoeffs, NameAngleCoeffs, and NameDihedralCoeffs.
 func NewCoeffs(name Name) *Coeffs {
 	return &Coeffs{name: name}
 }
 
+// NewCoeffsHybrid returns an instance of Coeffs for the hybrid styles (e.g.
+// pair_style hybrid). The second column of each value is the name of the
+// sub-style (e.g. "1 lj/cut 0.1 3.4") and is not a coefficient. The sub-styles
+// can be obtained or set with the Styles and SetStyles methods.
+func NewCoeffsHybrid(name Name) *Coeffs {
+	return &Coeffs{name: name, hybrid: true}
+}
+
 // Name returns the Name passed in NewCoeffs. It corresponds to the header of
 // the table.
 func (c *Coeffs) Name() Name {
@@ -34,9 +50,10 @@ func (c *Coeffs) Name() Name {
 }
 
 // Keyword tests whether the byte slice s begins with Name after trimming the
-// spaces. Keyword is useful to detect the header of the Coeffs table.
+// spaces. Keyword is useful to detect the header of the Coeffs table. The case
+// is ignored if Options.CaseInsensitive is true.
 func (c *Coeffs) Keyword(s []byte) bool {
-	return keyword(s, []byte(c.Name()))
+	return c.opts.keyword(s, c.Name())
 }
 
 // SetKeys assigns one or more Keys to Atoms. This method only accepts *Header
@@ -54,6 +71,12 @@ func (c *Coeffs) SetKeys(k ...Key) error {
 	return nil
 }
 
+// SetOptions assigns the Options used by the Keyword and Decode methods. o can
+// be nil.
+func (c *Coeffs) SetOptions(o *Options) {
+	c.opts = o
+}
+
 // SetKeysVal assigns to the NamexxxTypes (where xxx can be Atom, Angle, Bond,
 // etc.) Key the number of types based on the length of the map that is created
 // via the Set or Decode methods.
@@ -61,13 +84,31 @@ func (c *Coeffs) SetKeys(k ...Key) error {
 // This method needs a Key in order to work. This Key is an instance of Header
 // with Name equal to NamesxxTypes where xxx can be Atom, Angle, Bond, etc. Use
 // the Set method to assign this Key.
+//
+// For the class2 cross-term tables (e.g. NameBondBondCoeffs), this method
+// returns ErrUnsupported: their number of types is the one of their parent
+// interaction (e.g. NameAngleTypes) and must be set by the parent table or
+// the header itself. The Check method then verifies the table against it.
 func (c *Coeffs) SetKeysVal() error {
+	if isCrossTerm(c.name) {
+		return ErrUnsupported
+	}
 	if c.types == nil {
 		return fmt.Errorf("Key that is an instance of *Header with Name equal to NamexxxTypes is nil: use the Set method")
 	}
 	return c.types.Set(len(c.v))
 }
 
+// isCrossTerm returns true if name is the Name of a class2 cross-term table.
+func isCrossTerm(name Name) bool {
+	switch name {
+	case NameBondBondCoeffs, NameBondAngleCoeffs, NameMiddleBondTorsionCoeffs, NameEndBondTorsionCoeffs,
+		NameAngleTorsionCoeffs, NameAngleAngleTorsionCoeffs, NameBondBond13Coeffs, NameAngleAngleCoeffs:
+		return true
+	}
+	return false
+}
+
 // Encode writes a table containing the header, a blank line and each value (= 1
 // line = 1 type) into a writer.
 //
@@ -87,8 +128,13 @@ func (c *Coeffs) Encode(w io.Writer) error {
 		if _, err := fmt.Fprintf(w, "%d", k); err != nil {
 			return fmt.Errorf("fmt.Fprintf: %w", err)
 		}
+		if c.hybrid {
+			if _, err := fmt.Fprintf(w, " %s", c.styles[k]); err != nil {
+				return fmt.Errorf("fmt.Fprintf style: %w", err)
+			}
+		}
 		for _, v := range c.v[k] {
-			if _, err := fmt.Fprintf(w, " %g", v); err != nil {
+			if _, err := fmt.Fprintf(w, " %s", c.opts.float(v)); err != nil {
 				return fmt.Errorf("fmt.Fprintf coeff: %w", err)
 			}
 		}
@@ -108,49 +154,118 @@ func (c *Coeffs) Encode(w io.Writer) error {
 // with Name equal to NamexxxTypes where xxx can be Atom, Angle, Bond, etc. Use
 // the Set method to assign this Key.
 //
+// As the table is present, the Key must be greater than zero: its minimum is
+// set to one (see Header.SetMin).
+//
+// The blank lines between the values are skipped and are not counted.
+//
+// If Options.Wildcard is true, a value whose type is "*" (e.g. "* 0.1 3.4")
+// sets the coefficients of every type. The following values override the
+// coefficients of their type. Once every type has coefficients, the table ends
+// at the next blank line. An error is returned if there are more than
+// maxWildcardTypes types.
+//
 // Moreover, this method does not check the integrity and corectness of the
 // values decoded. To do so, use the Check method.
 //
-// Decode method does not return io.EOF error. The use of the Check method after
-// Decode is therefore highly recommended.
+// Decode method does not return io.EOF error. If the input ends before the
+// number of expected types is read, an error wrapping ErrTruncated is returned.
 func (c *Coeffs) Decode(s []byte, r *bufio.Scanner) error {
 	if c.types == nil {
 		return fmt.Errorf("Key that is an instance of *Header with Name equal to NamexxxTypes is nil: use the Set method")
 	}
+	c.types.SetMin(1) // the table is present: there is at least one type
 
 	types := c.types.Get().(int)
 	c.v = make(map[int][]float64)
+	if c.hybrid {
+		c.styles = make(map[int]string)
+	}
 
 	if ok := r.Scan(); !ok {
 		if r.Err() != nil {
 			return fmt.Errorf("r.Scan first line: %w", r.Err())
 		}
+		if types > 0 {
+			return truncated(c.Name(), 0, types)
+		}
 		return nil
 	}
 
-	for i := 0; i < types && r.Scan(); i++ {
+	i, width := 0, 0
+	wildcard, next := false, false // next is true if the line scanned is the next value
+	for ; i < types || wildcard; i++ {
+		if !next && !scanValue(r) {
+			break
+		}
+		next = false
 		s := delComments(r.Bytes())
-		f := strings.Fields(string(s))
+		f := c.opts.fields(string(s))
+		if i == 0 {
+			width = len(f)
+		} else if !c.hybrid {
+			if err := c.opts.width(c.Name(), i+1, len(f), width); err != nil {
+				return err
+			}
+		}
 		if len(f) < 2 {
 			return fmt.Errorf("not enough fields = %d, want >= 2", len(f))
 		}
-		typ, err := strconv.Atoi(f[0])
-		if err != nil {
-			return fmt.Errorf("strconv.Atoi type: %w", err)
+		typs := make([]int, 1)
+		if f[0] == "*" && c.opts != nil && c.opts.Wildcard {
+			if types > maxWildcardTypes {
+				return fmt.Errorf("wildcard cannot be expanded into %d types: the maximum is %d", types, maxWildcardTypes)
+			}
+			wildcard = true
+			typs = typs[:0]
+			for typ := 1; typ <= types; typ++ {
+				typs = append(typs, typ)
+			}
+		} else {
+			var err error
+			typs[0], err = strconv.Atoi(f[0])
+			if err != nil {
+				return setLine(parseError("strconv.Atoi", "type", err), c.Name(), i+1)
+			}
+		}
+		var style string
+		if c.hybrid {
+			style = f[1]
+			f = f[1:]
 		}
 		var coeffs []float64
 		for _, v := range f[1:] {
 			coeff, err := strconv.ParseFloat(v, 64)
 			if err != nil {
-				return fmt.Errorf("strconv.ParseFloat: %w", err)
+				return setLine(parseError("strconv.ParseFloat", "", err), c.Name(), i+1)
 			}
 			coeffs = append(coeffs, coeff)
 		}
-		c.v[typ] = coeffs
+		for j, typ := range typs {
+			if j > 0 {
+				coeffs = append([]float64(nil), coeffs...) // each type has its own slice
+			}
+			c.v[typ] = coeffs
+			if c.hybrid {
+				c.styles[typ] = style
+			}
+		}
+
+		if wildcard {
+			// every type has coefficients: the table ends at the next blank
+			// line instead of after one value per type.
+			if !r.Scan() || len(bytes.TrimSpace(r.Bytes())) == 0 {
+				break
+			}
+			next = true
+		}
 	}
 	if r.Err() != nil {
 		return fmt.Errorf("r.Scan: %w", r.Err())
 	}
+	if i < types && !wildcard {
+		return truncated(c.Name(), i, types)
+	}
 	return nil
 }
 
@@ -167,6 +282,24 @@ func (c *Coeffs) Set(v interface{}) error {
 	return nil
 }
 
+// Styles returns a map where the keys are the types and the values are the
+// names of the sub-styles. It returns nil if Coeffs was not instanced with
+// NewCoeffsHybrid.
+func (c *Coeffs) Styles() map[int]string {
+	return c.styles
+}
+
+// SetStyles puts a custom map where the keys are the types and the values are
+// the names of the sub-styles. It returns an error if Coeffs was not instanced
+// with NewCoeffsHybrid.
+func (c *Coeffs) SetStyles(styles map[int]string) error {
+	if !c.hybrid {
+		return fmt.Errorf("Coeffs is not hybrid: use the NewCoeffsHybrid function")
+	}
+	c.styles = styles
+	return nil
+}
+
 // Get returns a map[int][]float64 where the keys are the identifiers of the atoms.
 // As this method returns an interface, it must be useful to perform a type
 // assertion after calling this method.
@@ -174,6 +307,34 @@ func (c *Coeffs) Get() interface{} {
 	return c.v
 }
 
+// Values returns the map[int][]float64 where the keys are the types. Unlike Get,
+// no type assertion is required. The map is not copied: modifying it modifies
+// the values of Coeffs.
+func (c *Coeffs) Values() map[int][]float64 {
+	return c.v
+}
+
+// Each calls fn for each set of coefficients in increasing order of type.
+func (c *Coeffs) Each(fn func(typ int, coeffs []float64)) {
+	for _, typ := range sortIntsMap(c.v) {
+		fn(typ, c.v[typ])
+	}
+}
+
+// EachHybrid works like Each but fn also receives the name of the sub-style of
+// each type (e.g. "lj/cut"). The name is empty if Coeffs was not instanced
+// with NewCoeffsHybrid.
+func (c *Coeffs) EachHybrid(fn func(typ int, style string, coeffs []float64)) {
+	for _, typ := range sortIntsMap(c.v) {
+		fn(typ, c.styles[typ], c.v[typ])
+	}
+}
+
+// Len returns the number of sets of coefficients.
+func (c *Coeffs) Len() int {
+	return len(c.v)
+}
+
 // Check verifies the integrity and correctness of the data decoded with the
 // Decode method or set with the Set method.
 //
@@ -186,12 +347,20 @@ func (c *Coeffs) Check() error {
 	}
 	types := c.types.Get().(int)
 	if len(c.v) != types {
-		return fmt.Errorf("number of sets of coefficients (= 1 line = 1 type) = %d is not equal to the number of types = %d", len(c.v), types)
+		return countMismatch(c.Name(), len(c.v), types, "number of sets of coefficients (= 1 line = 1 type) = %d is not equal to the number of types = %d")
 	}
-	for typ := range c.v {
+	for typ, coeffs := range c.v {
 		if typ < 1 || typ > types {
 			return fmt.Errorf("type = %d is invalid: it must be greater than zero and lower or equal than the number of types = %d", typ, types)
 		}
+		if c.hybrid && c.styles[typ] == "" {
+			return fmt.Errorf("sub-style of type = %d is missing", typ)
+		}
+		for i, v := range coeffs {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("coefficient = %g of type = %d at column = %d is not finite", v, typ, i+1)
+			}
+		}
 	}
 	return nil
 }
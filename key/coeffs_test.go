@@ -0,0 +1,249 @@
+package key
+
+import (
+	"bufio"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCoeffsHybrid(t *testing.T) {
+	const in = "Pair Coeffs # hybrid\n\n1 lj/cut 0.1 3.4\n2 coul/cut 10\n"
+	c := NewCoeffsHybrid(NamePairCoeffs)
+	c.SetKeys(header(NameAtomTypes, 2))
+	if err := decode(t, c, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Check(); err != nil {
+		t.Fatal(err)
+	}
+	wantStyles := map[int]string{1: "lj/cut", 2: "coul/cut"}
+	if !reflect.DeepEqual(c.Styles(), wantStyles) {
+		t.Errorf("Styles = %v, want %v", c.Styles(), wantStyles)
+	}
+	wantValues := map[int][]float64{1: {0.1, 3.4}, 2: {10}}
+	if !reflect.DeepEqual(c.Values(), wantValues) {
+		t.Errorf("Values = %v, want %v", c.Values(), wantValues)
+	}
+	const out = "Pair Coeffs\n\n1 lj/cut 0.1 3.4\n2 coul/cut 10\n"
+	if got := encode(t, c); got != out {
+		t.Errorf("Encode = %q, want %q", got, out)
+	}
+
+	c.SetStyles(map[int]string{1: "lj/cut"})
+	if err := c.Check(); err == nil {
+		t.Error("Check = nil with a missing sub-style")
+	}
+	if err := NewCoeffs(NamePairCoeffs).SetStyles(wantStyles); err == nil {
+		t.Error("SetStyles = nil for a Coeffs that is not hybrid")
+	}
+}
+
+func TestCoeffsValues(t *testing.T) {
+	c := NewCoeffs(NameBondCoeffs)
+	c.SetKeys(header(NameBondTypes, 1))
+	if err := decode(t, c, "Bond Coeffs\n\n1 450 1\n"); err != nil {
+		t.Fatal(err)
+	}
+	v := c.Values()
+	if !reflect.DeepEqual(v, c.Get()) {
+		t.Errorf("Values = %v, want Get = %v", v, c.Get())
+	}
+	// the map is not copied.
+	v[1][0] = 500
+	if got := encode(t, c); got != "Bond Coeffs\n\n1 500 1\n" {
+		t.Errorf("Encode = %q after modifying Values", got)
+	}
+}
+
+func TestCoeffsEach(t *testing.T) {
+	type row struct {
+		typ    int
+		style  string
+		coeffs []float64
+	}
+	tests := []struct {
+		name  string
+		c     *Coeffs
+		in    string
+		types int
+		want  []row
+	}{
+		{"hybrid", NewCoeffsHybrid(NamePairCoeffs), "Pair Coeffs # hybrid\n\n3 coul/cut 10\n1 lj/cut 0.1 3.4\n2 lj/cut 0.2 2.5\n", 3,
+			[]row{{1, "lj/cut", []float64{0.1, 3.4}}, {2, "lj/cut", []float64{0.2, 2.5}}, {3, "coul/cut", []float64{10}}}},
+		{"not hybrid", NewCoeffs(NamePairCoeffs), "Pair Coeffs\n\n2 0.2 2.5\n1 0.1 3.4\n", 2,
+			[]row{{1, "", []float64{0.1, 3.4}}, {2, "", []float64{0.2, 2.5}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.c.SetKeys(header(NameAtomTypes, tt.types))
+			if err := decode(t, tt.c, tt.in); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []row
+			tt.c.EachHybrid(func(typ int, style string, coeffs []float64) {
+				got = append(got, row{typ, style, coeffs})
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EachHybrid = %v, want %v", got, tt.want)
+			}
+
+			got = got[:0]
+			tt.c.Each(func(typ int, coeffs []float64) {
+				got = append(got, row{typ: typ, coeffs: coeffs})
+			})
+			for i := range tt.want {
+				tt.want[i].style = ""
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Each = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoeffsCrossTermTypes(t *testing.T) {
+	tests := []struct {
+		name      string
+		table     Name
+		types     Name
+		crossTerm bool
+	}{
+		{"Angle Coeffs", NameAngleCoeffs, NameAngleTypes, false},
+		{"BondBond Coeffs", NameBondBondCoeffs, NameAngleTypes, true},
+		{"BondAngle Coeffs", NameBondAngleCoeffs, NameAngleTypes, true},
+		{"MiddleBondTorsion Coeffs", NameMiddleBondTorsionCoeffs, NameDihedralTypes, true},
+		{"AngleAngle Coeffs", NameAngleAngleCoeffs, NameImproperTypes, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the table has 2 values but the parent interaction has 3 types.
+			h := header(tt.types, 3)
+			c := NewCoeffs(tt.table)
+			c.SetKeys(h)
+			if err := c.Set(map[int][]float64{1: {1, 2}, 2: {3, 4}}); err != nil {
+				t.Fatal(err)
+			}
+			err := c.SetKeysVal()
+			if !tt.crossTerm {
+				if err != nil || h.Value() != 2 {
+					t.Errorf("SetKeysVal = %v with %d types, want nil with 2 types", err, h.Value())
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnsupported) || h.Value() != 3 {
+				t.Errorf("SetKeysVal = %v with %d types, want ErrUnsupported with 3 types", err, h.Value())
+			}
+			var cm *CountMismatchError
+			if err := c.Check(); !errors.As(err, &cm) || cm.Got != 2 || cm.Want != 3 {
+				t.Errorf("Check = %v, want a *CountMismatchError of 2 values for 3 types", err)
+			}
+		})
+	}
+}
+
+func TestCoeffColumns(t *testing.T) {
+	tests := []struct {
+		style string
+		n     int
+		ok    bool
+	}{
+		{"bond harmonic", 2, true},
+		{"angle harmonic", 2, true},
+		{"dihedral harmonic", 3, true},
+		{"improper harmonic", 2, true},
+		{"pair lj/cut", 2, true},
+		{"pair lj/charmm/coul/long", 2, true},
+		{"pair lj/charmm/coul/charmm", 2, true},
+		{"bond fene", 4, true},
+		{"dihedral opls", 4, true},
+		{"  bond   morse ", 3, true},
+		{"harmonic", 0, false},
+		{"pair hybrid", 0, false},
+		{"pair table", 0, false},
+		{"Bond Harmonic", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		n, ok := CoeffColumns(tt.style)
+		if n != tt.n || ok != tt.ok {
+			t.Errorf("CoeffColumns(%q) = %d, %v, want %d, %v", tt.style, n, ok, tt.n, tt.ok)
+		}
+	}
+
+	// the count matches the coefficients of a Bond Coeffs table.
+	c := NewCoeffs(NameBondCoeffs)
+	c.SetKeys(header(NameBondTypes, 1))
+	if err := decode(t, c, "Bond Coeffs # harmonic\n\n1 450 1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := CoeffColumns("bond harmonic"); len(c.Values()[1]) != n {
+		t.Errorf("len = %d coefficients, want CoeffColumns = %d", len(c.Values()[1]), n)
+	}
+}
+
+func TestCoeffsWildcard(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        *Coeffs
+		rows     string
+		wildcard bool
+		want     string
+		wantErr  string
+	}{
+		{"all types", NewCoeffs(NamePairCoeffs), "* 0.1 3.4\n", true,
+			"Pair Coeffs\n\n1 0.1 3.4\n2 0.1 3.4\n3 0.1 3.4\n", ""},
+		{"overridden", NewCoeffs(NamePairCoeffs), "* 0.1 3.4\n2 0.2 2.5\n", true,
+			"Pair Coeffs\n\n1 0.1 3.4\n2 0.2 2.5\n3 0.1 3.4\n", ""},
+		{"every type overridden", NewCoeffs(NamePairCoeffs), "* 0.1 3.4\n1 0.2 2.5\n2 0.3 2.5\n3 0.4 2.5\n", true,
+			"Pair Coeffs\n\n1 0.2 2.5\n2 0.3 2.5\n3 0.4 2.5\n", ""},
+		{"last value", NewCoeffs(NamePairCoeffs), "1 0.2 2.5\n* 0.1 3.4\n", true,
+			"Pair Coeffs\n\n1 0.1 3.4\n2 0.1 3.4\n3 0.1 3.4\n", ""},
+		{"hybrid", NewCoeffsHybrid(NamePairCoeffs), "* lj/cut 0.1 3.4\n3 coul/cut 10\n", true,
+			"Pair Coeffs\n\n1 lj/cut 0.1 3.4\n2 lj/cut 0.1 3.4\n3 coul/cut 10\n", ""},
+		{"disabled", NewCoeffs(NamePairCoeffs), "* 0.1 3.4\n", false, "", "strconv.Atoi"},
+		{"truncated", NewCoeffs(NamePairCoeffs), "1 0.1 3.4\n", true, "", "truncated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.c.SetKeys(header(NameAtomTypes, 3))
+			tt.c.SetOptions(&Options{Wildcard: tt.wildcard})
+			in := "Pair Coeffs\n\n" + tt.rows
+			if tt.c.hybrid {
+				in = "Pair Coeffs # hybrid\n\n" + tt.rows
+			}
+			if tt.wantErr == "" {
+				in += "\nBond Coeffs\n"
+			}
+			r := bufio.NewScanner(strings.NewReader(in))
+			r.Scan()
+			err := tt.c.Decode(r.Bytes(), r)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := encode(t, tt.c); got != tt.want {
+				t.Errorf("Encode = %q, want %q", got, tt.want)
+			}
+			// the values of the table are all read.
+			for r.Scan() && len(strings.TrimSpace(r.Text())) == 0 {
+			}
+			if r.Text() != "Bond Coeffs" {
+				t.Errorf("next line = %q, want %q", r.Text(), "Bond Coeffs")
+			}
+			// each type has its own coefficients.
+			v := tt.c.Values()
+			v[1][0] = 1
+			if v[2][0] == 1 {
+				t.Error("types = 1 and 2 share their coefficients")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package key
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ParseError is an error returned by the Decode methods if a value cannot be
+// converted into a number. It can be extracted with errors.As.
+type ParseError struct {
+	// Section is the Name of the Key that failed to decode the value.
+	Section Name
+	// Line is the position of the value (= 1 line) in the table, starting at
+	// one. It is zero if the Key is not a table (e.g. Header).
+	Line int
+	// Field is the name of the field that cannot be converted (e.g. "id",
+	// "X"). It can be empty.
+	Field string
+	// Func is the function that failed (e.g. "strconv.Atoi").
+	Func string
+	// Err is the error returned by Func.
+	Err error
+}
+
+// parseError returns a ParseError without Section and Line. They are set
+// afterwards by the table with the setLine function.
+func parseError(fn, field string, err error) error {
+	return &ParseError{Func: fn, Field: field, Err: err}
+}
+
+// setLine assigns the Section and the Line to err if it is a ParseError.
+func setLine(err error, section Name, line int) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.Section = section
+		pe.Line = line
+	}
+	return err
+}
+
+func (e *ParseError) Error() string {
+	var line string
+	if e.Line > 0 {
+		line = fmt.Sprintf("row = %d: ", e.Line)
+	}
+	if e.Field == "" {
+		return fmt.Sprintf("%s%s: %v", line, e.Func, e.Err)
+	}
+	return fmt.Sprintf("%s%s %s: %v", line, e.Func, e.Field, e.Err)
+}
+
+// Unwrap returns the error returned by Func.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// CountMismatchError is an error returned by the Check methods if the number of
+// values of a table is not equal to the number declared by its Header. It can
+// be extracted with errors.As.
+type CountMismatchError struct {
+	// Section is the Name of the table.
+	Section Name
+	// Got is the number of values of the table.
+	Got int
+	// Want is the number of values declared by the Header.
+	Want int
+
+	format string
+}
+
+// countMismatch returns a CountMismatchError whose message is given by format.
+// format must contain two %d verbs for Got and Want.
+func countMismatch(section Name, got, want int, format string) error {
+	return &CountMismatchError{Section: section, Got: got, Want: want, format: format}
+}
+
+func (e *CountMismatchError) Error() string {
+	if e.format == "" {
+		return fmt.Sprintf("number of values of %s = %d is not equal to the number of expected values = %d", e.Section, e.Got, e.Want)
+	}
+	return fmt.Sprintf(e.format, e.Got, e.Want)
+}
@@ -18,8 +18,10 @@ type Header struct {
 	name    Name
 	nameSep [][]byte
 
-	vBytes []byte
-	v      int
+	vBytes  []byte
+	v       int
+	min     int
+	comment string
 }
 
 // NewHeader returns an instance of Header.
@@ -35,7 +37,9 @@ func (h *Header) Name() Name {
 }
 
 // Keyword tests whether the byte slice s ends with the Name after an integer.
-// Keyword is useful to detect if Header can correctly decode the integer.
+// Keyword is useful to detect if Header can correctly decode the integer. The
+// words following the Name are ignored, e.g. "100 atoms # solvent" is matched
+// by NameAtomsNbr.
 func (h *Header) Keyword(s []byte) bool {
 	s = bytes.TrimLeftFunc(s, unicode.IsSpace)
 	idx := bytes.IndexFunc(s, unicode.IsSpace) // always a space after the number. After this space there is Name.
@@ -58,17 +62,23 @@ func (h *Header) SetKeysVal() error {
 }
 
 // Encode writes an integer followed by the Name into a writer. For instance, it
-// writes, "%int% atoms" if Name is NameAtomsNbr.
+// writes, "%int% atoms" if Name is NameAtomsNbr. The comment set with
+// SetComment is written after the Name if any.
 //
 // This method does not check the integrity and correctness of each value. To do
 // so, use the Check method.
 func (h *Header) Encode(w io.Writer) error {
+	if h.comment != "" {
+		_, err := fmt.Fprintf(w, "%d %s # %s\n", h.Get().(int), h.Name(), h.comment)
+		return err
+	}
 	_, err := fmt.Fprintf(w, "%d %s\n", h.Get().(int), h.Name())
 	return err
 }
 
 // Decode converts the integer for a specific keyword. This method will return
-// errors if Keyword was not called before.
+// errors if Keyword was not called before. The comment following the Name (e.g.
+// "solvent" for "100 atoms # solvent") is kept (see Comment).
 //
 // This method does not check the integrity or correctness of the passed data.
 // The use of the Check method after Decode is therefore highly recommended.
@@ -76,8 +86,9 @@ func (h *Header) Decode(s []byte, r *bufio.Scanner) error {
 	var err error
 	h.v, err = strconv.Atoi(string(h.vBytes))
 	if err != nil {
-		return fmt.Errorf("strconv.Atoi: %w", err)
+		return setLine(parseError("strconv.Atoi", "", err), h.Name(), 0)
 	}
+	_, h.comment = splitComment(s)
 	return nil
 }
 
@@ -100,11 +111,42 @@ func (h *Header) Get() interface{} {
 	return h.v
 }
 
+// Value returns the value of the Header. Unlike Get, no type assertion is
+// required.
+func (h *Header) Value() int {
+	return h.v
+}
+
+// Comment returns the comment following the Name without the "#" (e.g.
+// "solvent" for "100 atoms # solvent"). It is empty if there is no comment.
+func (h *Header) Comment() string {
+	return h.comment
+}
+
+// SetComment sets the comment written after the Name by the Encode method. An
+// empty comment removes it.
+func (h *Header) SetComment(c string) {
+	h.comment = c
+}
+
+// SetMin sets the minimum value accepted by the Check method. It is zero by
+// default. min cannot be lower than zero.
+func (h *Header) SetMin(min int) {
+	if min < 0 {
+		min = 0
+	}
+	h.min = min
+}
+
 // Check verifies the integrity and correctness of the data decoded with the
-// Decode method or set with the Set method.
+// Decode method or set with the Set method. The integer must be greater or
+// equal than the minimum set with SetMin.
 func (h *Header) Check() error {
 	if h.v < 0 {
 		return fmt.Errorf("integer = %d is lower than zero", h.v)
 	}
+	if h.v < h.min {
+		return fmt.Errorf("integer = %d is lower than the minimum = %d", h.v, h.min)
+	}
 	return nil
 }
@@ -0,0 +1,50 @@
+package key
+
+import "testing"
+
+func TestHeaderDescriptiveWords(t *testing.T) {
+	tests := []struct {
+		name    Name
+		in      string
+		keyword bool
+		v       int
+		comment string
+	}{
+		{NameAtomsNbr, "100 atoms", true, 100, ""},
+		{NameAtomsNbr, "100 atoms # solvent", true, 100, "solvent"},
+		{NameAtomsNbr, "  100\tatoms\t#solvent water", true, 100, "solvent water"},
+		{NameAtomsNbr, "100 atoms of solvent", true, 100, ""},
+		{NameAtomsNbr, "100 atoms #", true, 100, ""},
+		{NameAtomTypes, "2 atom types # O H", true, 2, "O H"},
+		{NameAtomsNbr, "2 atom types # O H", false, 0, ""},
+		{NameAtomsNbr, "# 100 atoms", false, 0, ""},
+		{NameBondsNbr, "100 atoms # bonds", false, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			h := NewHeader(tt.name)
+			if got := h.Keyword([]byte(tt.in)); got != tt.keyword {
+				t.Fatalf("Keyword = %v, want %v", got, tt.keyword)
+			}
+			if !tt.keyword {
+				return
+			}
+			if err := decode(t, h, tt.in); err != nil {
+				t.Fatal(err)
+			}
+			if h.Value() != tt.v || h.Comment() != tt.comment {
+				t.Errorf("Value = %d and Comment = %q, want %d and %q", h.Value(), h.Comment(), tt.v, tt.comment)
+			}
+		})
+	}
+
+	h := header(NameAtomsNbr, 100)
+	h.SetComment("solvent")
+	if got := encode(t, h); got != "100 atoms # solvent\n" {
+		t.Errorf("Encode = %q, want %q", got, "100 atoms # solvent\n")
+	}
+	h.SetComment("")
+	if got := encode(t, h); got != "100 atoms\n" {
+		t.Errorf("Encode = %q, want %q", got, "100 atoms\n")
+	}
+}
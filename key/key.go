@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"bytes"
 	"errors"
+	"fmt"
 	"io"
 	"reflect"
 	"sort"
+	"strconv"
 	"unicode"
 )
 
@@ -69,6 +71,14 @@ const (
 	NameAnglesNbr Name = "angles"
 	// NameDihedralsNbr is the Name related to the number of dihedrals.
 	NameDihedralsNbr Name = "dihedrals"
+	// NameImpropersNbr is the Name related to the number of impropers.
+	NameImpropersNbr Name = "impropers"
+	// NameLinesNbr is the Name related to the number of lines (atom_style
+	// line).
+	NameLinesNbr Name = "lines"
+	// NameTrianglesNbr is the Name related to the number of triangles
+	// (atom_style tri).
+	NameTrianglesNbr Name = "triangles"
 
 	// NameAtomTypes is the Name related to the number of atom types.
 	NameAtomTypes Name = "atom types"
@@ -78,6 +88,8 @@ const (
 	NameAngleTypes Name = "angle types"
 	// NameDihedralTypes is the Name related to the number of dihedral types.
 	NameDihedralTypes Name = "dihedral types"
+	// NameImproperTypes is the Name related to the number of improper types.
+	NameImproperTypes Name = "improper types"
 
 	// NameBoxX is the Name related to the size of the box for the x coordinate.
 	NameBoxX Name = "xlo xhi"
@@ -93,6 +105,10 @@ const (
 	// NamePairCoeffs is the Name related to the Pair Coeffs table (1st column: atom
 	// type, other columns: depend on pair_style)
 	NamePairCoeffs Name = "Pair Coeffs"
+	// NamePairIJCoeffs is the Name related to the PairIJ Coeffs table (1st
+	// and 2nd columns: atom types i <= j, other columns: depend on
+	// pair_style).
+	NamePairIJCoeffs Name = "PairIJ Coeffs"
 	// NameBondCoeffs is the Name related to the Bond Coeffs table (1st column:
 	// bond type, other columns: related to bond_style).
 	NameBondCoeffs Name = "Bond Coeffs"
@@ -103,6 +119,36 @@ const (
 	// column: dihedral type, other columns: depend on dihedral_style).
 	NameDihedralCoeffs Name = "Dihedral Coeffs"
 
+	// The Names below are related to the cross-term tables of the class2
+	// force field. Each table is indexed by the type of its parent
+	// interaction: BondBond and BondAngle Coeffs by angle type;
+	// MiddleBondTorsion, EndBondTorsion, AngleTorsion, AngleAngleTorsion,
+	// and BondBond13 Coeffs by dihedral type; AngleAngle Coeffs by improper
+	// type.
+
+	// NameBondBondCoeffs is the Name related to the BondBond Coeffs table.
+	NameBondBondCoeffs Name = "BondBond Coeffs"
+	// NameBondAngleCoeffs is the Name related to the BondAngle Coeffs table.
+	NameBondAngleCoeffs Name = "BondAngle Coeffs"
+	// NameMiddleBondTorsionCoeffs is the Name related to the
+	// MiddleBondTorsion Coeffs table.
+	NameMiddleBondTorsionCoeffs Name = "MiddleBondTorsion Coeffs"
+	// NameEndBondTorsionCoeffs is the Name related to the EndBondTorsion
+	// Coeffs table.
+	NameEndBondTorsionCoeffs Name = "EndBondTorsion Coeffs"
+	// NameAngleTorsionCoeffs is the Name related to the AngleTorsion Coeffs
+	// table.
+	NameAngleTorsionCoeffs Name = "AngleTorsion Coeffs"
+	// NameAngleAngleTorsionCoeffs is the Name related to the
+	// AngleAngleTorsion Coeffs table.
+	NameAngleAngleTorsionCoeffs Name = "AngleAngleTorsion Coeffs"
+	// NameBondBond13Coeffs is the Name related to the BondBond13 Coeffs
+	// table.
+	NameBondBond13Coeffs Name = "BondBond13 Coeffs"
+	// NameAngleAngleCoeffs is the Name related to the AngleAngle Coeffs
+	// table.
+	NameAngleAngleCoeffs Name = "AngleAngle Coeffs"
+
 	// NameAtoms is the Name related to the Atoms table. In order: atom number,
 	// molecule number, atom type, charge, x, y, z, nx, ny, and nz. The
 	// parameters nx, ny, and nz are optional.
@@ -119,6 +165,21 @@ const (
 	// atom 2, fifth: atom 3, sixth: atom 4.
 	NameDihedrals Name = "Dihedrals"
 
+	// NameLines is the Name related to the Lines table (atom_style line). 1st
+	// column: atom number, other columns: x1, y1, x2, y2.
+	NameLines Name = "Lines"
+	// NameTriangles is the Name related to the Triangles table (atom_style
+	// tri). 1st column: atom number, other columns: x1, y1, z1, x2, y2, z2, x3,
+	// y3, z3.
+	NameTriangles Name = "Triangles"
+
+	// NameImpropers is the Name related to the Impropers table and
+	// NameImproperCoeffs to the Improper Coeffs table. These tables are not
+	// supported yet: they are not in ListNames and are skipped by the
+	// Decoder (see ListUnsupportedSections).
+	NameImpropers      Name = "Impropers"
+	NameImproperCoeffs Name = "Improper Coeffs"
+
 	// NameTitle is the Name related to the title of the LAMMPS data file. It is
 	// located at the first line of the file.
 	NameTitle Name = "Title"
@@ -126,13 +187,19 @@ const (
 
 // ListNames is a list containing all the Names.
 var ListNames []Name = []Name{
+	NameAngleAngleCoeffs,
+	NameAngleAngleTorsionCoeffs,
 	NameAngleCoeffs,
+	NameAngleTorsionCoeffs,
 	NameAngleTypes,
 	NameAngles,
 	NameAnglesNbr,
 	NameAtomTypes,
 	NameAtoms,
 	NameAtomsNbr,
+	NameBondAngleCoeffs,
+	NameBondBond13Coeffs,
+	NameBondBondCoeffs,
 	NameBondCoeffs,
 	NameBondTypes,
 	NameBonds,
@@ -144,14 +211,73 @@ var ListNames []Name = []Name{
 	NameDihedralTypes,
 	NameDihedrals,
 	NameDihedralsNbr,
+	NameEndBondTorsionCoeffs,
+	NameImproperTypes,
+	NameImpropersNbr,
+	NameLines,
+	NameLinesNbr,
 	NameMasses,
+	NameMiddleBondTorsionCoeffs,
 	NamePairCoeffs,
+	NamePairIJCoeffs,
 	NameTitle,
+	NameTriangles,
+	NameTrianglesNbr,
+}
+
+// ListSections is a list containing the Names of the tables in the order they
+// are written by the Encoder. A table begins with a line containing its Name
+// followed by a blank line and the values.
+var ListSections []Name = []Name{
+	NameMasses,
+	NamePairCoeffs,
+	NamePairIJCoeffs,
+	NameBondCoeffs,
+	NameAngleCoeffs,
+	NameBondBondCoeffs,
+	NameBondAngleCoeffs,
+	NameDihedralCoeffs,
+	NameMiddleBondTorsionCoeffs,
+	NameEndBondTorsionCoeffs,
+	NameAngleTorsionCoeffs,
+	NameAngleAngleTorsionCoeffs,
+	NameBondBond13Coeffs,
+	NameAngleAngleCoeffs,
+	NameAtoms,
+	NameLines,
+	NameTriangles,
+	NameBonds,
+	NameAngles,
+	NameDihedrals,
+}
+
+// ListUnsupportedSections is a list containing the Names of the tables that
+// are documented by LAMMPS but not supported by this package. Their values must
+// be skipped so that they are not mistaken for the values of another table.
+var ListUnsupportedSections []Name = []Name{
+	NameImpropers,
+	NameImproperCoeffs,
 }
 
 // ErrUnsupported is an error return if a feature is unsupported by a Key.
 var ErrUnsupported error = errors.New("unsupported")
 
+// ErrTruncated is an error returned by the Decode methods if the input ends
+// before the number of values expected by a table is read.
+var ErrTruncated error = errors.New("truncated")
+
+// ErrStyleMismatch is an error returned by the Decode method of Atoms if
+// Options.StyleCheck is true and the values of the atoms suggest that the atom
+// style does not match the file (e.g. an atomic file decoded with the full atom
+// style).
+var ErrStyleMismatch error = errors.New("atom style mismatch")
+
+// truncated returns an error wrapping ErrTruncated. It indicates the number of
+// values read before the end of the input and the number of expected values.
+func truncated(name Name, read, want int) error {
+	return fmt.Errorf("%s table %w: read %d of %d", name, ErrTruncated, read, want)
+}
+
 // delComments deletes everything that is after "#".
 func delComments(s []byte) []byte {
 	if idx := bytes.IndexRune(s, '#'); idx != -1 {
@@ -160,6 +286,59 @@ func delComments(s []byte) []byte {
 	return s
 }
 
+// scanValue advances r to the next value (= 1 line) of a table. The blank lines
+// are skipped: they are not counted as values. It returns false when the scan
+// stops (see bufio.Scanner.Scan).
+func scanValue(r *bufio.Scanner) bool {
+	for r.Scan() {
+		if len(bytes.TrimSpace(r.Bytes())) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// splitComment returns s without the comment and the comment without the "#"
+// and the surrounding spaces.
+func splitComment(s []byte) ([]byte, string) {
+	idx := bytes.IndexRune(s, '#')
+	if idx == -1 {
+		return s, ""
+	}
+	return s[:idx], string(bytes.TrimSpace(s[idx+1:]))
+}
+
+// headerComment returns the first word of the comment of the header of a table
+// (e.g. "full" for "Atoms # full"). The header and the comment can be separated
+// by any whitespace, including tabs. It returns an empty string if there is no
+// comment.
+func headerComment(s []byte) string {
+	idx := bytes.IndexRune(s, '#')
+	if idx == -1 {
+		return ""
+	}
+	f := bytes.Fields(s[idx+1:])
+	if len(f) == 0 {
+		return ""
+	}
+	return string(f[0])
+}
+
+// parseID converts s into an identifier (e.g. atom or bond identifier). The
+// identifiers are parsed as 64-bit integers and stored as int: if int is a
+// 32-bit integer on the platform, an error is returned for the identifiers
+// greater than 2^31-1.
+func parseID(s string) (int, error) {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if int64(int(v)) != v {
+		return 0, fmt.Errorf("identifier = %d overflows int: a 64-bit platform is required", v)
+	}
+	return int(v), nil
+}
+
 // sortIntsMap returns the keys sorted in increasing order. If the keys are not
 // int or m is not a map, this method will panic.
 func sortIntsMap(m interface{}) (keys []int) {
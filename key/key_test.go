@@ -0,0 +1,137 @@
+package key
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// header returns a Header whose Name is name and whose value is v.
+func header(name Name, v int) *Header {
+	h := NewHeader(name)
+	h.Set(v)
+	return h
+}
+
+// decode decodes the table in whose first line is its header with k. It fails
+// if the Keyword method of k does not match the header.
+func decode(t testing.TB, k Key, in string) error {
+	t.Helper()
+	r := bufio.NewScanner(strings.NewReader(in))
+	if !r.Scan() {
+		t.Fatal("empty input")
+	}
+	if !k.Keyword(r.Bytes()) {
+		t.Fatalf("Keyword(%q) = false for Key = %s", r.Bytes(), k.Name())
+	}
+	return k.Decode(r.Bytes(), r)
+}
+
+// encode returns the table written by the Encode method of k.
+func encode(t testing.TB, k Key) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := k.Encode(&b); err != nil {
+		t.Fatalf("Encode for Key = %s: %v", k.Name(), err)
+	}
+	return b.String()
+}
+
+func TestKeywordBlank(t *testing.T) {
+	keys := []Key{NewHeader(NameAtomsNbr), NewHeader(NameAtomTypes), NewBox(NameBoxX), NewAtoms(nil), new(Masses)}
+	for _, k := range keys {
+		for _, s := range []string{"", " ", "\t \t"} {
+			if k.Keyword([]byte(s)) {
+				t.Errorf("Keyword(%q) = true for Key = %s", s, k.Name())
+			}
+		}
+		if k.Keyword(nil) {
+			t.Errorf("Keyword(nil) = true for Key = %s", k.Name())
+		}
+	}
+}
+
+func TestHeaderComment(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Atoms # full", "full"},
+		{"Atoms\t# full", "full"},
+		{"Atoms\t#\tatomic", "atomic"},
+		{"Pair Coeffs  \t #lj/cut/coul/long", "lj/cut/coul/long"},
+		{"Masses # O H", "O"},
+		{"Atoms", ""},
+		{"Atoms #", ""},
+		{"Atoms\t#\t", ""},
+	}
+	for _, tt := range tests {
+		if got := headerComment([]byte(tt.in)); got != tt.want {
+			t.Errorf("headerComment(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	// the Keys match their header followed by a tab.
+	a := newAtoms(nil, 1, &Options{DetectAtomStyle: true})
+	if err := decode(t, a, "Atoms\t# atomic\n\n1 1 0.5 1.5 2.5\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.AtomStyle(); got != AtomStyleAtomic {
+		t.Errorf("AtomStyle = %s, want atomic", got.Name())
+	}
+	c := NewCoeffsHybrid(NamePairCoeffs)
+	c.SetKeys(header(NameAtomTypes, 1))
+	if err := decode(t, c, "Pair Coeffs\t# hybrid\n\n1 lj/cut 0.1 3.4\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Styles()[1]; got != "lj/cut" {
+		t.Errorf("Styles = %v, want lj/cut for type = 1", c.Styles())
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	atoms := NewAtoms(AtomStyleFull)
+	links := NewLinks(NameBonds, 2)
+	bonus := NewBonus(NameLines, 4)
+	masses := new(Masses)
+	coeffs := NewCoeffs(NameBondCoeffs)
+	title := new(Title)
+	hdr := NewHeader(NameAtomsNbr)
+	box := NewBox(NameBoxX)
+	tests := []struct {
+		name  string
+		k     Key
+		val   interface{}
+		typed func() interface{}
+	}{
+		{"Header", hdr, 3, func() interface{} { return hdr.Value() }},
+		{"Box", box, [2]float64{-1, 2}, func() interface{} { return box.Bounds() }},
+		{"Title", title, "water box", func() interface{} { return title.String() }},
+		{"Atoms", atoms, map[int]*Atom{1: {AtomType: 1}}, func() interface{} { return atoms.Map() }},
+		{"Links", links, map[int]*Link{1: NewLink(1, 1, 2)}, func() interface{} { return links.Map() }},
+		{"Bonus", bonus, map[int][]float64{1: {0, 0, 1, 0}}, func() interface{} { return bonus.Values() }},
+		{"Masses", masses, map[int]float64{1: 12.011}, func() interface{} { return masses.Values() }},
+		{"Coeffs", coeffs, map[int][]float64{1: {450, 1}}, func() interface{} { return coeffs.Values() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.k.Set(tt.val); err != nil {
+				t.Fatal(err)
+			}
+			got := tt.typed()
+			if !reflect.DeepEqual(got, tt.val) || !reflect.DeepEqual(got, tt.k.Get()) {
+				t.Errorf("typed getter = %v, want %v and Get = %v", got, tt.val, tt.k.Get())
+			}
+		})
+	}
+
+	// the atoms stored as AtomF32 are returned by MapF32 only.
+	a := NewAtoms(AtomStyleFull)
+	if err := a.Set(map[int]*AtomF32{1: {AtomType: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if a.Map() != nil || len(a.MapF32()) != 1 {
+		t.Errorf("Map = %v and MapF32 = %v, want only MapF32", a.Map(), a.MapF32())
+	}
+}
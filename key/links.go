@@ -23,14 +23,69 @@ type Links struct {
 	nbr      *Header
 	types    *Header
 	atomsNbr *Header
+	atoms    *Atoms
+	opts     *Options
 	v        map[int]*Link
 }
 
 // Link contains the type (e.g. bond type number 1) and the links (e.g. atom1
 // linked to atom2). The identifier is not included in it.
 type Link struct {
-	typ   int
-	links []int
+	typ     int
+	links   []int
+	extra   []string
+	comment string
+}
+
+// NewLink returns an instance of Link with a type and the identifiers of the
+// linked atoms.
+func NewLink(typ int, atoms ...int) *Link {
+	return &Link{typ: typ, links: atoms}
+}
+
+// Type returns the type of the Link.
+func (l *Link) Type() int {
+	return l.typ
+}
+
+// SetType sets the type of the Link.
+func (l *Link) SetType(typ int) {
+	l.typ = typ
+}
+
+// Atoms returns the identifiers of the linked atoms.
+func (l *Link) Atoms() []int {
+	return l.links
+}
+
+// Extra returns the additional columns that follow the links. They are only
+// decoded if Options.ExtraColumns is true.
+func (l *Link) Extra() []string {
+	return l.extra
+}
+
+// Comment returns the comment that follows the value (= 1 line) without the
+// leading "#".
+func (l *Link) Comment() string {
+	return l.comment
+}
+
+// SetComment sets the comment written after the value (= 1 line). An empty
+// comment removes the previous one.
+func (l *Link) SetComment(c string) {
+	l.comment = c
+}
+
+// Clone returns a copy of the Link, including its additional columns and its
+// comment. The copy does not share the identifiers of the linked atoms with the
+// Link: they can be modified through the slice returned by its Atoms method.
+func (l *Link) Clone() *Link {
+	return &Link{
+		typ:     l.typ,
+		links:   append([]int(nil), l.links...),
+		extra:   append([]string(nil), l.extra...),
+		comment: l.comment,
+	}
 }
 
 // NewLinks returns an instance of Links. If links is equal to 2, then the
@@ -46,20 +101,27 @@ func (l *Links) Name() Name {
 }
 
 // Keyword tests whether the byte slice s begins with Name after trimming the
-// spaces. Keyword is useful to detect the header of the Coeffs table.
+// spaces. Keyword is useful to detect the header of the Links table. The case
+// is ignored if Options.CaseInsensitive is true.
 func (l *Links) Keyword(s []byte) bool {
-	return keyword(s, []byte(l.Name()))
+	return l.opts.keyword(s, l.Name())
 }
 
-// SetKeys assigns one or more Keys to Links. This method only accepts *Header.
-// One key must have a Name equal to NameAtomsNbr, another must have a suffix
-// equal to "types" (e.g. bond types). Other Headers are considered as the
-// number of values (e.g. BondsNbr).
+// SetKeys assigns one or more Keys to Links. This method only accepts *Header
+// and *Atoms. One key must have a Name equal to NameAtomsNbr, another must have
+// a suffix equal to "types" (e.g. bond types). Other Headers are considered as
+// the number of values (e.g. BondsNbr). The Atoms are optional: they are used
+// by the Check method to verify that the linked atoms exist. A nil *Atoms
+// removes the previous ones.
 func (l *Links) SetKeys(k ...Key) error {
 	for _, key := range k {
+		if atoms, ok := key.(*Atoms); ok {
+			l.atoms = atoms
+			continue
+		}
 		header, ok := key.(*Header)
 		if !ok {
-			return fmt.Errorf("type assertion error: key provided is not *Header")
+			return fmt.Errorf("type assertion error: key provided is not *Header or *Atoms")
 		}
 		if header.Name() == NameAtomsNbr {
 			l.atomsNbr = header
@@ -72,8 +134,15 @@ func (l *Links) SetKeys(k ...Key) error {
 	return nil
 }
 
+// SetOptions assigns the Options used by the Keyword, Decode, and Check
+// methods. o can be nil.
+func (l *Links) SetOptions(o *Options) {
+	l.opts = o
+}
+
 // Encode writes a table containing the header, a blank line and each value (= 1
-// line) into a writer.
+// line) into a writer. The additional columns of each Link are written after
+// the links, followed by the comment of the Link if any.
 //
 // This method does not check the integrity and correctness of each value. To do
 // so, use the Check method.
@@ -89,6 +158,9 @@ func (l *Links) Encode(w io.Writer) error {
 	fmt.Fprint(w, l.Name(), "\n\n")
 	for _, k := range keys {
 		link := l.v[k]
+		if link == nil {
+			return fmt.Errorf("link = %d is nil", k)
+		}
 		if _, err := fmt.Fprintf(w, "%d %d", k, link.typ); err != nil {
 			return fmt.Errorf("fmt.Fprintf: %w", err)
 		}
@@ -97,6 +169,16 @@ func (l *Links) Encode(w io.Writer) error {
 				return fmt.Errorf("fmt.Fprintf link: %w", err)
 			}
 		}
+		for _, v := range link.extra {
+			if _, err := fmt.Fprintf(w, " %s", v); err != nil {
+				return fmt.Errorf("fmt.Fprintf extra: %w", err)
+			}
+		}
+		if link.comment != "" {
+			if _, err := fmt.Fprintf(w, " # %s", link.comment); err != nil {
+				return fmt.Errorf("fmt.Fprintf comment: %w", err)
+			}
+		}
 		if _, err := fmt.Fprint(w, "\n"); err != nil {
 			return fmt.Errorf("fmt.Fprintf newline: %w", err)
 		}
@@ -113,15 +195,24 @@ func (l *Links) Encode(w io.Writer) error {
 // that represent the number of values (e.g. NameBondsNbr). Use the Set method
 // to assign this Key.
 //
+// Each value must have at least the number of columns given by NewLinks. The
+// additional columns are ignored unless Options.ExtraColumns is true. The
+// comment that follows a value is kept in its Link.
+//
+// The blank lines between the values are skipped and are not counted.
+//
 // Moreover, this method does not check the integrity and corectness of the
 // values decoded. To do so, use the Check method.
 //
-// Decode method does not return io.EOF error. The use of the Check method after
-// Decode is therefore highly recommended.
+// Decode method does not return io.EOF error. If the input ends before the
+// number of expected values is read, an error wrapping ErrTruncated is returned.
 func (l *Links) Decode(s []byte, r *bufio.Scanner) error {
 	if l.nbr == nil {
 		return fmt.Errorf("Key that is an instance of *Header that represent the number of values is nil: use the Set method")
 	}
+	if l.links < 3 {
+		return fmt.Errorf("number of links = %d is invalid: it must be greater than zero", l.links-2)
+	}
 
 	types := l.nbr.Get().(int)
 	l.v = make(map[int]*Link)
@@ -130,38 +221,55 @@ func (l *Links) Decode(s []byte, r *bufio.Scanner) error {
 		if r.Err() != nil {
 			return fmt.Errorf("r.Scan first line: %w", r.Err())
 		}
+		if types > 0 {
+			return truncated(l.Name(), 0, types)
+		}
 		return nil
 	}
 
-	for i := 0; i < types && r.Scan(); i++ {
-		f := strings.Fields(r.Text())
+	i, width := 0, 0
+	for ; i < types && scanValue(r); i++ {
+		s, comment := splitComment(r.Bytes())
+		f := l.opts.split(string(s))
+		if i == 0 {
+			width = len(f)
+		} else if err := l.opts.width(l.Name(), i+1, len(f), width); err != nil {
+			return err
+		}
 		if len(f) < l.links {
-			return fmt.Errorf("not enough fields = %d, want >= %d", len(f), l.links)
+			return fmt.Errorf("row = %d has not enough fields = %d, want >= %d (1 identifier, 1 type, and %d atoms): the number of links does not match the width of the data", i+1, len(f), l.links, l.links-2)
 		}
 
-		id, err := strconv.Atoi(f[0])
+		id, err := parseID(l.opts.integer(f[0]))
 		if err != nil {
-			return fmt.Errorf("strconv.Atoi id: %w", err)
+			return setLine(parseError("strconv.ParseInt", "id", err), l.Name(), i+1)
 		}
 
-		typ, err := strconv.Atoi(f[1])
+		typ, err := strconv.Atoi(l.opts.integer(f[1]))
 		if err != nil {
-			return fmt.Errorf("strconv.Atoi type: %w", err)
+			return setLine(parseError("strconv.Atoi", "type", err), l.Name(), i+1)
 		}
 
 		var links []int
 		for _, v := range f[2:l.links] {
-			atom, err := strconv.Atoi(v)
+			atom, err := parseID(l.opts.integer(v))
 			if err != nil {
-				return fmt.Errorf("strconv.Atoi link: %w", err)
+				return setLine(parseError("strconv.ParseInt", "link", err), l.Name(), i+1)
 			}
 			links = append(links, atom)
 		}
-		l.v[id] = &Link{typ: typ, links: links}
+		link := &Link{typ: typ, links: links, comment: comment}
+		if l.opts != nil && l.opts.ExtraColumns && len(f) > l.links {
+			link.extra = f[l.links:]
+		}
+		l.v[id] = link
 	}
 	if r.Err() != nil {
 		return fmt.Errorf("r.Scan: %w", r.Err())
 	}
+	if i < types {
+		return truncated(l.Name(), i, types)
+	}
 	return nil
 }
 
@@ -185,12 +293,81 @@ func (l *Links) Get() interface{} {
 	return l.v
 }
 
+// Map returns the map[int]*Link where the keys are the identifiers of the
+// links. Unlike Get, no type assertion is required. The map is not copied:
+// modifying it modifies the values of Links.
+func (l *Links) Map() map[int]*Link {
+	return l.v
+}
+
+// LinkComments returns a map where the keys are the identifiers of the links
+// having a comment and the values are the comments.
+func (l *Links) LinkComments() map[int]string {
+	c := make(map[int]string)
+	for id, link := range l.v {
+		if link != nil && link.comment != "" {
+			c[id] = link.comment
+		}
+	}
+	return c
+}
+
+// SetLinkComments sets the comments of the links whose identifiers are the keys
+// of c. The comments of the other links are left untouched. It returns an error
+// if a link does not exist.
+func (l *Links) SetLinkComments(c map[int]string) error {
+	for id := range c {
+		if l.v[id] == nil {
+			return fmt.Errorf("link = %d does not exist", id)
+		}
+	}
+	for id, comment := range c {
+		l.v[id].comment = comment
+	}
+	return nil
+}
+
+// MaxType returns the largest type used by the links. It returns zero if there
+// is no link.
+func (l *Links) MaxType() int {
+	var max int
+	for _, link := range l.v {
+		if link != nil && link.typ > max {
+			max = link.typ
+		}
+	}
+	return max
+}
+
+// UsedTypes returns the distinct types used by the links sorted in increasing
+// order. The declared types that are not used are not returned.
+func (l *Links) UsedTypes() []int {
+	used := make(map[int]bool)
+	for _, link := range l.v {
+		if link != nil {
+			used[link.typ] = true
+		}
+	}
+	return sortIntsMap(used)
+}
+
+// Len returns the number of values (e.g. bonds).
+func (l *Links) Len() int {
+	return len(l.v)
+}
+
 // Check verifies the integrity and correctness of the data decoded with the
 // Decode method or set with the Set method.
 //
 // This method needs three Keys in order to work. The first Key is the number of
 // types, the second is the number of atoms, and the third is the number of
 // values (identifiers).
+//
+// As in LAMMPS, the identifiers must be greater than zero but are not required
+// to be contiguous, and the linked atoms must exist in the Atoms assigned with
+// SetKeys (any atom greater than zero is accepted without Atoms). If
+// Options.ContiguousIDs is true, the identifiers and the linked atoms must be
+// between one and the number of values and of atoms respectively.
 func (l *Links) Check() error {
 	if l.types == nil || l.atomsNbr == nil || l.nbr == nil {
 		return fmt.Errorf("one or more Keys are nil: use the Set method")
@@ -201,19 +378,31 @@ func (l *Links) Check() error {
 	atomsNbr := l.atomsNbr.Get().(int)
 
 	if len(l.v) != nbr {
-		return fmt.Errorf("number of assigned values (ids) = %d is not equal to the number of expected values = %d", len(l.v), nbr)
+		return countMismatch(l.Name(), len(l.v), nbr, "number of assigned values (ids) = %d is not equal to the number of expected values = %d")
 	}
 
+	contiguous := l.opts != nil && l.opts.ContiguousIDs
 	for id, link := range l.v {
-		if id < 1 || id > nbr {
+		if link == nil {
+			return fmt.Errorf("link = %d is nil", id)
+		}
+		if id < 1 {
+			return fmt.Errorf("id = %d is invalid: it must be greater than zero", id)
+		}
+		if contiguous && id > nbr {
 			return fmt.Errorf("id = %d is invalid: it must be greater than zero and lower or equal than the number of id = %d", id, nbr)
 		}
 		if link.typ < 1 || link.typ > types {
-			return fmt.Errorf("type = %d is invalid: it must be greater than zero and lower or equal than the number of types = %d", id, nbr)
+			return fmt.Errorf("type = %d of id = %d is invalid: it must be greater than zero and lower or equal than the number of types = %d", link.typ, id, types)
 		}
 		for _, atom := range link.links {
-			if atom < 1 || atom > atomsNbr {
-				return fmt.Errorf("atom = %d is invalid: it must be greater than zero and lower or equal than the number of atoms = %d", id, nbr)
+			switch {
+			case atom < 1:
+				return fmt.Errorf("atom = %d of id = %d is invalid: it must be greater than zero", atom, id)
+			case contiguous && atom > atomsNbr:
+				return fmt.Errorf("atom = %d of id = %d is invalid: it must be greater than zero and lower or equal than the number of atoms = %d", atom, id, atomsNbr)
+			case !contiguous && l.atoms != nil && !l.atoms.has(atom):
+				return fmt.Errorf("atom = %d of id = %d does not exist", atom, id)
 			}
 		}
 	}
@@ -0,0 +1,215 @@
+package key
+
+import (
+	"strings"
+	"testing"
+)
+
+// newLinks returns a Links with 2 atoms, 2 types, and nbr values for each link
+// of n atoms (e.g. 2 for Bonds).
+func newLinks(name Name, n, nbr int, opts *Options) *Links {
+	l := NewLinks(name, n)
+	l.SetKeys(header(NameAtomsNbr, 4), header(NameBondTypes, 2), header(NameBondsNbr, nbr))
+	l.SetOptions(opts)
+	return l
+}
+
+func TestLinksDecodeFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		links   int
+		rows    string
+		wantErr string
+	}{
+		{"bond", 2, "1 1 1 2", ""},
+		{"bond with extra columns", 2, "1 1 1 2 0.5", ""},
+		{"bond missing an atom", 2, "1 1 1", "not enough fields = 3, want >= 4"},
+		{"bond without atom", 2, "1 1", "not enough fields = 2, want >= 4"},
+		{"identifier only", 2, "1", "not enough fields = 1, want >= 4"},
+		{"comment only", 2, "# 1 1 1 2", "not enough fields = 0, want >= 4"},
+		{"angle", 3, "1 1 1 2 3", ""},
+		{"angle missing an atom", 3, "1 1 1 2", "not enough fields = 4, want >= 5"},
+		{"dihedral missing an atom", 4, "1 1 1 2 3", "not enough fields = 5, want >= 6"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLinks(NameBonds, tt.links, 1, nil)
+			err := decode(t, l, "Bonds\n\n"+tt.rows+"\n")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Decode = %v", err)
+				}
+				if got := len(l.Map()[1].Atoms()); got != tt.links {
+					t.Errorf("len(Atoms) = %d, want %d", got, tt.links)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if err := decode(t, newLinks(NameBonds, 0, 1, nil), "Bonds\n\n1 1\n"); err == nil {
+		t.Error("Decode = nil for a Links of zero atom")
+	}
+}
+
+func TestLinksExtraColumns(t *testing.T) {
+	const in = "Angles\n\n1 1 1 2 3 0.5 x\n2 2 2 3 4\n"
+	tests := []struct {
+		name  string
+		opts  *Options
+		extra []string
+		out   string
+	}{
+		{"dropped", nil, nil, "Angles\n\n1 1 1 2 3\n2 2 2 3 4\n"},
+		{"kept", &Options{ExtraColumns: true}, []string{"0.5", "x"}, "Angles\n\n1 1 1 2 3 0.5 x\n2 2 2 3 4\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLinks(NameAngles, 3, 2, tt.opts)
+			if err := decode(t, l, in); err != nil {
+				t.Fatal(err)
+			}
+			if got := l.Map()[1].Extra(); strings.Join(got, " ") != strings.Join(tt.extra, " ") {
+				t.Errorf("Extra = %q, want %q", got, tt.extra)
+			}
+			if got := l.Map()[2].Extra(); len(got) != 0 {
+				t.Errorf("Extra of the second angle = %q, want none", got)
+			}
+			if got := encode(t, l); got != tt.out {
+				t.Errorf("Encode = %q, want %q", got, tt.out)
+			}
+		})
+	}
+}
+
+func TestLinksCheckIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       string
+		contiguous bool
+		wantErr    string
+	}{
+		{"contiguous", "1 1 1 2\n2 1 3 4\n", false, ""},
+		{"sparse", "10 1 1 2\n7 1 3 4\n", false, ""},
+		{"sparse with ContiguousIDs", "10 1 1 2\n1 1 3 4\n", true, "id = 10 is invalid"},
+		{"contiguous with ContiguousIDs", "2 1 1 2\n1 1 3 4\n", true, ""},
+		{"zero", "0 1 1 2\n1 1 3 4\n", false, "id = 0 is invalid"},
+		{"duplicate", "3 1 1 2\n3 1 3 4\n", false, "number of assigned values (ids) = 1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLinks(NameBonds, 2, 2, &Options{ContiguousIDs: tt.contiguous})
+			if err := decode(t, l, "Bonds\n\n"+tt.rows); err != nil {
+				t.Fatal(err)
+			}
+			err := l.Check()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLinksCheckAtoms(t *testing.T) {
+	// the atoms 1, 2, 3, and 60: the number of atoms is 4.
+	a := newAtoms(AtomStyleAtomic, 4, nil)
+	if err := decode(t, a, "Atoms\n\n1 1 0 0 0\n2 1 0 0 0\n3 1 0 0 0\n60 1 0 0 0\n"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		rows       string
+		atoms      *Atoms
+		contiguous bool
+		wantErr    string
+	}{
+		{"sparse atom", "1 1 1 60\n", a, false, ""},
+		{"missing atom", "1 1 1 4\n", a, false, "atom = 4 of id = 1 does not exist"},
+		{"zero atom", "1 1 0 1\n", a, false, "atom = 0 of id = 1 is invalid"},
+		{"sparse atom with ContiguousIDs", "1 1 1 60\n", a, true, "atom = 60 of id = 1 is invalid"},
+		{"missing atom with ContiguousIDs", "1 1 1 4\n", a, true, ""},
+		{"without atoms", "1 1 1 60\n", nil, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLinks(NameBonds, 2, 1, &Options{ContiguousIDs: tt.contiguous})
+			if tt.atoms != nil {
+				l.SetKeys(tt.atoms)
+			}
+			if err := decode(t, l, "Bonds\n\n"+tt.rows); err != nil {
+				t.Fatal(err)
+			}
+			err := l.Check()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLinksComments(t *testing.T) {
+	const in = "Bonds\n\n1 1 1 2 # O-H\n2 1 1 3\n"
+	l := newLinks(NameBonds, 2, 2, nil)
+	if err := decode(t, l, in); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.LinkComments(); len(got) != 1 || got[1] != "O-H" {
+		t.Errorf("LinkComments = %v, want map[1:O-H]", got)
+	}
+	if got := encode(t, l); got != in {
+		t.Errorf("Encode = %q, want %q", got, in)
+	}
+
+	if err := l.SetLinkComments(map[int]string{2: "O-H'"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := encode(t, l), "Bonds\n\n1 1 1 2 # O-H\n2 1 1 3 # O-H'\n"; got != want {
+		t.Errorf("Encode = %q, want %q", got, want)
+	}
+	if err := l.SetLinkComments(map[int]string{3: "x"}); err == nil {
+		t.Error("SetLinkComments = nil for a link that does not exist")
+	}
+}
+
+func TestLinksUsedTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		rows string
+		want []int
+	}{
+		{"unused type", "1 2 1 2\n2 2 3 4\n", []int{2}},
+		{"all types", "2 2 1 2\n1 1 3 4\n", []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLinks(NameBonds, 2, 2, nil)
+			if err := decode(t, l, "Bonds\n\n"+tt.rows); err != nil {
+				t.Fatal(err)
+			}
+			got := l.UsedTypes()
+			if len(got) != len(tt.want) {
+				t.Fatalf("UsedTypes = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("UsedTypes = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"strconv"
-	"strings"
 )
 
 // Masses is used to encode and/or decode a table containing the masses for each
@@ -16,8 +16,10 @@ import (
 //
 // Masses can be instanced by using the built-in new function.
 type Masses struct {
-	types *Header
-	v     map[int]float64
+	types    *Header
+	opts     *Options
+	v        map[int]float64
+	comments map[int]string
 }
 
 // Name returns NameMasses. It corresponds to the header of the table.
@@ -26,9 +28,10 @@ func (m *Masses) Name() Name {
 }
 
 // Keyword tests whether the byte slice s begins with Name after trimming the
-// spaces. Keyword is useful to detect the header of the Masses table.
+// spaces. Keyword is useful to detect the header of the Masses table. The case
+// is ignored if Options.CaseInsensitive is true.
 func (m *Masses) Keyword(s []byte) bool {
-	return keyword(s, []byte(m.Name()))
+	return m.opts.keyword(s, m.Name())
 }
 
 // SetKeys assigns one or more Keys to Atoms. This method only accepts *Header
@@ -48,6 +51,12 @@ func (m *Masses) SetKeys(k ...Key) error {
 	return nil
 }
 
+// SetOptions assigns the Options used by the Keyword and Decode methods. o can
+// be nil.
+func (m *Masses) SetOptions(o *Options) {
+	m.opts = o
+}
+
 // SetKeysVal assigns to the NameAtomTypes Key the number of types based on the
 // length of the map that is created via the Set or Decode methods.
 //
@@ -61,7 +70,8 @@ func (m *Masses) SetKeysVal() error {
 }
 
 // Encode writes a table containing the header, a blank line and each value (= 1
-// line) (mass) into a writer.
+// line) (mass) into a writer. The comment of each mass is written after it if
+// any.
 //
 // This method does not check the integrity and correctness of each value. To do
 // so, use the Check method.
@@ -76,7 +86,12 @@ func (m *Masses) Encode(w io.Writer) error {
 	fmt.Fprint(w, m.Name(), "\n\n")
 	for _, k := range keys {
 		v := m.v[k]
-		_, err := fmt.Fprintf(w, "%d %g\n", k, v)
+		var err error
+		if c := m.comments[k]; c != "" {
+			_, err = fmt.Fprintf(w, "%d %s # %s\n", k, m.opts.float(v), c)
+		} else {
+			_, err = fmt.Fprintf(w, "%d %s\n", k, m.opts.float(v))
+		}
 		if err != nil {
 			return fmt.Errorf("fmt.Fprintf: %w", err)
 		}
@@ -92,44 +107,85 @@ func (m *Masses) Encode(w io.Writer) error {
 // This method needs a Key in order to work. This Key is an instance of Header
 // with Name equal to NameAtomTypes. Use the Set method to assign this Key.
 //
+// As the table is present, the Key must be greater than zero: its minimum is
+// set to one (see Header.SetMin).
+//
+// The blank lines between the values are skipped and are not counted.
+//
 // Moreover, this method does not check the integrity and corectness of the
 // values decoded. To do so, use the Check method.
 //
-// Decode method does not return io.EOF error. The use of the Check method after
-// Decode is therefore highly recommended.
+// Decode method does not return io.EOF error. If the input ends before the
+// number of expected masses is read, an error wrapping ErrTruncated is returned.
 func (m *Masses) Decode(s []byte, r *bufio.Scanner) error {
 	if m.types == nil {
 		return fmt.Errorf("Key that is an instance of *Header with Name equal to NameAtomTypes is nil: use the Set method")
 	}
+	m.types.SetMin(1) // the table is present: there is at least one type
 
 	m.v = make(map[int]float64)
+	types := m.types.Get().(int)
 
 	if ok := r.Scan(); !ok {
 		if r.Err() != nil {
 			return fmt.Errorf("r.Scan first line: %w", r.Err())
 		}
+		if types > 0 {
+			return truncated(m.Name(), 0, types)
+		}
 		return nil
 	}
+	return m.decodeValues(r, types)
+}
 
-	types := m.types.Get().(int)
-	for i := 0; i < types && r.Scan(); i++ {
-		f := strings.Fields(r.Text())
+// DecodeStandalone reads a table of masses that is not part of a LAMMPS data
+// file: the reader only contains the values (= 1 line) without the header and
+// the blank line. types is the number of masses to read. The masses decoded
+// replace the previous ones and can be assigned to another Masses (e.g. the
+// Masses of a decoded file) with the Get and Set methods.
+//
+// This method does not need any Key in order to work and does not check the
+// integrity and correctness of the values decoded. To do so, use the Check
+// method.
+func (m *Masses) DecodeStandalone(r io.Reader, types int) error {
+	m.v = make(map[int]float64)
+	return m.decodeValues(bufio.NewScanner(r), types)
+}
+
+// decodeValues reads types values (= 1 line) and puts them into the map. The
+// fields that follow the mass (e.g. a unit such as g/mol) are ignored, or
+// rejected if Options.StrictMasses is true, and the comments are kept.
+func (m *Masses) decodeValues(r *bufio.Scanner, types int) error {
+	m.comments = make(map[int]string)
+	i := 0
+	for ; i < types && scanValue(r); i++ {
+		s, comment := splitComment(r.Bytes())
+		f := m.opts.fields(string(s))
 		if len(f) < 2 {
 			return fmt.Errorf("not enough fields = %d, expected > 2", len(f))
 		}
+		if m.opts != nil && m.opts.StrictMasses && len(f) > 2 {
+			return fmt.Errorf("row = %d has %d fields, want 2 (type and mass): the extra fields may be misplaced coefficients", i+1, len(f))
+		}
 		atomType, err := strconv.Atoi(f[0])
 		if err != nil {
-			return fmt.Errorf("strconv.Atoi: %w", err)
+			return setLine(parseError("strconv.Atoi", "", err), m.Name(), i+1)
 		}
 		mass, err := strconv.ParseFloat(f[1], 64)
 		if err != nil {
-			return fmt.Errorf("strconv.ParseFloat: %w", err)
+			return setLine(parseError("strconv.ParseFloat", "", err), m.Name(), i+1)
 		}
 		m.v[atomType] = mass
+		if comment != "" {
+			m.comments[atomType] = comment
+		}
 	}
 	if r.Err() != nil {
 		return fmt.Errorf("r.Scan: %w", r.Err())
 	}
+	if i < types {
+		return truncated(m.Name(), i, types)
+	}
 	return nil
 }
 
@@ -153,6 +209,31 @@ func (m *Masses) Get() interface{} {
 	return m.v
 }
 
+// Values returns the map[int]float64 where the keys are the atom types. Unlike
+// Get, no type assertion is required. The map is not copied: modifying it
+// modifies the values of Masses.
+func (m *Masses) Values() map[int]float64 {
+	return m.v
+}
+
+// Comments returns a map where the keys are the atom types whose mass has a
+// comment (e.g. "1 12.011 # C") and the values are the comments without the
+// "#". The map is not copied.
+func (m *Masses) Comments() map[int]string {
+	return m.comments
+}
+
+// SetComments sets the comments written after the masses by the Encode method.
+// The keys of c are the atom types. A nil map removes every comment.
+func (m *Masses) SetComments(c map[int]string) {
+	m.comments = c
+}
+
+// Len returns the number of masses.
+func (m *Masses) Len() int {
+	return len(m.v)
+}
+
 // Check verifies the integrity and correctness of the data decoded with the
 // Decode method or set with the Set method.
 //
@@ -164,9 +245,12 @@ func (m *Masses) Check() error {
 	}
 	types := m.types.Get().(int)
 	if len(m.v) != types {
-		return fmt.Errorf("number of masses (= 1 line = 1 type) = %d is not equal to the number of atom types = %d", len(m.v), types)
+		return countMismatch(m.Name(), len(m.v), types, "number of masses (= 1 line = 1 type) = %d is not equal to the number of atom types = %d")
 	}
 	for typ, mass := range m.v {
+		if math.IsNaN(mass) || math.IsInf(mass, 0) {
+			return fmt.Errorf("mass of type = %d is not finite = %g", typ, mass)
+		}
 		if mass < 0. {
 			return fmt.Errorf("mass of type = %d is lower than zero = %g", typ, mass)
 		}
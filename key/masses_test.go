@@ -0,0 +1,147 @@
+package key
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMassesDecodeStandalone(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		types int
+		want  map[int]float64
+		err   error
+	}{
+		{"masses", "1 15.9994\n2 1.008\n", 2, map[int]float64{1: 15.9994, 2: 1.008}, nil},
+		{"blank lines and comments", "\n1 15.9994 # O\n\n2 1.008\n", 2, map[int]float64{1: 15.9994, 2: 1.008}, nil},
+		{"first types", "1 15.9994\n2 1.008\n", 1, map[int]float64{1: 15.9994}, nil},
+		{"truncated", "1 15.9994\n", 2, nil, ErrTruncated},
+		{"empty", "", 1, nil, ErrTruncated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(Masses)
+			err := m.DecodeStandalone(strings.NewReader(tt.in), tt.types)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Errorf("DecodeStandalone = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(m.Values()) != len(tt.want) {
+				t.Fatalf("Values = %v, want %v", m.Values(), tt.want)
+			}
+			for typ, mass := range tt.want {
+				if m.Values()[typ] != mass {
+					t.Errorf("Values = %v, want %v", m.Values(), tt.want)
+				}
+			}
+		})
+	}
+
+	// the masses decoded can be attached to the Masses of a file.
+	m := new(Masses)
+	if err := m.DecodeStandalone(strings.NewReader("1 12.011\n"), 1); err != nil {
+		t.Fatal(err)
+	}
+	file := new(Masses)
+	file.SetKeys(header(NameAtomTypes, 1))
+	if err := file.Set(m.Get()); err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Check(); err != nil {
+		t.Errorf("Check = %v", err)
+	}
+}
+
+func TestMassesValues(t *testing.T) {
+	m := new(Masses)
+	m.SetKeys(header(NameAtomTypes, 2))
+	if err := decode(t, m, "Masses\n\n1 15.9994\n2 1.008\n"); err != nil {
+		t.Fatal(err)
+	}
+	v := m.Values()
+	if len(v) != 2 || v[1] != 15.9994 || v[2] != 1.008 {
+		t.Errorf("Values = %v", v)
+	}
+	// the map is not copied.
+	v[2] = 2.014
+	if got := encode(t, m); got != "Masses\n\n1 15.9994\n2 2.014\n" {
+		t.Errorf("Encode = %q after modifying Values", got)
+	}
+}
+
+func TestMassesTrailingTokens(t *testing.T) {
+	const in = "Masses\n\n1 12.011 g/mol\n2 1.008 # H\n3 15.9994 g/mol # O\n"
+	tests := []struct {
+		name    string
+		opts    *Options
+		wantErr string
+	}{
+		{"ignored", nil, ""},
+		{"strict", &Options{StrictMasses: true}, "row = 1 has 3 fields, want 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(Masses)
+			m.SetKeys(header(NameAtomTypes, 3))
+			m.SetOptions(tt.opts)
+			err := decode(t, m, in)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := m.Values(); len(v) != 3 || v[1] != 12.011 || v[2] != 1.008 || v[3] != 15.9994 {
+				t.Errorf("Values = %v", v)
+			}
+			if c := m.Comments(); len(c) != 2 || c[2] != "H" || c[3] != "O" {
+				t.Errorf("Comments = %q, want map[2:H 3:O]", c)
+			}
+		})
+	}
+}
+
+func TestMassesStrict(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    string
+		strict  bool
+		wantErr string
+	}{
+		{"3-column row", "1 15.9994\n2 1.008 450\n", false, ""},
+		{"3-column row strict", "1 15.9994\n2 1.008 450\n", true, "row = 2 has 3 fields, want 2"},
+		{"4-column row strict", "1 15.9994 450 1\n2 1.008\n", true, "row = 1 has 4 fields, want 2"},
+		{"comment strict", "1 15.9994 # O\n2 1.008 # H 450\n", true, ""},
+		{"2-column rows strict", "1 15.9994\n2 1.008\n", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(Masses)
+			m.SetKeys(header(NameAtomTypes, 2))
+			m.SetOptions(&Options{StrictMasses: tt.strict})
+			err := decode(t, m, "Masses\n\n"+tt.rows)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v := m.Values(); len(v) != 2 || v[1] != 15.9994 || v[2] != 1.008 {
+				t.Errorf("Values = %v", v)
+			}
+		})
+	}
+}
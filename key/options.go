@@ -0,0 +1,259 @@
+package key
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Options changes the way the Keys decode and encode the data. The zero value
+// of Options follows strictly the LAMMPS data file format.
+//
+// Options can be assigned to the Keys by using the SetOptions function.
+type Options struct {
+	// FortranExponent allows the floats to have a D or d exponent (e.g.
+	// 1.5D+02) as written by some legacy Fortran tools. The exponent is
+	// translated to E before parsing.
+	FortranExponent bool
+
+	// ExtraColumns keeps the additional columns that follow the atoms of
+	// each value of the Links tables (e.g. Angles, Dihedrals). They are
+	// written back by the Encode method. By default, they are dropped.
+	ExtraColumns bool
+
+	// DetectAtomStyle detects the atom style of the Atoms table if it is not
+	// specified (see NewAtoms). The atom style named by the comment of the
+	// header of the table (e.g. "Atoms # atomic") is used. Without such a
+	// comment, the atom style is guessed from the number of columns of the
+	// first atom: 5 or 8 columns for atomic, full otherwise. This heuristic
+	// is ambiguous as other atom styles may have the same number of columns.
+	DetectAtomStyle bool
+
+	// ContiguousIDs requires the identifiers of the Atoms and Links tables
+	// (e.g. Bonds) to be between one and their number of values, and the
+	// atoms of the links to be between one and the number of atoms. By
+	// default, as in LAMMPS, any unique identifier greater than zero is
+	// accepted and the atoms of the links must exist in the Atoms table.
+	ContiguousIDs bool
+
+	// CaseInsensitive matches the headers of the tables regardless of their
+	// case (e.g. "masses" or "MASSES" for the Masses table). By default, as
+	// in LAMMPS, the case must match.
+	CaseInsensitive bool
+
+	// FlatZ accepts a box whose zlo is equal to zhi, as written by some
+	// tools for 2D systems. By default, lo must be lower than hi for every
+	// coordinate.
+	FlatZ bool
+
+	// SplitBox accepts the bounds of the box written on separate lines and
+	// keyed by their token, e.g. "xlo -5" and "xhi 5" instead of "-5 5 xlo
+	// xhi". The token can follow the bound (e.g. "-5 xlo"). The two lines
+	// do not need to be consecutive. By default, only the LAMMPS form is
+	// accepted.
+	SplitBox bool
+
+	// Wildcard accepts the values of the Coeffs tables whose type is "*"
+	// (e.g. "* 0.1 3.4"), as in the LAMMPS input scripts. Such a value sets
+	// the coefficients of every type. By default, the type must be an
+	// integer as in the LAMMPS data files.
+	Wildcard bool
+
+	// StrictMasses rejects the values of the Masses table having fields after
+	// the mass that are not in a comment (e.g. a misplaced coefficient). By
+	// default, these fields are ignored as some tools write a comment
+	// without "#" (e.g. a unit such as g/mol).
+	StrictMasses bool
+
+	// StripTitleComment removes the leading "#" of the title, as written by
+	// some pipelines (e.g. "# LAMMPS data file via ..."). By default, the
+	// title is kept verbatim.
+	StripTitleComment bool
+
+	// MixedImageFlags accepts an Atoms table where only some atoms have the
+	// image flags. The missing image flags are set to 0 0 0 so that every
+	// atom has them. By default, the Check method of Atoms rejects such a
+	// table.
+	MixedImageFlags bool
+
+	// ThousandsSeparator accepts the integers of the Atoms and Links tables
+	// written with commas separating the groups of three digits (e.g.
+	// 1,000), as written by some locale-dependent exporters. The commas are
+	// removed before parsing. In the Atoms table, only the integer columns
+	// of the atom style (the identifier, the molecule tag, the atom type,
+	// the flags, and the image flags) are accepted: a float written as 1,000
+	// is rejected.
+	ThousandsSeparator bool
+
+	// StyleCheck inspects the atoms once the Atoms table is decoded and
+	// rejects the values suggesting that the atom style does not match the
+	// file, such as an atom type greater than the number of atom types or a
+	// charge that looks like a coordinate. The first row that cannot be
+	// decoded with the atom style (e.g. an atomic row decoded with the full
+	// atom style) is also rejected as a mismatch. This heuristic may reject
+	// valid files with unusually large charges.
+	StyleCheck bool
+
+	// Delimiter separates the fields of the values of the tables (e.g. "|"
+	// for pipe-delimited rows). The spaces surrounding each field are
+	// trimmed and the empty fields are dropped, so that "|1|2|" has two
+	// fields. By default, the fields are separated by whitespace. The
+	// headers of the file (e.g. "10 atoms") are not affected. A comma
+	// delimiter cannot be used with ThousandsSeparator.
+	Delimiter string
+
+	// UniformColumns requires every value (= 1 line) of the Atoms, Links, and
+	// Coeffs tables to have the same number of columns as the first value of
+	// the table. A row having a dropped field may still be decoded without
+	// error, e.g. a row of the Links tables with an extra column missing.
+	// The comments are not counted. The hybrid Coeffs tables are not
+	// verified as their sub-styles have different numbers of coefficients,
+	// nor is the Atoms table if MixedImageFlags is true.
+	UniformColumns bool
+
+	// Float32 stores the atoms of the Atoms table as AtomF32 instead of Atom
+	// to reduce the memory used by very large systems. The floats lose their
+	// precision beyond about 7 significant digits.
+	Float32 bool
+
+	// TrailingZero writes the floats whose value is whole with a trailing
+	// ".0" (e.g. 0.0 and 12.0 instead of 0 and 12) in the Atoms, Masses,
+	// Coeffs, Lines, and Triangles tables and in the box. It only affects the
+	// floats written with the default format %g: the columns having a
+	// format in Formats and the box written with BoxFormat are unchanged.
+	TrailingZero bool
+
+	// BoxFormat is the format (see the fmt package) used to write each bound
+	// of the box, e.g. WriteDataBoxFormat to match the output of the LAMMPS
+	// write_data command. By default, the bounds are written with %g.
+	BoxFormat string
+
+	// Formats contains the format (see the fmt package) used to write each
+	// column of the Atoms table, e.g. "%.10f" for ColumnX. The columns that
+	// are not in Formats are written with %d for the integers and %g for the
+	// floats.
+	Formats map[Column]string
+}
+
+// optioner is implemented by the Keys that support Options.
+type optioner interface {
+	SetOptions(*Options)
+}
+
+// SetOptions assigns o to every Key of keys that supports Options. The Keys
+// that do not support Options are left untouched.
+func SetOptions(keys map[Name]Key, o *Options) {
+	for _, k := range keys {
+		if v, ok := k.(optioner); ok {
+			v.SetOptions(o)
+		}
+	}
+}
+
+// fields splits s (see the split method) and normalizes each field according
+// to the Options. o can be nil.
+func (o *Options) fields(s string) []string {
+	f := o.split(s)
+	if o == nil {
+		return f
+	}
+	if o.FortranExponent {
+		for i := range f {
+			f[i] = fortranExponent(f[i])
+		}
+	}
+	return f
+}
+
+// split splits s around Delimiter, or around whitespace if Delimiter is empty.
+// o can be nil.
+func (o *Options) split(s string) []string {
+	if o == nil || o.Delimiter == "" {
+		return strings.Fields(s)
+	}
+	var f []string
+	for _, v := range strings.Split(s, o.Delimiter) {
+		if v = strings.TrimSpace(v); v != "" {
+			f = append(f, v)
+		}
+	}
+	return f
+}
+
+// width returns an error if UniformColumns is true and the value at row of the
+// table name has n columns while the first value of the table has want
+// columns. o can be nil.
+func (o *Options) width(name Name, row, n, want int) error {
+	if o == nil || !o.UniformColumns || n == want {
+		return nil
+	}
+	return fmt.Errorf("%s table: row = %d has %d columns but the first row has %d: a field may be missing", name, row, n, want)
+}
+
+// float formats v as %g does, with a trailing ".0" if TrailingZero is true (see
+// formatFloat). o can be nil.
+func (o *Options) float(v float64) string {
+	return formatFloat(v, o != nil && o.TrailingZero)
+}
+
+// formatFloat formats v as %g does. If zero is true and v is whole (e.g. 12 but
+// not 1e+21 or NaN), ".0" is appended.
+func formatFloat(v float64, zero bool) string {
+	s := strconv.FormatFloat(v, 'g', -1, 64)
+	if zero && !strings.ContainsAny(s, ".eInN") {
+		s += ".0"
+	}
+	return s
+}
+
+// integer removes the commas separating the groups of three digits of s (e.g.
+// 1,000) if ThousandsSeparator is true. s is returned unchanged if it is not an
+// integer written this way. o can be nil.
+func (o *Options) integer(s string) string {
+	if o == nil || !o.ThousandsSeparator || !strings.ContainsRune(s, ',') {
+		return s
+	}
+	digits := strings.TrimLeft(s, "+-")
+	if len(s)-len(digits) > 1 {
+		return s
+	}
+	groups := strings.Split(digits, ",")
+	for i, g := range groups {
+		if (i == 0 && (len(g) < 1 || len(g) > 3)) || (i > 0 && len(g) != 3) {
+			return s
+		}
+		for _, c := range g {
+			if c < '0' || c > '9' {
+				return s
+			}
+		}
+	}
+	return strings.ReplaceAll(s, ",", "")
+}
+
+// keyword works like the keyword function but ignores the case if
+// CaseInsensitive is true. o can be nil.
+func (o *Options) keyword(s []byte, name Name) bool {
+	if o == nil || !o.CaseInsensitive {
+		return keyword(s, []byte(name))
+	}
+	s = bytes.TrimLeftFunc(s, unicode.IsSpace)
+	return len(s) >= len(name) && bytes.EqualFold(s[:len(name)], []byte(name))
+}
+
+// fortranExponent translates the D or d exponent of a float into E. s is
+// returned unchanged if it is not a float.
+func fortranExponent(s string) string {
+	idx := strings.IndexAny(s, "Dd")
+	if idx < 1 {
+		return s
+	}
+	for i, c := range s {
+		if i != idx && !strings.ContainsRune("0123456789+-.", c) {
+			return s
+		}
+	}
+	return s[:idx] + "E" + s[idx+1:]
+}
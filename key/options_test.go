@@ -0,0 +1,240 @@
+package key
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFortranExponent(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"1.5D+02", "1.5E+02"},
+		{"1.5d-02", "1.5E-02"},
+		{"-2D0", "-2E0"},
+		{"1.5E+02", "1.5E+02"},
+		{"150", "150"},
+		{"D2", "D2"},
+		{"1.5D+0x", "1.5D+0x"},
+	}
+	for _, tt := range tests {
+		if got := fortranExponent(tt.in); got != tt.want {
+			t.Errorf("fortranExponent(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsSection(t *testing.T) {
+	tests := []struct {
+		s           string
+		o           *Options
+		section     Name
+		unsupported Name
+	}{
+		{"Masses", nil, NameMasses, ""},
+		{"masses", nil, "", ""},
+		{"masses", &Options{CaseInsensitive: true}, NameMasses, ""},
+		{"  PAIR COEFFS # lj/cut", &Options{CaseInsensitive: true}, NamePairCoeffs, ""},
+		{"impropers", nil, "", ""},
+		{"impropers", &Options{CaseInsensitive: true}, "", NameImpropers},
+		{"1 15.9994", &Options{CaseInsensitive: true}, "", ""},
+	}
+	for _, tt := range tests {
+		if n, _ := IsSection([]byte(tt.s), tt.o); n != tt.section {
+			t.Errorf("IsSection(%q, %+v) = %q, want %q", tt.s, tt.o, n, tt.section)
+		}
+		if n, _ := IsUnsupportedSection([]byte(tt.s), tt.o); n != tt.unsupported {
+			t.Errorf("IsUnsupportedSection(%q, %+v) = %q, want %q", tt.s, tt.o, n, tt.unsupported)
+		}
+	}
+}
+
+func TestThousandsSeparator(t *testing.T) {
+	tests := []struct {
+		name string
+		opts *Options
+		ok   bool
+	}{
+		{"strict", nil, false},
+		{"lenient", &Options{ThousandsSeparator: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAtoms(AtomStyleFull, 1, tt.opts)
+			err := decode(t, a, "Atoms\n\n1,000 1,200 2 -0.8 0.5 1.5 2.5\n")
+			if !tt.ok {
+				if err == nil {
+					t.Error("Decode = nil with a thousands separator")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if atom := a.Map()[1000]; atom == nil || atom.MolTag != 1200 || atom.X != 0.5 {
+				t.Errorf("Atoms = %v, want atom = 1000 of molecule = 1200", a.Map())
+			}
+
+			l := newLinks(NameBonds, 2, 1, tt.opts)
+			if err := decode(t, l, "Bonds\n\n1,001 1 1,000 2\n"); err != nil {
+				t.Fatal(err)
+			}
+			if link := l.Map()[1001]; link == nil || link.Atoms()[0] != 1000 {
+				t.Errorf("Bonds = %v, want bond = 1001 of atom = 1000", l.Map())
+			}
+		})
+	}
+}
+
+func TestThousandsSeparatorColumns(t *testing.T) {
+	tests := []struct {
+		name    string
+		as      AtomStyle
+		row     string
+		want    Atom
+		wantErr string
+	}{
+		{"image flags", AtomStyleFull, "1 1 2 -0.8 0.5 1.5 2.5 1,000 -1,000 0", Atom{MolTag: 1, AtomType: 2, Q: -0.8, X: 0.5, Y: 1.5, Z: 2.5, N: true, NX: 1000, NY: -1000}, ""},
+		{"atomic type", AtomStyleAtomic, "1 1,002 0.5 1.5 2.5", Atom{AtomType: 1002, X: 0.5, Y: 1.5, Z: 2.5}, ""},
+		{"line flag", AtomStyleLine, "1 1 2 1,000 1 0 0 0", Atom{MolTag: 1, AtomType: 2, Flag: 1000, Density: 1}, ""},
+		{"coordinate", AtomStyleFull, "1 1 2 -0.8 1,000 1.5 2.5", Atom{}, `X: strconv.ParseFloat: parsing "1,000"`},
+		{"charge", AtomStyleFull, "1 1 2 1,000 0.5 1.5 2.5", Atom{}, `Q: strconv.ParseFloat: parsing "1,000"`},
+		{"density", AtomStyleLine, "1 1 2 0 1,000 0 0 0", Atom{}, `strconv.ParseFloat: parsing "1,000"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newAtoms(tt.as, 1, &Options{ThousandsSeparator: true})
+			err := decode(t, a, "Atoms\n\n"+tt.row+"\n")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if atom := a.Map()[1]; atom == nil || *atom != tt.want {
+				t.Errorf("atom = %+v, want %+v", atom, tt.want)
+			}
+		})
+	}
+}
+
+func TestDelimiter(t *testing.T) {
+	tests := []struct {
+		name  string
+		delim string
+		in    string
+		want  []string
+	}{
+		{"whitespace", "", " 1  2\t3 ", []string{"1", "2", "3"}},
+		{"pipe", "|", "|1| 2 |3|", []string{"1", "2", "3"}},
+		{"empty fields", "|", "1||2", []string{"1", "2"}},
+		{"comma", ",", "1, 2,3", []string{"1", "2", "3"}},
+		{"no field", "|", " | ", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (&Options{Delimiter: tt.delim}).split(tt.in); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+
+	opts := &Options{Delimiter: "|"}
+	a := newAtoms(AtomStyleFull, 2, opts)
+	if err := decode(t, a, "Atoms\n\n1|1|1|-0.8|0.5|1.5|2.5\n| 2 | 1 | 2 | 0.4 | 1 | 1 | 1 | 0 | 1 | 0 | # H\n"); err != nil {
+		t.Fatal(err)
+	}
+	const out = "Atoms\n\n1 1 1 -0.8 0.5 1.5 2.5\n2 1 2 0.4 1 1 1 0 1 0\n"
+	if got := encode(t, a); got != out {
+		t.Errorf("Encode = %q, want %q", got, out)
+	}
+
+	l := newLinks(NameBonds, 2, 1, opts)
+	if err := decode(t, l, "Bonds\n\n1|1|1|2\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got := l.Map()[1].Atoms(); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Atoms = %v, want [1 2]", got)
+	}
+
+	m := new(Masses)
+	m.SetKeys(header(NameAtomTypes, 2))
+	m.SetOptions(opts)
+	if err := decode(t, m, "Masses\n\n1|15.9994\n2|1.008\n"); err != nil {
+		t.Fatal(err)
+	}
+	if v := m.Values(); v[1] != 15.9994 || v[2] != 1.008 {
+		t.Errorf("Values = %v", v)
+	}
+}
+
+func TestUniformColumns(t *testing.T) {
+	// rows returns n rows built by row, the row short being built by short.
+	rows := func(n, short int, row, shortRow string) string {
+		var sb strings.Builder
+		for i := 1; i <= n; i++ {
+			format := row
+			if i == short {
+				format = shortRow
+			}
+			fmt.Fprintf(&sb, format+"\n", i)
+		}
+		return sb.String()
+	}
+	tests := []struct {
+		name    string
+		k       func(opts *Options) Key
+		in      string
+		wantErr string
+	}{
+		{"atoms", func(opts *Options) Key {
+			a := NewAtoms(AtomStyleFull)
+			a.SetKeys(header(NameAtomsNbr, 8), header(NameAtomTypes, 2))
+			a.SetOptions(opts)
+			return a
+		}, "Atoms\n\n" + rows(8, 5, "%d 1 1 0 0 0 0 0 0 0", "%d 1 1 0 0 0 0"), "Atoms table: row = 5 has 7 columns but the first row has 10"},
+		{"bonds", func(opts *Options) Key {
+			return newLinks(NameBonds, 2, 8, opts)
+		}, "Bonds\n\n" + rows(8, 6, "%d 1 1 2 0.5", "%d 1 1 2"), "Bonds table: row = 6 has 4 columns but the first row has 5"},
+		{"coeffs", func(opts *Options) Key {
+			c := NewCoeffs(NameBondCoeffs)
+			c.SetKeys(header(NameBondTypes, 8))
+			c.SetOptions(opts)
+			return c
+		}, "Bond Coeffs\n\n" + rows(8, 8, "%d 450 1", "%d 450"), "Bond Coeffs table: row = 8 has 2 columns but the first row has 3"},
+		{"comments are not counted", func(opts *Options) Key {
+			return newLinks(NameBonds, 2, 8, opts)
+		}, "Bonds\n\n" + rows(8, 3, "%d 1 1 2", "%d 1 1 2 # O-H"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the short row is decoded by default.
+			if err := decode(t, tt.k(nil), tt.in); err != nil {
+				t.Fatalf("Decode = %v without UniformColumns", err)
+			}
+			err := decode(t, tt.k(&Options{UniformColumns: true}), tt.in)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Decode = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Decode = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// the sub-styles of the hybrid Coeffs tables have different widths.
+	c := NewCoeffsHybrid(NamePairCoeffs)
+	c.SetKeys(header(NameAtomTypes, 2))
+	c.SetOptions(&Options{UniformColumns: true})
+	if err := decode(t, c, "Pair Coeffs # hybrid\n\n1 lj/cut 0.1 3.4\n2 coul/cut 10\n"); err != nil {
+		t.Errorf("Decode = %v for a hybrid table", err)
+	}
+}
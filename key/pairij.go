@@ -0,0 +1,246 @@
+package key
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// PairIJ is used to encode and/or decode the PairIJ Coeffs table from a LAMMPS
+// data file. This table has a header where a blank line separate the values
+// from it. Each value (= 1 line = 1 pair of atom types) has 3 or more columns:
+// the atom types i and j with i <= j followed by the coefficients (e.g. "1 2
+// 0.1 3.4"). As written by the write_data command of LAMMPS, there is one value
+// for each pair, including the pairs where i is equal to j.
+//
+// PairIJ can be instanced by using the built-in new function.
+type PairIJ struct {
+	types *Header
+	opts  *Options
+	v     map[[2]int][]float64
+}
+
+// Name returns NamePairIJCoeffs. It corresponds to the header of the table.
+func (p *PairIJ) Name() Name {
+	return NamePairIJCoeffs
+}
+
+// Keyword tests whether the byte slice s begins with Name after trimming the
+// spaces. Keyword is useful to detect the header of the PairIJ Coeffs table.
+// The case is ignored if Options.CaseInsensitive is true.
+func (p *PairIJ) Keyword(s []byte) bool {
+	return p.opts.keyword(s, p.Name())
+}
+
+// SetKeys assigns one or more Keys to PairIJ. This method only accepts *Header
+// with Name equal to NameAtomTypes. Only one Key must be passed.
+func (p *PairIJ) SetKeys(k ...Key) error {
+	if len(k) != 1 {
+		return fmt.Errorf("only one Key is accepted")
+	}
+	h, ok := k[0].(*Header)
+	if !ok {
+		return fmt.Errorf("type assertion error: Key provided is not *Header")
+	}
+	if h.name != NameAtomTypes {
+		return fmt.Errorf("Key provided does not have a Name equal to NameAtomTypes")
+	}
+	p.types = h
+	return nil
+}
+
+// SetOptions assigns the Options used by the Keyword, Encode, and Decode
+// methods. o can be nil.
+func (p *PairIJ) SetOptions(o *Options) {
+	p.opts = o
+}
+
+// SetKeysVal returns ErrUnsupported: the number of atom types cannot be
+// deduced from the number of pairs without ambiguity and must be set by the
+// Masses or Pair Coeffs tables or the header itself. The Check method then
+// verifies the table against it.
+func (p *PairIJ) SetKeysVal() error {
+	return ErrUnsupported
+}
+
+// pairs returns the pairs of atom types of the values sorted by i then j.
+func (p *PairIJ) pairs() [][2]int {
+	pairs := make([][2]int, 0, len(p.v))
+	for ij := range p.v {
+		pairs = append(pairs, ij)
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a][0] != pairs[b][0] {
+			return pairs[a][0] < pairs[b][0]
+		}
+		return pairs[a][1] < pairs[b][1]
+	})
+	return pairs
+}
+
+// Encode writes a table containing the header, a blank line and each value (= 1
+// line = 1 pair) into a writer in increasing order of i then j.
+//
+// This method does not check the integrity and correctness of each value. To do
+// so, use the Check method.
+func (p *PairIJ) Encode(w io.Writer) error {
+	if p.v == nil {
+		return fmt.Errorf("map[[2]int][]float64 is nil: use the Decode or Set methods")
+	}
+	if len(p.v) == 0 {
+		return nil
+	}
+
+	fmt.Fprint(w, p.Name(), "\n\n")
+	for _, ij := range p.pairs() {
+		if _, err := fmt.Fprintf(w, "%d %d", ij[0], ij[1]); err != nil {
+			return fmt.Errorf("fmt.Fprintf: %w", err)
+		}
+		for _, v := range p.v[ij] {
+			if _, err := fmt.Fprintf(w, " %s", p.opts.float(v)); err != nil {
+				return fmt.Errorf("fmt.Fprintf coeff: %w", err)
+			}
+		}
+		if _, err := fmt.Fprint(w, "\n"); err != nil {
+			return fmt.Errorf("fmt.Fprintf newline: %w", err)
+		}
+	}
+	return nil
+}
+
+// Decode reads a reader where the offset is after the header of the table (at
+// the beginning of the blank line). It reads one value (= 1 line) for each pair
+// of atom types i <= j and creates a slice of float64s that is put into a map
+// where the keys are the pairs.
+//
+// This method needs a Key in order to work. This Key is an instance of Header
+// with Name equal to NameAtomTypes. Use the Set method to assign this Key.
+//
+// The blank lines between the values are skipped and are not counted.
+//
+// Moreover, this method does not check the integrity and corectness of the
+// values decoded. To do so, use the Check method.
+//
+// Decode method does not return io.EOF error. If the input ends before the
+// number of expected pairs is read, an error wrapping ErrTruncated is returned.
+func (p *PairIJ) Decode(s []byte, r *bufio.Scanner) error {
+	if p.types == nil {
+		return fmt.Errorf("Key that is an instance of *Header with Name equal to NameAtomTypes is nil: use the Set method")
+	}
+	p.types.SetMin(1) // the table is present: there is at least one type
+
+	types := p.types.Get().(int)
+	pairs := types * (types + 1) / 2
+	p.v = make(map[[2]int][]float64)
+
+	if ok := r.Scan(); !ok {
+		if r.Err() != nil {
+			return fmt.Errorf("r.Scan first line: %w", r.Err())
+		}
+		if pairs > 0 {
+			return truncated(p.Name(), 0, pairs)
+		}
+		return nil
+	}
+
+	i, width := 0, 0
+	for ; i < pairs && scanValue(r); i++ {
+		s := delComments(r.Bytes())
+		f := p.opts.fields(string(s))
+		if i == 0 {
+			width = len(f)
+		} else if err := p.opts.width(p.Name(), i+1, len(f), width); err != nil {
+			return err
+		}
+		if len(f) < 3 {
+			return fmt.Errorf("not enough fields = %d, want >= 3", len(f))
+		}
+		var ij [2]int
+		for j, field := range []string{"i", "j"} {
+			var err error
+			ij[j], err = strconv.Atoi(f[j])
+			if err != nil {
+				return setLine(parseError("strconv.Atoi", field, err), p.Name(), i+1)
+			}
+		}
+		coeffs := make([]float64, 0, len(f)-2)
+		for _, v := range f[2:] {
+			coeff, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return setLine(parseError("strconv.ParseFloat", "", err), p.Name(), i+1)
+			}
+			coeffs = append(coeffs, coeff)
+		}
+		p.v[ij] = coeffs
+	}
+	if r.Err() != nil {
+		return fmt.Errorf("r.Scan: %w", r.Err())
+	}
+	if i < pairs {
+		return truncated(p.Name(), i, pairs)
+	}
+	return nil
+}
+
+// Set puts a custom map[[2]int][]float64 (e.g. returned by the MixPairCoeffs
+// function of the lmpsdat package).
+//
+// This method does not check the integrity or correctness of the passed data.
+// The use of the Check method after Set is therefore highly recommended.
+func (p *PairIJ) Set(v interface{}) error {
+	var ok bool
+	p.v, ok = v.(map[[2]int][]float64)
+	if !ok {
+		return fmt.Errorf("type assertion error: value is not map[[2]int][]float64")
+	}
+	return nil
+}
+
+// Get returns a map[[2]int][]float64 where the keys are the pairs of atom types.
+// As this method returns an interface, it must be useful to perform a type
+// assertion after calling this method.
+func (p *PairIJ) Get() interface{} {
+	return p.v
+}
+
+// Values returns the map[[2]int][]float64 where the keys are the pairs of atom
+// types. Unlike Get, no type assertion is required. The map is not copied:
+// modifying it modifies the values of PairIJ.
+func (p *PairIJ) Values() map[[2]int][]float64 {
+	return p.v
+}
+
+// Len returns the number of pairs.
+func (p *PairIJ) Len() int {
+	return len(p.v)
+}
+
+// Check verifies the integrity and correctness of the data decoded with the
+// Decode method or set with the Set method. There must be one value for each
+// pair of atom types i <= j.
+//
+// This method needs a Key in order to work. This Key is an instance of Header
+// with Name equal to NameAtomTypes. Use the Set method to assign this Key.
+func (p *PairIJ) Check() error {
+	if p.types == nil {
+		return fmt.Errorf("Key that is an instance of *Header with Name equal to NameAtomTypes is nil: use the Set method")
+	}
+	types := p.types.Get().(int)
+	if pairs := types * (types + 1) / 2; len(p.v) != pairs {
+		return countMismatch(p.Name(), len(p.v), pairs, "number of pairs of coefficients (= 1 line = 1 pair) = %d is not equal to the number of pairs of types = %d")
+	}
+	for _, ij := range p.pairs() {
+		if ij[0] < 1 || ij[0] > ij[1] || ij[1] > types {
+			return fmt.Errorf("pair = %d %d is invalid: the types must verify 1 <= i <= j <= the number of types = %d", ij[0], ij[1], types)
+		}
+		for i, v := range p.v[ij] {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("coefficient = %g of pair = %d %d at column = %d is not finite", v, ij[0], ij[1], i+1)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package key
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPairIJ(t *testing.T) {
+	tests := []struct {
+		name     string
+		rows     string
+		want     map[[2]int][]float64
+		err      error
+		checkErr string
+	}{
+		{"pairs", "1 1 0.1 3\n1 2 0.2 3.5\n\n2 2 0.4 4\n", map[[2]int][]float64{{1, 1}: {0.1, 3}, {1, 2}: {0.2, 3.5}, {2, 2}: {0.4, 4}}, nil, ""},
+		{"unordered", "2 2 0.4 4\n1 2 0.2 3.5\n1 1 0.1 3\n", map[[2]int][]float64{{1, 1}: {0.1, 3}, {1, 2}: {0.2, 3.5}, {2, 2}: {0.4, 4}}, nil, ""},
+		{"truncated", "1 1 0.1 3\n1 2 0.2 3.5\n", nil, ErrTruncated, ""},
+		{"j lower than i", "1 1 0.1 3\n2 1 0.2 3.5\n2 2 0.4 4\n", nil, nil, "pair = 2 1 is invalid"},
+		{"type out of range", "1 1 0.1 3\n1 3 0.2 3.5\n2 2 0.4 4\n", nil, nil, "pair = 1 3 is invalid"},
+		{"duplicate", "1 1 0.1 3\n1 1 0.2 3.5\n2 2 0.4 4\n", nil, nil, "number of pairs of coefficients (= 1 line = 1 pair) = 2"},
+		{"no coefficient", "1 1\n1 2\n2 2\n", nil, errors.New("not enough fields = 2, want >= 3"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := new(PairIJ)
+			p.SetKeys(header(NameAtomTypes, 2))
+			err := decode(t, p, "PairIJ Coeffs\n\n"+tt.rows)
+			if tt.err != nil {
+				if err == nil || (!errors.Is(err, tt.err) && err.Error() != tt.err.Error()) {
+					t.Errorf("Decode = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = p.Check()
+			if tt.checkErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.checkErr) {
+					t.Errorf("Check = %v, want an error containing %q", err, tt.checkErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p.Values(), tt.want) {
+				t.Errorf("Values = %v, want %v", p.Values(), tt.want)
+			}
+			const out = "PairIJ Coeffs\n\n1 1 0.1 3\n1 2 0.2 3.5\n2 2 0.4 4\n"
+			if got := encode(t, p); got != out {
+				t.Errorf("Encode = %q, want %q", got, out)
+			}
+		})
+	}
+
+	if new(PairIJ).Keyword([]byte("Pair Coeffs")) || NewCoeffs(NamePairCoeffs).Keyword([]byte("PairIJ Coeffs")) {
+		t.Error("Keyword matches the header of the other Pair Coeffs table")
+	}
+}
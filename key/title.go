@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 )
 
 // Title is used to encode and/or decode the title from a LAMMPS data file. It
@@ -11,7 +12,8 @@ import (
 //
 // Title must be instanced by using the built-in new function.
 type Title struct {
-	v string
+	v    string
+	opts *Options
 }
 
 // Name returns NameTitle. It corresponds to the title of the LAMMPS data file
@@ -31,23 +33,47 @@ func (t *Title) SetKeys(k ...Key) error {
 	return ErrUnsupported
 }
 
+// SetOptions assigns the Options used by the Decode method. o can be nil.
+func (t *Title) SetOptions(o *Options) {
+	t.opts = o
+}
+
 // SetKeysVal returns ErrUnsupported as it is unsupported by Title.
 func (t *Title) SetKeysVal() error {
 	return ErrUnsupported
 }
 
-// Encode writes the title of the LAMMPS data file.
+// Encode writes the title of the LAMMPS data file. A title of several lines
+// (see Decoder.SetTitleLines in the lmpsdat package) is written as is.
 func (t *Title) Encode(w io.Writer) error {
 	_, err := fmt.Fprintln(w, t.v)
 	return err
 }
 
-// Decode assigns the title of the LAMMPS data file into Title.
+// Decode assigns the title of the LAMMPS data file into Title. The title is
+// kept verbatim unless Options.StripTitleComment is true: the leading "#" and
+// the spaces that follow it are then removed from each line of the title (e.g.
+// "# LAMMPS data file" becomes "LAMMPS data file").
 func (t *Title) Decode(s []byte, r *bufio.Scanner) error {
 	t.v = string(s)
+	if t.opts != nil && t.opts.StripTitleComment {
+		lines := strings.Split(t.v, "\n")
+		for i, l := range lines {
+			if trimmed := strings.TrimLeft(l, " \t"); strings.HasPrefix(trimmed, "#") {
+				lines[i] = strings.TrimLeft(trimmed[1:], " \t")
+			}
+		}
+		t.v = strings.Join(lines, "\n")
+	}
 	return nil
 }
 
+// IsComment returns true if the title begins with a "#" after the leading
+// spaces, as written by some pipelines (e.g. "# LAMMPS data file via ...").
+func (t *Title) IsComment() bool {
+	return strings.HasPrefix(strings.TrimLeft(t.v, " \t"), "#")
+}
+
 // Set puts a custom string.
 func (t *Title) Set(v interface{}) error {
 	val, ok := v.(string)
@@ -63,6 +89,12 @@ func (t *Title) Get() interface{} {
 	return t.v
 }
 
+// String returns the title of the LAMMPS data file. Unlike Get, no type
+// assertion is required.
+func (t *Title) String() string {
+	return t.v
+}
+
 // Check verifies the integrity and correctness of the data decoded with the
 // Decode method or set with the Set method. This method always return nil.
 func (t *Title) Check() error {
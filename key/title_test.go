@@ -0,0 +1,44 @@
+package key
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestTitleComment(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		comment  bool
+		stripped string
+	}{
+		{"comment", "# LAMMPS data file via write_data", true, "LAMMPS data file via write_data"},
+		{"indented comment", "  #LAMMPS data file", true, "LAMMPS data file"},
+		{"several lines", "# water box\n# 2 molecules", true, "water box\n2 molecules"},
+		{"plain", "LAMMPS data file", false, "LAMMPS data file"},
+		{"inner #", "LAMMPS data file # water", false, "LAMMPS data file # water"},
+		{"empty", "", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, strip := range []bool{false, true} {
+				title := new(Title)
+				title.SetOptions(&Options{StripTitleComment: strip})
+				if err := title.Decode([]byte(tt.in), bufio.NewScanner(strings.NewReader(""))); err != nil {
+					t.Fatal(err)
+				}
+				want, comment := tt.in, tt.comment
+				if strip {
+					want, comment = tt.stripped, false
+				}
+				if title.String() != want {
+					t.Errorf("StripTitleComment = %v: title = %q, want %q", strip, title.String(), want)
+				}
+				if title.IsComment() != comment {
+					t.Errorf("StripTitleComment = %v: IsComment = %v, want %v", strip, title.IsComment(), comment)
+				}
+			}
+		})
+	}
+}
@@ -21,6 +21,31 @@ func IsName(name Name) bool {
 	return false
 }
 
+// IsSection returns the Name of the table whose header is the line s. If s is
+// not the header of a table listed in ListSections, it returns false. The case
+// is ignored if o.CaseInsensitive is true. o can be nil.
+func IsSection(s []byte, o *Options) (Name, bool) {
+	for _, n := range ListSections {
+		if o.keyword(s, n) {
+			return n, true
+		}
+	}
+	return "", false
+}
+
+// IsUnsupportedSection returns the Name of the table whose header is the line
+// s. If s is not the header of a table listed in ListUnsupportedSections, it
+// returns false. The case is ignored if o.CaseInsensitive is true. o can be
+// nil.
+func IsUnsupportedSection(s []byte, o *Options) (Name, bool) {
+	for _, n := range ListUnsupportedSections {
+		if o.keyword(s, n) {
+			return n, true
+		}
+	}
+	return "", false
+}
+
 // IsAtomStyle returns true if an Atom Style exists and is supported by this
 // package.
 func IsAtomStyle(as string) bool {
@@ -43,6 +68,22 @@ func NewAtomStyle(as string) AtomStyle {
 	return nil
 }
 
+// Columns returns the columns of an atom style (after the identifier of the
+// atom and without the image flags). It returns nil if the columns of the atom
+// style are not known, e.g. for an atom style defined outside of this package.
+func Columns(as AtomStyle) []Column {
+	switch as {
+	case AtomStyleFull:
+		return []Column{ColumnMolTag, ColumnAtomType, ColumnQ, ColumnX, ColumnY, ColumnZ}
+	case AtomStyleAtomic:
+		return []Column{ColumnAtomType, ColumnX, ColumnY, ColumnZ}
+	}
+	if a, ok := as.(*atomStyleColumns); ok {
+		return append([]Column(nil), a.cols...)
+	}
+	return nil
+}
+
 type makeKeys struct {
 	k  map[Name]Key
 	as AtomStyle
@@ -56,6 +97,14 @@ func MakeKeys(names []Name, as AtomStyle) map[Name]Key {
 	for _, n := range names {
 		m.New(n)
 	}
+	// the Links verify that their atoms exist if the Atoms are decoded.
+	if atoms, ok := m.k[NameAtoms]; ok {
+		for _, n := range []Name{NameBonds, NameAngles, NameDihedrals} {
+			if l, ok := m.k[n]; ok {
+				l.SetKeys(atoms)
+			}
+		}
+	}
 	return m.k
 }
 
@@ -69,6 +118,9 @@ func (m *makeKeys) New(name Name) Key {
 	case NamePairCoeffs:
 		v = NewCoeffs(name)
 		v.SetKeys(m.New(NameAtomTypes))
+	case NamePairIJCoeffs:
+		v = new(PairIJ)
+		v.SetKeys(m.New(NameAtomTypes))
 	case NameBondCoeffs:
 		v = NewCoeffs(name)
 		v.SetKeys(m.New(NameBondTypes))
@@ -79,9 +131,23 @@ func (m *makeKeys) New(name Name) Key {
 		v = NewCoeffs(name)
 		v.SetKeys(m.New(NameDihedralTypes))
 
-	case NameAtomsNbr, NameBondsNbr, NameAnglesNbr, NameDihedralsNbr:
+	// class2 cross-terms: the tables are indexed by the type of their parent
+	// interaction.
+	case NameBondBondCoeffs, NameBondAngleCoeffs:
+		v = NewCoeffs(name)
+		v.SetKeys(m.New(NameAngleTypes))
+	case NameMiddleBondTorsionCoeffs, NameEndBondTorsionCoeffs, NameAngleTorsionCoeffs,
+		NameAngleAngleTorsionCoeffs, NameBondBond13Coeffs:
+		v = NewCoeffs(name)
+		v.SetKeys(m.New(NameDihedralTypes))
+	case NameAngleAngleCoeffs:
+		v = NewCoeffs(name)
+		v.SetKeys(m.New(NameImproperTypes))
+
+	case NameAtomsNbr, NameBondsNbr, NameAnglesNbr, NameDihedralsNbr, NameImpropersNbr,
+		NameLinesNbr, NameTrianglesNbr:
 		v = NewHeader(name)
-	case NameAtomTypes, NameBondTypes, NameAngleTypes, NameDihedralTypes:
+	case NameAtomTypes, NameBondTypes, NameAngleTypes, NameDihedralTypes, NameImproperTypes:
 		v = NewHeader(name)
 	case NameBoxX, NameBoxY, NameBoxZ:
 		v = NewBox(name)
@@ -95,6 +161,15 @@ func (m *makeKeys) New(name Name) Key {
 		v.SetKeys(m.New(NameAtomTypes),
 			m.New(NameAtomsNbr))
 
+	case NameLines:
+		v = NewBonus(name, 4)
+		v.SetKeys(m.New(NameAtomsNbr),
+			m.New(NameLinesNbr))
+	case NameTriangles:
+		v = NewBonus(name, 9)
+		v.SetKeys(m.New(NameAtomsNbr),
+			m.New(NameTrianglesNbr))
+
 	case NameBonds:
 		v = NewLinks(name, 2)
 		v.SetKeys(m.New(NameAtomsNbr),
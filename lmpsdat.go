@@ -2,53 +2,268 @@ package lmpsdat
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/kpotier/lmpsdat/key"
 )
 
-// createNames returns a map that links the Names to the field identifiers of a
-// structure and a map that links the Names to the corresponding Keys.
+// createNames returns a map that links the Names to the index sequences (see
+// reflect.Value.FieldByIndex) of the fields of a structure and a map that links
+// the Names to the corresponding Keys.
 // lmpsdat:"Atoms" must include the Atom Style. For instance, it should be
 // lmpsdat:"Atoms, full". If the Atom Style is not specified or does not exist,
-// the Atom Style "full" will be used.
-func createNames(typ reflect.Type) (map[key.Name]int, map[key.Name]key.Key) {
-	atomStyle := key.AtomStyleFull
+// the Atom Style "full" will be used unless it is detected while decoding (see
+// key.Options.DetectAtomStyle). If sections is not nil, only the Names that are
+// in sections are kept.
+//
+// The fields of the embedded structs that are not tagged are also analyzed, as
+// in encoding/json. The embedded pointers of struct are ignored. As for the
+// promoted fields, a Name tagged at a shallower depth takes precedence.
+func createNames(typ reflect.Type, sections map[key.Name]bool) (map[key.Name][]int, map[key.Name]key.Key) {
+	var atomStyle key.AtomStyle // nil means that the Atom Style is not specified
 	names := make([]key.Name, 0)
-	namesFields := make(map[key.Name]int, 0)
-	for i := 0; i < typ.NumField(); i++ {
-		f := typ.Field(i)
-		v, ok := f.Tag.Lookup("lmpsdat")
-		if !ok {
-			continue
-		}
-		if strings.HasPrefix(v, string(key.NameAtoms)) { // case where lmpsdat:"Atoms, ..."
-			idx := strings.IndexRune(v, ',')
-			if idx >= 0 && idx <= len(v) {
-				as := strings.TrimSpace(v[idx+1:])
-				if key.IsAtomStyle(as) {
-					atomStyle = key.NewAtomStyle(as)
+	namesFields := make(map[key.Name][]int, 0)
+
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	for level := []embedded{{typ: typ}}; len(level) > 0; {
+		var next []embedded
+		for _, e := range level {
+			for i := 0; i < e.typ.NumField(); i++ {
+				f := e.typ.Field(i)
+				index := append(append([]int(nil), e.index...), i)
+				v, ok := f.Tag.Lookup("lmpsdat")
+				if !ok {
+					if f.Anonymous && f.Type.Kind() == reflect.Struct {
+						next = append(next, embedded{typ: f.Type, index: index})
+					}
+					continue
+				}
+				if f.PkgPath != "" { // unexported fields cannot be set or read
+					fmt.Fprintf(os.Stderr, "WARNING: field = %s is unexported", f.Name)
+					continue
+				}
+				if strings.HasPrefix(v, string(key.NameAtoms)) { // case where lmpsdat:"Atoms, ..."
+					idx := strings.IndexRune(v, ',')
+					if idx >= 0 && idx <= len(v) {
+						as := strings.TrimSpace(v[idx+1:])
+						if key.IsAtomStyle(as) {
+							atomStyle = key.NewAtomStyle(as)
+						} else {
+							fmt.Fprintf(os.Stderr, "WARNING: atom style = %s is not supported", as)
+						}
+						v = strings.TrimSpace(v[:idx])
+					}
+				}
+				n := key.Name(v)
+				if sections != nil && !sections[n] {
+					continue
+				}
+				if _, ok := namesFields[n]; ok {
+					continue // shadowed by a shallower field
+				}
+				if key.IsName(n) {
+					namesFields[n] = index
+					names = append(names, n)
 				} else {
-					fmt.Fprintf(os.Stderr, "WARNING: atom style = %s is not supported", as)
+					fmt.Fprintf(os.Stderr, "WARNING: name = %s is not supported", v)
 				}
-				v = strings.TrimSpace(v[:idx])
 			}
 		}
-		n := key.Name(v)
-		if key.IsName(n) {
-			namesFields[n] = i
-			names = append(names, n)
-		} else {
-			fmt.Fprintf(os.Stderr, "WARNING: name = %s is not supported", v)
-		}
+		level = next
 	}
 	return namesFields, key.MakeKeys(names, atomStyle)
 }
 
-// headBody separate the keys. It reproduces what the LAMMPS data parser does.
+// structOf returns the struct pointed to by v. It returns an error if v is not
+// a pointer of a struct.
+func structOf(v interface{}) (reflect.Value, error) {
+	ptr := reflect.TypeOf(v)
+	if ptr == nil || ptr.Kind() != reflect.Ptr {
+		return reflect.Value{}, fmt.Errorf("interface passed is not a pointer")
+	}
+	if reflect.ValueOf(v).IsNil() {
+		return reflect.Value{}, fmt.Errorf("interface passed is a nil pointer")
+	}
+	if ptr.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("interface passed is not a pointer of a struct")
+	}
+	return reflect.ValueOf(v).Elem(), nil
+}
+
+// fields returns the fields of the struct pointed to by v that are tagged with
+// a supported Name.
+func fields(v interface{}) (map[key.Name]reflect.Value, error) {
+	val, err := structOf(v)
+	if err != nil {
+		return nil, err
+	}
+	nFields, _ := createNames(val.Type(), nil)
+	f := make(map[key.Name]reflect.Value, len(nFields))
+	for n, i := range nFields {
+		f[n] = val.FieldByIndex(i)
+	}
+	return f, nil
+}
+
+// assign sets field to v. Besides the values assignable to field, it accepts:
+//   - a pointer field whose element is assignable from v, e.g. *string for the
+//     title: the pointer is allocated;
+//   - a slice field whose elements have the type of the elements of the array
+//     v, e.g. []float64 for the box;
+//   - a field of a named type whose underlying type is the one of v, e.g. a
+//     type defined as map[int]float64 for the masses.
+func assign(field, v reflect.Value) error {
+	ft := field.Type()
+	switch {
+	case v.Type().AssignableTo(ft):
+		field.Set(v)
+	case ft.Kind() == reflect.Ptr && v.Type().AssignableTo(ft.Elem()):
+		p := reflect.New(ft.Elem())
+		p.Elem().Set(v)
+		field.Set(p)
+	case ft.Kind() == reflect.Slice && v.Kind() == reflect.Array && v.Type().Elem() == ft.Elem():
+		s := reflect.MakeSlice(ft, v.Len(), v.Len())
+		reflect.Copy(s, v)
+		field.Set(s)
+	case v.Kind() == ft.Kind() && v.Type().ConvertibleTo(ft):
+		field.Set(v.Convert(ft))
+	default:
+		return fmt.Errorf("type = %s is not assignable to type = %s", v.Type(), ft)
+	}
+	return nil
+}
+
+// valueOf returns the value of field converted to typ, the type of the values
+// accepted by the Key of the field. It reverses the conversions of assign: a
+// nil pointer gives the zero value of typ, and a slice is copied into an array
+// if its length is the one of the array. field is returned unchanged if it
+// cannot be converted or if typ is nil.
+func valueOf(field reflect.Value, typ reflect.Type) interface{} {
+	if typ == nil || field.Type() == typ {
+		return field.Interface()
+	}
+	switch {
+	case field.Kind() == reflect.Ptr && field.Type().Elem().AssignableTo(typ):
+		if field.IsNil() {
+			return reflect.Zero(typ).Interface()
+		}
+		return field.Elem().Interface()
+	case field.Kind() == reflect.Slice && typ.Kind() == reflect.Array && field.Type().Elem() == typ.Elem() && field.Len() == typ.Len():
+		a := reflect.New(typ).Elem()
+		reflect.Copy(a, field)
+		return a.Interface()
+	case field.Kind() == typ.Kind() && field.Type().ConvertibleTo(typ):
+		return field.Convert(typ).Interface()
+	}
+	return field.Interface()
+}
+
+// keyTypes links each Name to the type of the values of its Key (see
+// key.Key.Get), e.g. map[int]*key.Atom for NameAtoms. The fields are converted
+// to these types by valueOf.
+var keyTypes = func() map[key.Name]reflect.Type {
+	keys := key.MakeKeys(key.ListNames, nil)
+	types := make(map[key.Name]reflect.Type, len(keys))
+	for n, k := range keys {
+		types[n] = reflect.TypeOf(k.Get())
+	}
+	return types
+}()
+
+// fieldOf returns the value of the field of f whose Name is name converted to
+// the type of the values of its Key (see valueOf), so that the pointer, slice,
+// and named-type fields accepted by Decode can be read. It returns false if
+// there is no such field and an error if the field cannot be converted.
+func fieldOf(f map[key.Name]reflect.Value, name key.Name) (interface{}, bool, error) {
+	field, ok := f[name]
+	if !ok {
+		return nil, false, nil
+	}
+	typ := keyTypes[name]
+	v := valueOf(field, typ)
+	if reflect.TypeOf(v) != typ {
+		return nil, true, fmt.Errorf("field with Name = %s is not %s", name, typ)
+	}
+	return v, true, nil
+}
+
+// setField sets the field of f whose Name is name to v (see assign).
+func setField(f map[key.Name]reflect.Value, name key.Name, v interface{}) error {
+	if err := assign(f[name], reflect.ValueOf(v)); err != nil {
+		return fmt.Errorf("field with Name = %s: %w", name, err)
+	}
+	return nil
+}
+
+// intOf returns the value of the Header field of f whose Name is name. It
+// returns false if there is no such field.
+func intOf(f map[key.Name]reflect.Value, name key.Name) (int, bool, error) {
+	v, ok, err := fieldOf(f, name)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	return v.(int), true, nil
+}
+
+// atomsOf returns the atoms of the field of f tagged with NameAtoms. It returns
+// false if there is no such field.
+func atomsOf(f map[key.Name]reflect.Value) (map[int]*key.Atom, bool, error) {
+	v, ok, err := fieldOf(f, key.NameAtoms)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return v.(map[int]*key.Atom), true, nil
+}
+
+// linksOf returns the links of the Links table of the fields f whose Name is
+// name. It returns false if there is no such field.
+func linksOf(f map[key.Name]reflect.Value, name key.Name) (map[int]*key.Link, bool, error) {
+	v, ok, err := fieldOf(f, name)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return v.(map[int]*key.Link), true, nil
+}
+
+// tableLen returns the number of values of the table of the fields f whose
+// Name is name. It returns false if there is no such field.
+func tableLen(f map[key.Name]reflect.Value, name key.Name) (int, bool, error) {
+	v, ok, err := fieldOf(f, name)
+	if err != nil || !ok {
+		return 0, ok, err
+	}
+	return reflect.ValueOf(v).Len(), true, nil
+}
+
+// isNil returns true if v is a nil map, slice, or pointer.
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// atomIDs returns the identifiers of the atoms sorted in increasing order.
+func atomIDs(atoms map[int]*key.Atom) []int {
+	ids := make([]int, 0, len(atoms))
+	for id := range atoms {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// headBody separate the keys. It reproduces what the LAMMPS data parser does:
+// the headers are matched regardless of their order until the first table.
 func headBody(keys map[key.Name]key.Key) (headers, bodies map[key.Name]key.Key) {
 	headers = make(map[key.Name]key.Key)
 	bodies = make(map[key.Name]key.Key)
@@ -62,18 +277,23 @@ func headBody(keys map[key.Name]key.Key) (headers, bodies map[key.Name]key.Key)
 	return
 }
 
-// keyDecode calls the Keyword method for several Keys. If a Keyword returns
-// true, the Decode method will be called and this function will return true.
-func keyDecode(s []byte, keys map[key.Name]key.Key, r *bufio.Scanner) (bool, error) {
-	for n, k := range keys {
-		if k.Keyword(s) {
-			err := k.Decode(s, r)
-			if err != nil {
-				return true, fmt.Errorf("k.Decode for Key = %s: %w", k.Name(), err)
-			}
-			delete(keys, n)
+// skipSection reads a reader where the offset is after the header of a table.
+// It reads the blank lines following the header and the values until the next
+// blank line. It returns true if it stopped at a FrameSeparator.
+func skipSection(r *bufio.Scanner) (bool, error) {
+	values := false
+	for r.Scan() {
+		if isSeparator(r.Bytes()) {
 			return true, nil
 		}
+		blank := len(bytes.TrimSpace(r.Bytes())) == 0
+		if blank && values {
+			break
+		}
+		values = !blank
+	}
+	if r.Err() != nil {
+		return false, fmt.Errorf("r.Scan: %w", r.Err())
 	}
 	return false, nil
 }
@@ -0,0 +1,152 @@
+package lmpsdat
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// system is the structure used by the tests. It has a field for each Key of
+// testdata/full.data.
+type system struct {
+	Title string `lmpsdat:"Title"`
+
+	AtomsNbr   int `lmpsdat:"atoms"`
+	AtomTypes  int `lmpsdat:"atom types"`
+	BondsNbr   int `lmpsdat:"bonds"`
+	BondTypes  int `lmpsdat:"bond types"`
+	AnglesNbr  int `lmpsdat:"angles"`
+	AngleTypes int `lmpsdat:"angle types"`
+
+	X [2]float64 `lmpsdat:"xlo xhi"`
+	Y [2]float64 `lmpsdat:"ylo yhi"`
+	Z [2]float64 `lmpsdat:"zlo zhi"`
+
+	Masses      map[int]float64   `lmpsdat:"Masses"`
+	PairCoeffs  map[int][]float64 `lmpsdat:"Pair Coeffs"`
+	BondCoeffs  map[int][]float64 `lmpsdat:"Bond Coeffs"`
+	AngleCoeffs map[int][]float64 `lmpsdat:"Angle Coeffs"`
+
+	Atoms  map[int]*key.Atom `lmpsdat:"Atoms, full"`
+	Bonds  map[int]*key.Link `lmpsdat:"Bonds"`
+	Angles map[int]*key.Link `lmpsdat:"Angles"`
+}
+
+// atomic is a minimal structure with an Atoms table of atom style atomic.
+type atomic struct {
+	Title     string            `lmpsdat:"Title"`
+	AtomsNbr  int               `lmpsdat:"atoms"`
+	AtomTypes int               `lmpsdat:"atom types"`
+	X         [2]float64        `lmpsdat:"xlo xhi"`
+	Y         [2]float64        `lmpsdat:"ylo yhi"`
+	Z         [2]float64        `lmpsdat:"zlo zhi"`
+	Masses    map[int]float64   `lmpsdat:"Masses"`
+	Atoms     map[int]*key.Atom `lmpsdat:"Atoms, atomic"`
+}
+
+// lines is a structure with an Atoms table of atom style line and its Lines
+// table.
+type lines struct {
+	AtomsNbr  int               `lmpsdat:"atoms"`
+	AtomTypes int               `lmpsdat:"atom types"`
+	LinesNbr  int               `lmpsdat:"lines"`
+	X         [2]float64        `lmpsdat:"xlo xhi"`
+	Y         [2]float64        `lmpsdat:"ylo yhi"`
+	Z         [2]float64        `lmpsdat:"zlo zhi"`
+	Atoms     map[int]*key.Atom `lmpsdat:"Atoms, line"`
+	Lines     map[int][]float64 `lmpsdat:"Lines"`
+}
+
+// readFile returns the content of the file name of the testdata directory.
+func readFile(t testing.TB, name string) string {
+	t.Helper()
+	b, err := os.ReadFile("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// update rewrites the golden files of the testdata directory with the output of
+// the tests: go test -run TestName -update.
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+// golden compares got to the content of the file name of the testdata
+// directory. The file is written instead if the -update flag is set.
+func golden(t testing.TB, name, got string) {
+	t.Helper()
+	if *update {
+		if err := os.WriteFile("testdata/"+name, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	if want := readFile(t, name); got != want {
+		t.Errorf("output differs from testdata/%s:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+// decodeString decodes s into v with a new Decoder configured by opts.
+func decodeString(s string, v interface{}, opts ...func(*Decoder)) error {
+	dec := NewDecoder(strings.NewReader(s))
+	for _, o := range opts {
+		o(dec)
+	}
+	return dec.Decode(v)
+}
+
+// encodeString encodes v with a new Encoder configured by opts.
+func encodeString(t testing.TB, v interface{}, opts ...func(*Encoder)) string {
+	t.Helper()
+	var b bytes.Buffer
+	enc := NewEncoder(&b)
+	for _, o := range opts {
+		o(enc)
+	}
+	if err := enc.Encode(v); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	return b.String()
+}
+
+// fullSystem returns testdata/full.data decoded into a system.
+func fullSystem(t testing.TB) *system {
+	t.Helper()
+	var s system
+	if err := decodeString(readFile(t, "full.data"), &s); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	return &s
+}
+
+func TestDecodeFull(t *testing.T) {
+	s := fullSystem(t)
+	if s.Title != "LAMMPS data file" {
+		t.Errorf("Title = %q", s.Title)
+	}
+	if len(s.Atoms) != 6 || len(s.Bonds) != 4 || len(s.Angles) != 2 {
+		t.Errorf("len = %d atoms, %d bonds, %d angles, want 6, 4, 2", len(s.Atoms), len(s.Bonds), len(s.Angles))
+	}
+	if got := *s.Atoms[2]; got != (key.Atom{MolTag: 1, AtomType: 2, Q: 0.4238, X: 1.8, Y: 1.5, Z: 1}) {
+		t.Errorf("atom 2 = %+v", got)
+	}
+	if got := s.Angles[1].Atoms(); len(got) != 3 || got[1] != 1 {
+		t.Errorf("angle 1 = %v", got)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s := fullSystem(t)
+	out := encodeString(t, s)
+	var s2 system
+	if err := decodeString(out, &s2); err != nil {
+		t.Fatalf("Decode of the encoded file: %v\n%s", err, out)
+	}
+	if out2 := encodeString(t, &s2); out2 != out {
+		t.Errorf("second encoding differs:\n%s\nwant:\n%s", out2, out)
+	}
+}
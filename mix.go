@@ -0,0 +1,78 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MixPairCoeffs returns the coefficients of each pair of atom types (i, j) with
+// i <= j, mixed from the coefficients of the types in diagonal (see
+// NamePairCoeffs) as done by LAMMPS for the lj styles. The pairs where i is
+// equal to j get a copy of the coefficients of their type, so that the result
+// can be written as the PairIJ Coeffs table of write_data (see
+// NamePairIJCoeffs). The coefficients of each type are epsilon and sigma,
+// optionally followed by the cutoff. rule is the mixing rule, named as in the
+// pair_modify command of LAMMPS:
+//   - "geometric": epsilon and sigma are the geometric means;
+//   - "arithmetic" (Lorentz-Berthelot): epsilon is the geometric mean and sigma
+//     is the arithmetic mean;
+//   - "sixthpower": sigma_ij = ((sigma_i^6 + sigma_j^6) / 2)^(1/6) and
+//     epsilon_ij = 2 sqrt(epsilon_i epsilon_j) sigma_i^3 sigma_j^3 /
+//     (sigma_i^6 + sigma_j^6).
+//
+// The cutoff is mixed as sigma. An error is returned if rule is not known or if
+// the coefficients of two types cannot be mixed (e.g. a cutoff for only one of
+// them).
+func MixPairCoeffs(diagonal map[int][]float64, rule string) (map[[2]int][]float64, error) {
+	var mix func(ei, ej, si, sj float64) (eps, sigma float64)
+	var mixCut func(ci, cj float64) float64
+	switch rule {
+	case "geometric":
+		mix = func(ei, ej, si, sj float64) (float64, float64) {
+			return math.Sqrt(ei * ej), math.Sqrt(si * sj)
+		}
+		mixCut = func(ci, cj float64) float64 { return math.Sqrt(ci * cj) }
+	case "arithmetic":
+		mix = func(ei, ej, si, sj float64) (float64, float64) {
+			return math.Sqrt(ei * ej), (si + sj) / 2
+		}
+		mixCut = func(ci, cj float64) float64 { return (ci + cj) / 2 }
+	case "sixthpower":
+		mix = func(ei, ej, si, sj float64) (float64, float64) {
+			si3, sj3 := math.Pow(si, 3), math.Pow(sj, 3)
+			s6 := si3*si3 + sj3*sj3
+			return 2 * math.Sqrt(ei*ej) * si3 * sj3 / s6, math.Pow(s6/2, 1./6)
+		}
+		mixCut = func(ci, cj float64) float64 { return math.Pow((math.Pow(ci, 6)+math.Pow(cj, 6))/2, 1./6) }
+	default:
+		return nil, fmt.Errorf("mixing rule = %s is not supported: it must be geometric, arithmetic, or sixthpower", rule)
+	}
+
+	types := make([]int, 0, len(diagonal))
+	for typ, coeffs := range diagonal {
+		if len(coeffs) != 2 && len(coeffs) != 3 {
+			return nil, fmt.Errorf("type = %d has %d coefficients, want 2 (epsilon, sigma) or 3 (epsilon, sigma, cutoff)", typ, len(coeffs))
+		}
+		types = append(types, typ)
+	}
+	sort.Ints(types)
+
+	mixed := make(map[[2]int][]float64)
+	for a, i := range types {
+		mixed[[2]int{i, i}] = append([]float64(nil), diagonal[i]...)
+		for _, j := range types[a+1:] {
+			ci, cj := diagonal[i], diagonal[j]
+			if len(ci) != len(cj) {
+				return nil, fmt.Errorf("types = %d and %d cannot be mixed: only one of them has a cutoff", i, j)
+			}
+			eps, sigma := mix(ci[0], cj[0], ci[1], cj[1])
+			coeffs := []float64{eps, sigma}
+			if len(ci) == 3 {
+				coeffs = append(coeffs, mixCut(ci[2], cj[2]))
+			}
+			mixed[[2]int{i, j}] = coeffs
+		}
+	}
+	return mixed, nil
+}
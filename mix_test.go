@@ -0,0 +1,104 @@
+package lmpsdat
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestMixPairCoeffs(t *testing.T) {
+	diagonal := map[int][]float64{1: {0.1, 3}, 2: {0.4, 4}}
+	withCutoff := map[int][]float64{1: {0.1, 3, 10}, 2: {0.4, 4, 12}}
+	tests := []struct {
+		name     string
+		diagonal map[int][]float64
+		rule     string
+		want     map[[2]int][]float64
+		wantErr  bool
+	}{
+		{"geometric", diagonal, "geometric", map[[2]int][]float64{{1, 1}: {0.1, 3}, {1, 2}: {0.2, 3.4641016151377544}, {2, 2}: {0.4, 4}}, false},
+		{"Lorentz-Berthelot", diagonal, "arithmetic", map[[2]int][]float64{{1, 1}: {0.1, 3}, {1, 2}: {0.2, 3.5}, {2, 2}: {0.4, 4}}, false},
+		{"sixthpower", diagonal, "sixthpower", map[[2]int][]float64{{1, 1}: {0.1, 3}, {1, 2}: {0.1432538860103627, 3.662221042366007}, {2, 2}: {0.4, 4}}, false},
+		{"geometric cutoff", withCutoff, "geometric", map[[2]int][]float64{{1, 1}: {0.1, 3, 10}, {1, 2}: {0.2, 3.4641016151377544, 10.954451150103322}, {2, 2}: {0.4, 4, 12}}, false},
+		{"Lorentz-Berthelot cutoff", withCutoff, "arithmetic", map[[2]int][]float64{{1, 1}: {0.1, 3, 10}, {1, 2}: {0.2, 3.5, 11}, {2, 2}: {0.4, 4, 12}}, false},
+		{"sixthpower cutoff", withCutoff, "sixthpower", map[[2]int][]float64{{1, 1}: {0.1, 3, 10}, {1, 2}: {0.1432538860103627, 3.662221042366007, 11.21805571362643}, {2, 2}: {0.4, 4, 12}}, false},
+		{"three types", map[int][]float64{1: {0.1, 3}, 2: {0.4, 4}, 4: {0.9, 2}}, "arithmetic", map[[2]int][]float64{
+			{1, 1}: {0.1, 3},
+			{1, 2}: {0.2, 3.5},
+			{1, 4}: {0.3, 2.5},
+			{2, 2}: {0.4, 4},
+			{2, 4}: {0.6, 3},
+			{4, 4}: {0.9, 2},
+		}, false},
+		{"one type", map[int][]float64{1: {0.1, 3}}, "arithmetic", map[[2]int][]float64{{1, 1}: {0.1, 3}}, false},
+		{"unknown rule", diagonal, "harmonic", nil, true},
+		{"one cutoff", map[int][]float64{1: {0.1, 3}, 2: {0.4, 4, 12}}, "arithmetic", nil, true},
+		{"not lj", map[int][]float64{1: {0.1}, 2: {0.4, 4}}, "arithmetic", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MixPairCoeffs(tt.diagonal, tt.rule)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("MixPairCoeffs = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("MixPairCoeffs = %v, want %v", got, tt.want)
+			}
+			for pair, want := range tt.want {
+				coeffs := got[pair]
+				if len(coeffs) != len(want) {
+					t.Errorf("pair = %v: coefficients = %v, want %v", pair, coeffs, want)
+					continue
+				}
+				for i := range want {
+					if math.Abs(coeffs[i]-want[i]) > 1e-12 {
+						t.Errorf("pair = %v: coefficients = %v, want %v", pair, coeffs, want)
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEncodePairIJCoeffs(t *testing.T) {
+	var v struct {
+		AtomTypes int                  `lmpsdat:"atom types"`
+		Pair      map[int][]float64    `lmpsdat:"Pair Coeffs"`
+		PairIJ    map[[2]int][]float64 `lmpsdat:"PairIJ Coeffs"`
+	}
+	v.Pair = map[int][]float64{1: {0.1, 3}, 2: {0.4, 4}}
+	var err error
+	v.PairIJ, err = MixPairCoeffs(v.Pair, "arithmetic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := encodeString(t, &v)
+	const want = "\n\n2 atom types\n\nPair Coeffs\n\n1 0.1 3\n2 0.4 4\n\nPairIJ Coeffs\n\n1 1 0.1 3\n1 2 0.2 3.5\n2 2 0.4 4\n\n"
+	if out != want {
+		t.Errorf("Encode = %q, want %q", out, want)
+	}
+
+	var v2 struct {
+		AtomTypes int                  `lmpsdat:"atom types"`
+		PairIJ    map[[2]int][]float64 `lmpsdat:"PairIJ Coeffs"`
+	}
+	if err := decodeString(out, &v2); err != nil {
+		t.Fatal(err)
+	}
+	if len(v2.PairIJ) != 3 || v2.PairIJ[[2]int{1, 2}][1] != 3.5 {
+		t.Errorf("PairIJ = %v", v2.PairIJ)
+	}
+
+	delete(v.PairIJ, [2]int{2, 2}) // the diagonal is required
+	var b strings.Builder
+	if err := NewEncoder(&b).Encode(&v); err == nil {
+		t.Error("Encode = nil without the pair 2 2")
+	}
+}
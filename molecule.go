@@ -0,0 +1,102 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// WriteMolecule writes the atoms and the links of a single molecule using the
+// molecule file format of LAMMPS (see the molecule command in the LAMMPS
+// documentation). It is a different format than the data file: the title is
+// followed by the counts, then the Coords, Types, and Charges sections, and the
+// Bonds, Angles, and Dihedrals sections. bonds, angles, and dihedrals can be
+// nil.
+//
+// The atoms and the links are renumbered from one in increasing order of
+// identifier as required by the format. An error is returned if a link
+// references an atom that is not in atoms.
+func WriteMolecule(w io.Writer, title string, atoms map[int]*key.Atom, bonds, angles, dihedrals map[int]*key.Link) error {
+	ids := atomIDs(atoms)
+	remap := make(map[int]int, len(ids))
+	for i, id := range ids {
+		if atoms[id] == nil {
+			return fmt.Errorf("atom = %d is nil", id)
+		}
+		remap[id] = i + 1
+	}
+
+	tables := []struct {
+		name  key.Name
+		count string
+		links map[int]*key.Link
+	}{
+		{key.NameBonds, "bonds", bonds},
+		{key.NameAngles, "angles", angles},
+		{key.NameDihedrals, "dihedrals", dihedrals},
+	}
+	for _, t := range tables {
+		for id, link := range t.links {
+			if link == nil {
+				return fmt.Errorf("link = %d of %s is nil", id, t.name)
+			}
+			for _, atom := range link.Atoms() {
+				if _, ok := remap[atom]; !ok {
+					return fmt.Errorf("atom = %d of link = %d of %s is not in the molecule", atom, id, t.name)
+				}
+			}
+		}
+	}
+
+	if _, err := fmt.Fprintf(w, "%s\n\n%d atoms\n", title, len(ids)); err != nil {
+		return fmt.Errorf("fmt.Fprintf header: %w", err)
+	}
+	for _, t := range tables {
+		if len(t.links) > 0 {
+			fmt.Fprintf(w, "%d %s\n", len(t.links), t.count)
+		}
+	}
+
+	sections := []struct {
+		name   string
+		format func(a *key.Atom) string
+	}{
+		{"Coords", func(a *key.Atom) string { return fmt.Sprintf("%g %g %g", a.X, a.Y, a.Z) }},
+		{"Types", func(a *key.Atom) string { return fmt.Sprintf("%d", a.AtomType) }},
+		{"Charges", func(a *key.Atom) string { return fmt.Sprintf("%g", a.Q) }},
+	}
+	for _, s := range sections {
+		fmt.Fprintf(w, "\n%s\n\n", s.name)
+		for i, id := range ids {
+			if _, err := fmt.Fprintf(w, "%d %s\n", i+1, s.format(atoms[id])); err != nil {
+				return fmt.Errorf("fmt.Fprintf %s: %w", s.name, err)
+			}
+		}
+	}
+
+	for _, t := range tables {
+		if len(t.links) == 0 {
+			continue
+		}
+		linkIDs := make([]int, 0, len(t.links))
+		for id := range t.links {
+			linkIDs = append(linkIDs, id)
+		}
+		sort.Ints(linkIDs)
+
+		fmt.Fprintf(w, "\n%s\n\n", t.name)
+		for i, id := range linkIDs {
+			link := t.links[id]
+			if _, err := fmt.Fprintf(w, "%d %d", i+1, link.Type()); err != nil {
+				return fmt.Errorf("fmt.Fprintf %s: %w", t.name, err)
+			}
+			for _, atom := range link.Atoms() {
+				fmt.Fprintf(w, " %d", remap[atom])
+			}
+			fmt.Fprint(w, "\n")
+		}
+	}
+	return nil
+}
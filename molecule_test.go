@@ -0,0 +1,27 @@
+package lmpsdat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestWriteMolecule(t *testing.T) {
+	s := fullSystem(t)
+	atoms := map[int]*key.Atom{4: s.Atoms[4], 5: s.Atoms[5], 6: s.Atoms[6]}
+	bonds := map[int]*key.Link{3: s.Bonds[3], 4: s.Bonds[4]}
+	angles := map[int]*key.Link{2: s.Angles[2]}
+
+	var b strings.Builder
+	if err := WriteMolecule(&b, "water", atoms, bonds, angles, nil); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "water.mol", b.String())
+
+	// the bond references the atom = 1 of the other molecule.
+	bonds[1] = s.Bonds[1]
+	if err := WriteMolecule(&strings.Builder{}, "water", atoms, bonds, angles, nil); err == nil {
+		t.Error("WriteMolecule = nil with an atom that is not in the molecule")
+	}
+}
@@ -0,0 +1,299 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// linkNames contains the Names of the Links tables.
+var linkNames = []key.Name{key.NameBonds, key.NameAngles, key.NameDihedrals}
+
+// bonusNames contains the Names of the Bonus tables. Their values are keyed by
+// the identifiers of the atoms.
+var bonusNames = []key.Name{key.NameLines, key.NameTriangles}
+
+// countOf links the Names of the tables to the Names of the Headers containing
+// their number of values.
+var countOf = map[key.Name]key.Name{
+	key.NameAtoms:     key.NameAtomsNbr,
+	key.NameLines:     key.NameLinesNbr,
+	key.NameTriangles: key.NameTrianglesNbr,
+	key.NameBonds:     key.NameBondsNbr,
+	key.NameAngles:    key.NameAnglesNbr,
+	key.NameDihedrals: key.NameDihedralsNbr,
+}
+
+// Renumber renumbers the atoms of the struct pointed to by v so that their
+// identifiers are contiguous from one while keeping their order. The atoms
+// referenced by the links of the fields tagged with NameBonds, NameAngles, and
+// NameDihedrals are updated and the identifiers of the links are renumbered
+// the same way. The values of the fields tagged with NameLines and
+// NameTriangles follow their atom. The struct must have a field tagged with
+// NameAtoms.
+//
+// It returns a map linking the previous identifiers of the atoms to the new
+// ones. v is not modified if an error is returned, e.g. if a link or a value of
+// the Lines table references an atom that does not exist.
+func Renumber(v interface{}) (map[int]int, error) {
+	f, err := fields(v)
+	if err != nil {
+		return nil, err
+	}
+	atoms, ok, err := atomsOf(f)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("field with Name = %s is missing", key.NameAtoms)
+	}
+
+	remap := make(map[int]int, len(atoms))
+	newAtoms := make(map[int]*key.Atom, len(atoms))
+	for i, id := range atomIDs(atoms) {
+		remap[id] = i + 1
+		newAtoms[i+1] = atoms[id]
+	}
+
+	renumbered := make(map[key.Name]map[int]*key.Link)
+	for _, name := range linkNames {
+		links, ok, err := linksOf(f, name)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		ids := make([]int, 0, len(links))
+		for id, link := range links {
+			if link == nil {
+				return nil, fmt.Errorf("link = %d of %s is nil", id, name)
+			}
+			for _, atom := range link.Atoms() {
+				if _, ok := remap[atom]; !ok {
+					return nil, fmt.Errorf("atom = %d of link = %d of %s does not exist", atom, id, name)
+				}
+			}
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		newLinks := make(map[int]*key.Link, len(links))
+		for i, id := range ids {
+			newLinks[i+1] = links[id]
+		}
+		renumbered[name] = newLinks
+	}
+
+	bonus := make(map[key.Name]map[int][]float64)
+	for _, name := range bonusNames {
+		values, ok, err := bonusOf(f, name)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		newValues := make(map[int][]float64, len(values))
+		for id, val := range values {
+			newID, ok := remap[id]
+			if !ok {
+				return nil, fmt.Errorf("atom = %d of %s does not exist", id, name)
+			}
+			newValues[newID] = val
+		}
+		bonus[name] = newValues
+	}
+
+	// v is modified once every value is renumbered without error.
+	for name, values := range bonus {
+		if err := setField(f, name, values); err != nil {
+			return nil, err
+		}
+	}
+	for name, links := range renumbered {
+		for _, link := range links {
+			a := link.Atoms()
+			for i := range a {
+				a[i] = remap[a[i]]
+			}
+		}
+		if err := setField(f, name, links); err != nil {
+			return nil, err
+		}
+	}
+	if err := setField(f, key.NameAtoms, newAtoms); err != nil {
+		return nil, err
+	}
+	return remap, nil
+}
+
+// NormalizeForLAMMPS modifies the struct pointed to by v so that it can be read
+// by LAMMPS. The struct must have a field tagged with NameAtoms. It:
+//
+//   - renumbers the atoms and the links with Renumber if their identifiers are
+//     not contiguous from one;
+//   - sets the fields containing the number of values (e.g. NameAtomsNbr) to
+//     the number of values of their table;
+//   - sets the fields containing the number of types (e.g. NameAtomTypes) to
+//     the largest type used if it is lower;
+//   - sets the box of the fields tagged with NameBoxX, NameBoxY, and NameBoxZ
+//     to the extents of the atoms if its length is not greater than zero. A
+//     margin of 0.5 is added on each side if all the atoms have the same
+//     coordinate.
+//
+// It returns a description of each change that was made. The fields are read
+// and set as by Decode: an error is returned if one of them cannot be converted
+// to the type of its Key (e.g. a Header field of kind string).
+func NormalizeForLAMMPS(v interface{}) ([]string, error) {
+	var report []string
+	f, err := fields(v)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := f[key.NameAtoms]; !ok {
+		return nil, fmt.Errorf("field with Name = %s is missing", key.NameAtoms)
+	}
+
+	if ok, err := contiguous(f); err != nil {
+		return nil, err
+	} else if !ok {
+		if _, err := Renumber(v); err != nil {
+			return nil, err
+		}
+		report = append(report, "identifiers renumbered from one")
+	}
+
+	atoms, _, err := atomsOf(f)
+	if err != nil {
+		return nil, err
+	}
+	for table, count := range countOf {
+		n, ok, err := tableLen(f, table)
+		if err != nil {
+			return nil, err
+		}
+		c, ok2, err := intOf(f, count)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || !ok2 || c == n {
+			continue
+		}
+		report = append(report, fmt.Sprintf("%s set from %d to %d", count, c, n))
+		if err := setField(f, count, n); err != nil {
+			return nil, err
+		}
+	}
+
+	maxTypes := map[key.Name]int{}
+	for _, atom := range atoms {
+		if atom != nil && atom.AtomType > maxTypes[key.NameAtomTypes] {
+			maxTypes[key.NameAtomTypes] = atom.AtomType
+		}
+	}
+	for _, name := range linkNames {
+		links, _, err := linksOf(f, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, link := range links {
+			if link != nil && link.Type() > maxTypes[typesOf[name]] {
+				maxTypes[typesOf[name]] = link.Type()
+			}
+		}
+	}
+	for name, max := range maxTypes {
+		types, ok, err := intOf(f, name)
+		if err != nil {
+			return nil, err
+		} else if !ok || types >= max {
+			continue
+		}
+		report = append(report, fmt.Sprintf("%s set from %d to %d", name, types, max))
+		if err := setField(f, name, max); err != nil {
+			return nil, err
+		}
+	}
+
+	all, _ := Extents(atoms)
+	for i, name := range []key.Name{key.NameBoxX, key.NameBoxY, key.NameBoxZ} {
+		v, ok, err := fieldOf(f, name)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		b := v.([2]float64)
+		if b[0] < b[1] {
+			continue
+		}
+		nb := all[i]
+		if nb[0] == nb[1] {
+			nb[0], nb[1] = nb[0]-0.5, nb[1]+0.5
+		}
+		report = append(report, fmt.Sprintf("%s set from %g %g to %g %g", name, b[0], b[1], nb[0], nb[1]))
+		if err := setField(f, name, nb); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Strings(report)
+	return report, nil
+}
+
+// bonusOf returns the values of the Bonus table of the fields f whose Name is
+// name. It returns false if there is no such field.
+func bonusOf(f map[key.Name]reflect.Value, name key.Name) (map[int][]float64, bool, error) {
+	v, ok, err := fieldOf(f, name)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	return v.(map[int][]float64), true, nil
+}
+
+// setCounts sets the fields of f containing the number of values of a table
+// (e.g. NameAtomsNbr) to the length of their table.
+func setCounts(f map[key.Name]reflect.Value) error {
+	for table, c := range countOf {
+		n, ok, err := tableLen(f, table)
+		if err != nil {
+			return err
+		}
+		if _, ok2 := f[c]; !ok || !ok2 {
+			continue
+		}
+		if err := setField(f, c, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contiguous returns true if the identifiers of the atoms and of the links of
+// the fields f are contiguous from one. The values of the Lines and Triangles
+// tables must reference these atoms.
+func contiguous(f map[key.Name]reflect.Value) (bool, error) {
+	atoms, _, err := tableLen(f, key.NameAtoms)
+	if err != nil {
+		return false, err
+	}
+	for _, name := range append(append([]key.Name{key.NameAtoms}, linkNames...), bonusNames...) {
+		v, ok, err := fieldOf(f, name)
+		if err != nil {
+			return false, err
+		} else if !ok {
+			continue
+		}
+		m := reflect.ValueOf(v)
+		n := m.Len()
+		if _, ok := bonusAxes[name]; ok {
+			n = atoms // the values are keyed by the identifiers of the atoms
+		}
+		iter := m.MapRange()
+		for iter.Next() {
+			if id := int(iter.Key().Int()); id < 1 || id > n {
+				return false, nil
+			}
+		}
+	}
+	return true, nil
+}
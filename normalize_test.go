@@ -0,0 +1,100 @@
+package lmpsdat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestNormalizeForLAMMPS(t *testing.T) {
+	type messy struct {
+		AtomsNbr  int               `lmpsdat:"atoms"`
+		AtomTypes int               `lmpsdat:"atom types"`
+		BondsNbr  int               `lmpsdat:"bonds"`
+		BondTypes int               `lmpsdat:"bond types"`
+		X         [2]float64        `lmpsdat:"xlo xhi"`
+		Y         [2]float64        `lmpsdat:"ylo yhi"`
+		Z         [2]float64        `lmpsdat:"zlo zhi"`
+		Atoms     map[int]*key.Atom `lmpsdat:"Atoms, full"`
+		Bonds     map[int]*key.Link `lmpsdat:"Bonds"`
+	}
+	v := messy{
+		AtomsNbr: 1,
+		X:        [2]float64{0, 10},
+		Atoms: map[int]*key.Atom{
+			10: {MolTag: 1, AtomType: 1, X: 1, Y: 2, Z: 3},
+			30: {MolTag: 1, AtomType: 2, X: 2, Y: 4, Z: 3},
+			20: {MolTag: 1, AtomType: 2, X: 3, Y: 6, Z: 3},
+		},
+		Bonds: map[int]*key.Link{5: key.NewLink(1, 10, 30), 9: key.NewLink(1, 10, 20)},
+	}
+	report, err := NormalizeForLAMMPS(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"atom types set from 0 to 2",
+		"atoms set from 1 to 3",
+		"bond types set from 0 to 1",
+		"bonds set from 0 to 2",
+		"identifiers renumbered from one",
+		"ylo yhi set from 0 0 to 2 6",
+		"zlo zhi set from 0 0 to 2.5 3.5",
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("report = %q, want %q", report, want)
+	}
+	if v.Atoms[2].X != 3 || v.Atoms[3].X != 2 {
+		t.Errorf("atoms are not renumbered in order: %+v, %+v", *v.Atoms[2], *v.Atoms[3])
+	}
+	if a := v.Bonds[1].Atoms(); a[0] != 1 || a[1] != 3 {
+		t.Errorf("bond 1 = %v, want [1 3]", a)
+	}
+
+	// the result is a valid LAMMPS data file.
+	out := encodeString(t, &v)
+	var v2 messy
+	if err := decodeString(out, &v2); err != nil {
+		t.Errorf("Decode of the normalized system: %v\n%s", err, out)
+	}
+
+	report, err = NormalizeForLAMMPS(&v)
+	if err != nil || len(report) != 0 {
+		t.Errorf("second NormalizeForLAMMPS = %q, %v, want no change", report, err)
+	}
+}
+
+func TestRenumber(t *testing.T) {
+	v := lines{
+		Atoms: map[int]*key.Atom{
+			7:  {MolTag: 1, AtomType: 1, Flag: 1, Density: 1},
+			3:  {MolTag: 1, AtomType: 1, Density: 1},
+			12: {MolTag: 1, AtomType: 1, Flag: 1, Density: 1},
+		},
+		Lines: map[int][]float64{7: {0, 0, 1, 0}, 12: {0, 1, 1, 1}},
+	}
+	remap, err := Renumber(&v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]int{3: 1, 7: 2, 12: 3}; !reflect.DeepEqual(remap, want) {
+		t.Errorf("remap = %v, want %v", remap, want)
+	}
+	if want := map[int][]float64{2: {0, 0, 1, 0}, 3: {0, 1, 1, 1}}; !reflect.DeepEqual(v.Lines, want) {
+		t.Errorf("Lines = %v, want %v", v.Lines, want)
+	}
+
+	v.Lines[4] = []float64{0, 0, 0, 1}
+	if _, err := Renumber(&v); err == nil {
+		t.Error("Renumber = nil with a line of an atom that does not exist")
+	}
+	if len(v.Lines) != 3 || v.Lines[4] == nil {
+		t.Errorf("Lines = %v is modified despite the error", v.Lines)
+	}
+
+	// the lines must reference the atoms: the identifiers are not contiguous.
+	if _, err := NormalizeForLAMMPS(&v); err == nil {
+		t.Error("NormalizeForLAMMPS = nil with a line of an atom that does not exist")
+	}
+}
@@ -0,0 +1,185 @@
+package lmpsdat
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// Passthrough contains the original bytes of a LAMMPS data file decoded with
+// the DecodePassthrough method. It is used by the EncodePassthrough method to
+// write the tables and headers that were not modified exactly as they were read
+// (whitespace, comments, number formatting, and the sections that were not
+// decoded are preserved).
+//
+// The whole file is kept in memory.
+type Passthrough struct {
+	buf      []byte
+	last     int // offset of the last line read
+	end      int // offset of the end of the last segment
+	segments []segment
+}
+
+// segment is a part of the original file. name is empty if the segment does not
+// belong to a table or a header (e.g. blank lines, comments, skipped sections).
+// canon contains the bytes written by the Encode method of the Key right after
+// decoding: it is used to detect whether the Key was modified.
+type segment struct {
+	name  key.Name
+	raw   []byte
+	canon []byte
+}
+
+// record retains the bytes of the line b. It does nothing if p is nil.
+func (p *Passthrough) record(b []byte) {
+	if p == nil {
+		return
+	}
+	p.last = len(p.buf)
+	p.buf = append(p.buf, b...)
+}
+
+// unread removes the last line read. It is used to discard the FrameSeparator.
+// It does nothing if p is nil.
+func (p *Passthrough) unread() {
+	if p == nil {
+		return
+	}
+	p.buf = p.buf[:p.last]
+}
+
+// lineStart returns the offset of the last line read. It returns zero if p is
+// nil.
+func (p *Passthrough) lineStart() int {
+	if p == nil {
+		return 0
+	}
+	return p.last
+}
+
+// add creates a segment for the Key whose Name is name. The segment starts at
+// the offset start and ends at the last byte read. The bytes between the
+// previous segment and start are put into an unnamed segment. It does nothing
+// if p is nil.
+func (p *Passthrough) add(name key.Name, start int) {
+	if p == nil {
+		return
+	}
+	if start > p.end {
+		p.segments = append(p.segments, segment{raw: p.buf[p.end:start]})
+	}
+	p.segments = append(p.segments, segment{name: name, raw: p.buf[start:]})
+	p.end = len(p.buf)
+}
+
+// finish puts the remaining bytes into an unnamed segment and encodes the Keys
+// of the segments. It does nothing if p is nil.
+func (p *Passthrough) finish(keys map[key.Name]key.Key) error {
+	if p == nil {
+		return nil
+	}
+	if len(p.buf) > p.end {
+		p.segments = append(p.segments, segment{raw: p.buf[p.end:]})
+		p.end = len(p.buf)
+	}
+	for i, seg := range p.segments {
+		if seg.name == "" {
+			continue
+		}
+		k := keys[seg.name]
+		if c, ok := k.(interface{ SetComments(map[int]string) }); ok {
+			// the comments of the Key are not kept in v: they are not
+			// written back by EncodePassthrough.
+			c.SetComments(nil)
+		}
+		if c, ok := k.(interface{ SetComment(string) }); ok {
+			c.SetComment("")
+		}
+		var b bytes.Buffer
+		if err := k.Encode(&b); err != nil {
+			return fmt.Errorf("k.Encode for Key = %s: %w", seg.name, err)
+		}
+		p.segments[i].canon = b.Bytes()
+	}
+	return nil
+}
+
+// DecodePassthrough works like Decode but also returns the original bytes of
+// the file. Passing them to the EncodePassthrough method of an Encoder writes
+// the file again with only the modified tables and headers re-formatted.
+func (dec *Decoder) DecodePassthrough(v interface{}) (*Passthrough, error) {
+	p := &Passthrough{}
+	if err := dec.decode(v, false, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// EncodePassthrough writes the LAMMPS data of v to the stream by reusing the
+// original bytes in p. The tables and headers whose values are unchanged since
+// DecodePassthrough, the fields of v that are not tagged, and everything that
+// was not decoded (blank lines, comments, skipped sections) are written
+// verbatim. The modified tables and headers are written as the Encode method
+// does, at their original position.
+//
+// The tables of v that are not in the original file are written at the end. An
+// error is returned if a header of v that is not in the original file is not
+// equal to zero, as it cannot be placed among the other headers.
+func (enc *Encoder) EncodePassthrough(v interface{}, p *Passthrough) error {
+	if p == nil {
+		return fmt.Errorf("Passthrough is nil: use the DecodePassthrough method")
+	}
+	keys, err := enc.setKeys(v)
+	if err != nil {
+		return err
+	}
+
+	written := make(map[key.Name]bool)
+	for _, seg := range p.segments {
+		k, ok := keys[seg.name]
+		if seg.name == "" || !ok {
+			if _, err := enc.w.Write(seg.raw); err != nil {
+				return fmt.Errorf("enc.w.Write: %w", err)
+			}
+			continue
+		}
+		written[seg.name] = true
+
+		var b bytes.Buffer
+		if err := k.Encode(&b); err != nil {
+			return fmt.Errorf("k.Encode for Key = %s: %w", seg.name, err)
+		}
+		out := b.Bytes()
+		if bytes.Equal(out, seg.canon) {
+			out = seg.raw
+		}
+		if _, err := enc.w.Write(out); err != nil {
+			return fmt.Errorf("enc.w.Write for Key = %s: %w", seg.name, err)
+		}
+	}
+
+	for n, k := range keys {
+		if written[n] || !key.IsHeader(k) || n == key.NameTitle {
+			continue
+		}
+		if h, ok := k.(*key.Header); ok && h.Get().(int) == 0 {
+			continue
+		}
+		return fmt.Errorf("Key = %s is not in the original data and cannot be written", n)
+	}
+	for _, n := range key.ListSections {
+		k, ok := keys[n]
+		if !ok || written[n] {
+			continue
+		}
+		if l, ok := k.(lener); ok && l.Len() == 0 {
+			continue
+		}
+		fmt.Fprint(enc.w, "\n")
+		if err := enc.encodeKey(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package lmpsdat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPassthrough(t *testing.T) {
+	in := readFile(t, "passthrough.data")
+	tests := []struct {
+		name   string
+		modify func(s *system)
+		want   string
+	}{
+		{"unchanged", func(s *system) {}, in},
+		{"box", func(s *system) { s.X[1] = 20 }, strings.Replace(in, "0.000000 10.000000 xlo xhi", "0 20 xlo xhi", 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s system
+			p, err := NewDecoder(strings.NewReader(in)).DecodePassthrough(&s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&s)
+			var b bytes.Buffer
+			if err := NewEncoder(&b).EncodePassthrough(&s, p); err != nil {
+				t.Fatal(err)
+			}
+			got, want := strings.Split(b.String(), "\n"), strings.Split(tt.want, "\n")
+			if len(got) != len(want) {
+				t.Fatalf("EncodePassthrough = %d lines, want %d:\n%s", len(got), len(want), b.String())
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Errorf("line %d = %q, want %q", i+1, got[i], want[i])
+				}
+			}
+		})
+	}
+}
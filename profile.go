@@ -0,0 +1,133 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// VersionProfile is a set of requirements that a LAMMPS data file must satisfy
+// to be read by a given version of LAMMPS (or by another tool). It is used by
+// ValidateProfile. The zero value does not require anything more than
+// Validate.
+type VersionProfile struct {
+	// Name identifies the profile in the errors.
+	Name string
+
+	// ContiguousIDs requires the identifiers of the Atoms, Bonds, Angles,
+	// and Dihedrals tables to be between one and their number of values, as
+	// some old versions of LAMMPS do.
+	ContiguousIDs bool
+
+	// RequireBox requires the struct to have the fields tagged with
+	// NameBoxX, NameBoxY, and NameBoxZ, and each box to have a length
+	// greater than zero. LAMMPS uses a default box otherwise.
+	RequireBox bool
+
+	// RequireMasses requires a mass for each atom type used by the Atoms
+	// table, i.e. the masses cannot be set by the input script.
+	RequireMasses bool
+
+	// MoleculeTopology requires the molecule tags of the atoms to match the
+	// bond connectivity (see ValidateMolecules). It is verified only if the
+	// struct has the fields tagged with NameAtoms and NameBonds.
+	MoleculeTopology bool
+}
+
+// ProfileLegacy is the profile of the old versions of LAMMPS and of the tools
+// requiring a self-contained file: the identifiers are contiguous, and the box
+// and the masses are in the file.
+var ProfileLegacy = VersionProfile{
+	Name:          "legacy",
+	ContiguousIDs: true,
+	RequireBox:    true,
+	RequireMasses: true,
+}
+
+// ProfileCurrent is the profile of the current versions of LAMMPS. It does not
+// require anything more than Validate.
+var ProfileCurrent = VersionProfile{
+	Name: "current",
+}
+
+// ValidateProfile works like Validate but also verifies the requirements of
+// the profile p. The error returned is prefixed by the Name of the profile if
+// a requirement is not satisfied.
+func ValidateProfile(v interface{}, p VersionProfile) error {
+	if err := Validate(v); err != nil {
+		return err
+	}
+	f, err := fields(v)
+	if err != nil {
+		return err
+	}
+	if p.ContiguousIDs {
+		for _, name := range append([]key.Name{key.NameAtoms}, linkNames...) {
+			v, ok, err := fieldOf(f, name)
+			if err != nil {
+				return err
+			} else if !ok {
+				continue
+			}
+			m := reflect.ValueOf(v)
+			iter := m.MapRange()
+			for iter.Next() {
+				if id := int(iter.Key().Int()); id < 1 || id > m.Len() {
+					return fmt.Errorf("profile = %s: identifier = %d of %s is not between 1 and the number of values = %d", p.Name, id, name, m.Len())
+				}
+			}
+		}
+	}
+	if p.RequireBox {
+		for _, name := range []key.Name{key.NameBoxX, key.NameBoxY, key.NameBoxZ} {
+			v, ok, err := fieldOf(f, name)
+			if err != nil {
+				return fmt.Errorf("profile = %s: %w", p.Name, err)
+			} else if !ok {
+				return fmt.Errorf("profile = %s: field with Name = %s is missing", p.Name, name)
+			}
+			if box := v.([2]float64); box[0] >= box[1] {
+				return fmt.Errorf("profile = %s: box %s = %v has no length", p.Name, name, box)
+			}
+		}
+	}
+	if p.RequireMasses {
+		v, ok, err := fieldOf(f, key.NameMasses)
+		if err != nil {
+			return fmt.Errorf("profile = %s: %w", p.Name, err)
+		} else if !ok {
+			return fmt.Errorf("profile = %s: field with Name = %s is missing", p.Name, key.NameMasses)
+		}
+		masses := v.(map[int]float64)
+		atoms, ok, err := atomsOf(f)
+		if err != nil {
+			return err
+		} else if ok {
+			for _, id := range atomIDs(atoms) {
+				if atoms[id] == nil {
+					return fmt.Errorf("atom = %d is nil", id)
+				}
+				if _, ok := masses[atoms[id].AtomType]; !ok {
+					return fmt.Errorf("profile = %s: mass of type = %d of atom = %d is missing", p.Name, atoms[id].AtomType, id)
+				}
+			}
+		}
+	}
+	if p.MoleculeTopology {
+		atoms, ok, err := atomsOf(f)
+		if err != nil {
+			return err
+		}
+		bonds, ok2, err := linksOf(f, key.NameBonds)
+		if err != nil {
+			return err
+		}
+		if ok && ok2 {
+			if err := ValidateMolecules(atoms, bonds); err != nil {
+				return fmt.Errorf("profile = %s: %w", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
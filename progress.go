@@ -0,0 +1,53 @@
+package lmpsdat
+
+import (
+	"io"
+)
+
+// progressBytes is the number of bytes read between two calls of the progress
+// callback set with SetProgress.
+const progressBytes = 64 * 1024
+
+// progressReader counts the bytes read from r and calls fn every progressBytes
+// bytes and at the end of the input.
+type progressReader struct {
+	r     io.Reader
+	fn    func(read, total int64)
+	total int64
+	read  int64
+	next  int64 // number of bytes read at the next call of fn
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.next != -1 && (p.read >= p.next || err == io.EOF) {
+		p.fn(p.read, p.total)
+		p.next = p.read + progressBytes
+		if err == io.EOF {
+			p.next = -1 // fn is called only once at the end of the input
+		}
+	}
+	return n, err
+}
+
+// SetProgress sets a function that is called periodically with the number of
+// bytes read from the input, e.g. to display a progress bar. total is passed
+// unchanged to fn: it can be the size of the input, or zero if it is not
+// known. fn is called every 64 KiB and once at the end of the input, with an
+// increasing number of bytes. For a compressed input, the compressed bytes are
+// counted.
+//
+// SetProgress must be called before the first call to Decode: it does nothing
+// afterwards. A nil fn removes the previous one.
+func (dec *Decoder) SetProgress(total int64, fn func(read, total int64)) {
+	if dec.scan != nil {
+		return
+	}
+	if p, ok := dec.r.(*progressReader); ok {
+		dec.r = p.r
+	}
+	if fn != nil {
+		dec.r = &progressReader{r: dec.r, fn: fn, total: total, next: progressBytes}
+	}
+}
@@ -0,0 +1,60 @@
+package lmpsdat
+
+import (
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodeProgress(t *testing.T) {
+	in := bigFile(20000)
+	tests := []struct {
+		name  string
+		total int64
+	}{
+		{"size known", int64(len(in))},
+		{"size unknown", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []int64
+			dec := NewDecoder(iotest.HalfReader(strings.NewReader(in)))
+			dec.SetProgress(tt.total, func(read, total int64) {
+				if total != tt.total {
+					t.Errorf("total = %d, want %d", total, tt.total)
+				}
+				calls = append(calls, read)
+			})
+			var v atomic
+			if err := dec.Decode(&v); err != nil {
+				t.Fatal(err)
+			}
+			if want := len(in)/progressBytes + 1; len(calls) < want {
+				t.Fatalf("fn called %d times, want at least %d", len(calls), want)
+			}
+			for i := 1; i < len(calls); i++ {
+				if calls[i] <= calls[i-1] {
+					t.Fatalf("read = %v is not increasing", calls)
+				}
+			}
+			if last := calls[len(calls)-1]; last != int64(len(in)) {
+				t.Errorf("last read = %d, want %d", last, len(in))
+			}
+		})
+	}
+
+	// fn is removed by nil and cannot be set after the first call to Decode.
+	called := false
+	dec := NewDecoder(strings.NewReader(in))
+	dec.SetProgress(0, func(int64, int64) { called = true })
+	dec.SetProgress(0, nil)
+	var v atomic
+	if err := dec.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	dec.SetProgress(0, func(int64, int64) { called = true })
+	dec.Decode(&v)
+	if called {
+		t.Error("fn called after being removed")
+	}
+}
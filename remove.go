@@ -0,0 +1,113 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// RemoveMolecule removes the atoms of the struct pointed to by v whose molecule
+// tag is molTag, as well as the links of the fields tagged with NameBonds,
+// NameAngles, and NameDihedrals that reference at least one of them and their
+// values of the fields tagged with NameLines and NameTriangles. The remaining
+// atoms, links, and values are then renumbered with Renumber, and the fields
+// containing their number (e.g. NameAtomsNbr) are updated. The struct must
+// have a field tagged with NameAtoms.
+//
+// It returns the number of values removed from each table. v is not modified
+// if an error is returned, e.g. if a link references an atom that does not
+// exist.
+func RemoveMolecule(v interface{}, molTag int) (map[key.Name]int, error) {
+	f, err := fields(v)
+	if err != nil {
+		return nil, err
+	}
+	atoms, ok, err := atomsOf(f)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, fmt.Errorf("field with Name = %s is missing", key.NameAtoms)
+	}
+
+	removed := make(map[int]bool)
+	for id, atom := range atoms {
+		if atom == nil {
+			return nil, fmt.Errorf("atom = %d is nil", id)
+		}
+		if atom.MolTag == molTag {
+			removed[id] = true
+		}
+	}
+
+	pruned := make(map[key.Name][]int)
+	links := make(map[key.Name]map[int]*key.Link)
+	for _, name := range linkNames {
+		l, ok, err := linksOf(f, name)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		links[name] = l
+		for id, link := range l {
+			if link == nil {
+				return nil, fmt.Errorf("link = %d of %s is nil", id, name)
+			}
+			prune := false
+			for _, atom := range link.Atoms() {
+				if _, ok := atoms[atom]; !ok {
+					return nil, fmt.Errorf("atom = %d of link = %d of %s does not exist", atom, id, name)
+				}
+				prune = prune || removed[atom]
+			}
+			if prune {
+				pruned[name] = append(pruned[name], id)
+			}
+		}
+	}
+
+	bonus := make(map[key.Name]map[int][]float64)
+	for _, name := range bonusNames {
+		values, ok, err := bonusOf(f, name)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		for id := range values {
+			if _, ok := atoms[id]; !ok {
+				return nil, fmt.Errorf("atom = %d of %s does not exist", id, name)
+			}
+			if removed[id] {
+				pruned[name] = append(pruned[name], id)
+			}
+		}
+		bonus[name] = values
+	}
+
+	// v is modified once every value is verified.
+	count := map[key.Name]int{key.NameAtoms: len(removed)}
+	for id := range removed {
+		delete(atoms, id)
+	}
+	for name, ids := range pruned {
+		if values, ok := bonus[name]; ok {
+			for _, id := range ids {
+				delete(values, id)
+			}
+		} else {
+			for _, id := range ids {
+				delete(links[name], id)
+			}
+		}
+		count[name] = len(ids)
+	}
+	if _, err := Renumber(v); err != nil {
+		return nil, err
+	}
+
+	if err := setCounts(f); err != nil {
+		return nil, err
+	}
+	return count, nil
+}
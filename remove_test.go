@@ -0,0 +1,127 @@
+package lmpsdat
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestRemoveMolecule(t *testing.T) {
+	tests := []struct {
+		name   string
+		molTag int
+		count  map[key.Name]int
+		atoms  map[int][3]float64 // coordinates of the remaining atoms
+		bonds  map[int][]int
+		angles map[int][]int
+	}{
+		{"first", 1, map[key.Name]int{key.NameAtoms: 3, key.NameBonds: 2, key.NameAngles: 1},
+			map[int][3]float64{1: {5, 5, 5}, 2: {5.8, 5.5, 5}, 3: {4.2, 5.5, 5}},
+			map[int][]int{1: {1, 2}, 2: {1, 3}}, map[int][]int{1: {2, 1, 3}}},
+		{"second", 2, map[key.Name]int{key.NameAtoms: 3, key.NameBonds: 2, key.NameAngles: 1},
+			map[int][3]float64{1: {1, 1, 1}, 2: {1.8, 1.5, 1}, 3: {0.2, 1.5, 1}},
+			map[int][]int{1: {1, 2}, 2: {1, 3}}, map[int][]int{1: {2, 1, 3}}},
+		{"missing", 3, map[key.Name]int{key.NameAtoms: 0},
+			map[int][3]float64{1: {1, 1, 1}, 2: {1.8, 1.5, 1}, 3: {0.2, 1.5, 1}, 4: {5, 5, 5}, 5: {5.8, 5.5, 5}, 6: {4.2, 5.5, 5}},
+			map[int][]int{1: {1, 2}, 2: {1, 3}, 3: {4, 5}, 4: {4, 6}}, map[int][]int{1: {2, 1, 3}, 2: {5, 4, 6}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			count, err := RemoveMolecule(s, tt.molTag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(count, tt.count) {
+				t.Errorf("removed = %v, want %v", count, tt.count)
+			}
+			if s.AtomsNbr != len(tt.atoms) || s.BondsNbr != len(tt.bonds) || s.AnglesNbr != len(tt.angles) {
+				t.Errorf("counts = %d atoms, %d bonds, %d angles, want %d, %d, %d", s.AtomsNbr, s.BondsNbr, s.AnglesNbr, len(tt.atoms), len(tt.bonds), len(tt.angles))
+			}
+			if len(s.Atoms) != len(tt.atoms) {
+				t.Fatalf("Atoms = %v, want %d atoms", s.Atoms, len(tt.atoms))
+			}
+			for id, want := range tt.atoms {
+				if a := s.Atoms[id]; a == nil || [3]float64{a.X, a.Y, a.Z} != want {
+					t.Errorf("atom = %d is %+v, want the coordinates %v", id, a, want)
+				}
+			}
+			for name, want := range map[key.Name]map[int][]int{key.NameBonds: tt.bonds, key.NameAngles: tt.angles} {
+				links := s.Bonds
+				if name == key.NameAngles {
+					links = s.Angles
+				}
+				got := make(map[int][]int, len(links))
+				for id, l := range links {
+					got[id] = l.Atoms()
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %v, want %v", name, got, want)
+				}
+			}
+			// the file is still valid.
+			var s2 system
+			if err := decodeString(encodeString(t, s), &s2); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestRemoveMoleculeLines(t *testing.T) {
+	v := lines{
+		AtomsNbr: 3, AtomTypes: 1, LinesNbr: 2,
+		X: [2]float64{0, 10}, Y: [2]float64{0, 10}, Z: [2]float64{-0.5, 0.5},
+		Atoms: map[int]*key.Atom{
+			1: {MolTag: 1, AtomType: 1, Flag: 1, Density: 1, X: 0.5},
+			2: {MolTag: 2, AtomType: 1, Flag: 1, Density: 1, X: 2.5},
+			3: {MolTag: 2, AtomType: 1, Density: 1, X: 4},
+		},
+		Lines: map[int][]float64{1: {0, 0, 1, 0}, 2: {2, 0, 3, 0}},
+	}
+	count, err := RemoveMolecule(&v, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (map[key.Name]int{key.NameAtoms: 1, key.NameLines: 1}); !reflect.DeepEqual(count, want) {
+		t.Errorf("removed = %v, want %v", count, want)
+	}
+	// the line of atom = 2 follows its atom.
+	if want := (map[int][]float64{1: {2, 0, 3, 0}}); !reflect.DeepEqual(v.Lines, want) {
+		t.Errorf("Lines = %v, want %v", v.Lines, want)
+	}
+	if v.AtomsNbr != 2 || v.LinesNbr != 1 || v.Atoms[1].X != 2.5 {
+		t.Errorf("%d atoms and %d lines with atom = 1 = %+v, want 2 and 1 with x = 2.5", v.AtomsNbr, v.LinesNbr, *v.Atoms[1])
+	}
+}
+
+func TestRemoveMoleculeInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *system)
+	}{
+		{"dangling bond", func(s *system) { s.Bonds[1] = key.NewLink(1, 1, 7) }},
+		{"nil atom", func(s *system) { s.Atoms[7] = nil }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			tt.modify(s)
+			if _, err := RemoveMolecule(s, 1); err == nil {
+				t.Fatal("RemoveMolecule = nil, want an error")
+			}
+			if len(s.Atoms) < 6 || len(s.Bonds) != 4 || len(s.Angles) != 2 {
+				t.Errorf("the system was modified: %d atoms, %d bonds, %d angles", len(s.Atoms), len(s.Bonds), len(s.Angles))
+			}
+		})
+	}
+
+	v := lines{AtomsNbr: 1, Atoms: map[int]*key.Atom{1: {MolTag: 1}}, Lines: map[int][]float64{2: {0, 0, 1, 0}}}
+	if _, err := RemoveMolecule(&v, 1); err == nil {
+		t.Error("RemoveMolecule = nil with a line of a missing atom")
+	}
+	if len(v.Atoms) != 1 {
+		t.Error("the atoms were removed despite the error")
+	}
+}
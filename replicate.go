@@ -0,0 +1,196 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// bonusAxes gives, for each Bonus table, the dimension (0 for x, 1 for y, and 2
+// for z) of each of its values: x1 y1 x2 y2 for the Lines table and the x, y,
+// and z coordinates of the three corners for the Triangles table.
+var bonusAxes = map[key.Name][]int{
+	key.NameLines:     {0, 1, 0, 1},
+	key.NameTriangles: {0, 1, 2, 0, 1, 2, 0, 1, 2},
+}
+
+// Replicate replicates the system stored in the struct pointed to by v nx, ny,
+// and nz times in the x, y, and z dimensions. The struct must have the fields
+// tagged with NameAtoms, NameBoxX, NameBoxY, and NameBoxZ. The fields tagged
+// with NameBonds, NameAngles, NameDihedrals, NameLines, and NameTriangles are
+// replicated if present.
+//
+// The atoms of each image are shifted by the lengths of the box and get new
+// identifiers and molecule tags: the identifiers of the image c are offset by c
+// times the largest identifier of the original system. The links are
+// replicated the same way with their additional columns and their comment, and
+// reference the atoms of their image. The values of the Lines and Triangles
+// tables follow their atom and their coordinates are shifted as the atoms.
+// Finally, the box is expanded and the fields containing the number of values
+// (e.g. NameAtomsNbr) are updated.
+//
+// As done by the replicate command of LAMMPS, the atoms having image flags
+// (see key.Atom.N) are shifted to their unwrapped coordinates in their image,
+// then wrapped into the expanded box and their image flags are updated. A
+// link crossing a periodic boundary of the original box thus links an atom to
+// the atom of the adjacent image instead of the atom of the opposite side of
+// its own image. The image flags are therefore required to replicate such a
+// link correctly.
+//
+// The box must be orthogonal: the tilt factors of a triclinic box are not
+// supported by this package.
+func Replicate(v interface{}, nx, ny, nz int) error {
+	if nx < 1 || ny < 1 || nz < 1 {
+		return fmt.Errorf("number of images = %dx%dx%d is invalid: each must be greater than zero", nx, ny, nz)
+	}
+	box, err := GetBox(v)
+	if err != nil {
+		return err
+	}
+	f, err := fields(v)
+	if err != nil {
+		return err
+	}
+	atoms, ok, err := atomsOf(f)
+	if err != nil {
+		return err
+	} else if !ok {
+		return fmt.Errorf("field with Name = %s is missing", key.NameAtoms)
+	}
+
+	var maxID, maxMol int
+	for id, atom := range atoms {
+		if atom == nil {
+			return fmt.Errorf("atom = %d is nil", id)
+		}
+		if id > maxID {
+			maxID = id
+		}
+		if atom.MolTag > maxMol {
+			maxMol = atom.MolTag
+		}
+	}
+
+	l := box.Lengths()
+	dims := [3]int{nx, ny, nz}
+	n := nx * ny * nz
+	newAtoms := make(map[int]*key.Atom, len(atoms)*n)
+	shifts := make(map[int][3]float64, len(atoms)*n) // shift of each new atom
+	for c := 0; c < n; c++ {
+		cell := [3]int{c / (ny * nz), c / nz % ny, c % nz}
+		for id, atom := range atoms {
+			a := *atom
+			var shift [3]float64
+			img := [3]*int{&a.NX, &a.NY, &a.NZ}
+			for k := range shift {
+				if !a.N {
+					shift[k] = float64(cell[k]) * l[k]
+					continue
+				}
+				// the unwrapped cell of the atom in the expanded box.
+				u := cell[k] + *img[k]
+				w := u / dims[k]
+				if u%dims[k] < 0 {
+					w-- // rounded toward negative infinity
+				}
+				shift[k] = float64(u-w*dims[k]) * l[k]
+				*img[k] = w
+			}
+			a.X += shift[0]
+			a.Y += shift[1]
+			a.Z += shift[2]
+			if a.MolTag > 0 {
+				a.MolTag += c * maxMol
+			}
+			newAtoms[id+c*maxID] = &a
+			shifts[id+c*maxID] = shift
+		}
+	}
+
+	replicated := make(map[key.Name]map[int]*key.Link)
+	for _, name := range []key.Name{key.NameBonds, key.NameAngles, key.NameDihedrals} {
+		links, ok, err := linksOf(f, name)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		var maxLink int
+		for id := range links {
+			if id > maxLink {
+				maxLink = id
+			}
+		}
+		newLinks := make(map[int]*key.Link, len(links)*n)
+		for c := 0; c < n; c++ {
+			for id, link := range links {
+				if link == nil {
+					return fmt.Errorf("link = %d of %s is nil", id, name)
+				}
+				clone := link.Clone()
+				a := clone.Atoms()
+				for i := range a {
+					a[i] += c * maxID
+				}
+				newLinks[id+c*maxLink] = clone
+			}
+		}
+		replicated[name] = newLinks
+	}
+
+	bonus := make(map[key.Name]map[int][]float64)
+	for _, name := range bonusNames {
+		values, ok, err := bonusOf(f, name)
+		if err != nil {
+			return err
+		} else if !ok {
+			continue
+		}
+		axes := bonusAxes[name]
+		newValues := make(map[int][]float64, len(values)*n)
+		for c := 0; c < n; c++ {
+			for id, val := range values {
+				if _, ok := atoms[id]; !ok {
+					return fmt.Errorf("atom = %d of %s does not exist", id, name)
+				}
+				if len(val) != len(axes) {
+					return fmt.Errorf("atom = %d of %s has %d values, want %d", id, name, len(val), len(axes))
+				}
+				shifted := make([]float64, len(val))
+				for i, axis := range axes {
+					shifted[i] = val[i] + shifts[id+c*maxID][axis]
+				}
+				newValues[id+c*maxID] = shifted
+			}
+		}
+		bonus[name] = newValues
+	}
+
+	// v is modified once every value is replicated without error.
+	for name, links := range replicated {
+		if err := setField(f, name, links); err != nil {
+			return err
+		}
+	}
+	for name, values := range bonus {
+		if err := setField(f, name, values); err != nil {
+			return err
+		}
+	}
+	if err := setField(f, key.NameAtoms, newAtoms); err != nil {
+		return err
+	}
+	if err := setCounts(f); err != nil {
+		return err
+	}
+
+	for i, m := range [3]int{nx, ny, nz} {
+		box[i][1] = box[i][0] + float64(m)*l[i]
+	}
+	for i, name := range []key.Name{key.NameBoxX, key.NameBoxY, key.NameBoxZ} {
+		if err := setField(f, name, box[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
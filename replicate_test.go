@@ -0,0 +1,192 @@
+package lmpsdat
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestReplicate(t *testing.T) {
+	const in = `title
+
+2 atoms
+1 atom types
+1 bonds
+1 bond types
+
+0 2 xlo xhi
+0 3 ylo yhi
+0 4 zlo zhi
+
+Atoms
+
+1 1 1 0 0.5 1 1
+2 1 1 0 1.5 1 1
+
+Bonds
+
+1 1 1 2 0.25 x
+`
+	type bonded struct {
+		AtomsNbr  int               `lmpsdat:"atoms"`
+		AtomTypes int               `lmpsdat:"atom types"`
+		BondsNbr  int               `lmpsdat:"bonds"`
+		BondTypes int               `lmpsdat:"bond types"`
+		X         [2]float64        `lmpsdat:"xlo xhi"`
+		Y         [2]float64        `lmpsdat:"ylo yhi"`
+		Z         [2]float64        `lmpsdat:"zlo zhi"`
+		Atoms     map[int]*key.Atom `lmpsdat:"Atoms, full"`
+		Bonds     map[int]*key.Link `lmpsdat:"Bonds"`
+	}
+	var b bonded
+	if err := decodeString(in, &b, func(dec *Decoder) { dec.SetExtraColumns(true) }); err != nil {
+		t.Fatal(err)
+	}
+	b.Bonds[1].SetComment("O-H")
+
+	v := struct {
+		bonded
+		LinesNbr int               `lmpsdat:"lines"`
+		Lines    map[int][]float64 `lmpsdat:"Lines"`
+	}{bonded: b, LinesNbr: 1, Lines: map[int][]float64{2: {1, 1, 2, 1}}}
+	if err := Replicate(&v, 2, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.AtomsNbr != 4 || v.BondsNbr != 2 || v.LinesNbr != 2 {
+		t.Errorf("counts = %d atoms, %d bonds, %d lines, want 4, 2, 2", v.AtomsNbr, v.BondsNbr, v.LinesNbr)
+	}
+	if v.X != [2]float64{0, 4} || v.Y != [2]float64{0, 3} || v.Z != [2]float64{0, 4} {
+		t.Errorf("box = %v %v %v, want [0 4] [0 3] [0 4]", v.X, v.Y, v.Z)
+	}
+	atoms := []struct {
+		id     int
+		mol    int
+		x      float64
+		source int
+	}{
+		{1, 1, 0.5, 1},
+		{2, 1, 1.5, 2},
+		{3, 2, 2.5, 1},
+		{4, 2, 3.5, 2},
+	}
+	for _, a := range atoms {
+		got, ok := v.Atoms[a.id]
+		if !ok {
+			t.Errorf("atom %d is missing", a.id)
+			continue
+		}
+		if got.MolTag != a.mol || got.X != a.x || got.Y != 1 || got.Z != 1 {
+			t.Errorf("atom %d = %+v, want mol = %d and x = %g", a.id, *got, a.mol, a.x)
+		}
+	}
+
+	bonds := map[int][]int{1: {1, 2}, 2: {3, 4}}
+	for id, want := range bonds {
+		got, ok := v.Bonds[id]
+		if !ok {
+			t.Errorf("bond %d is missing", id)
+			continue
+		}
+		if a := got.Atoms(); len(a) != 2 || a[0] != want[0] || a[1] != want[1] {
+			t.Errorf("bond %d atoms = %v, want %v", id, a, want)
+		}
+		if e := strings.Join(got.Extra(), " "); e != "0.25 x" {
+			t.Errorf("bond %d extra = %q, want %q", id, e, "0.25 x")
+		}
+		if got.Comment() != "O-H" {
+			t.Errorf("bond %d comment = %q, want %q", id, got.Comment(), "O-H")
+		}
+	}
+	if v.Bonds[1] == b.Bonds[1] || v.Bonds[1].Atoms()[0] != 1 {
+		t.Error("the original bond is shared with its image")
+	}
+
+	lines := map[int][]float64{2: {1, 1, 2, 1}, 4: {3, 1, 4, 1}}
+	if len(v.Lines) != len(lines) {
+		t.Errorf("Lines = %v, want %v", v.Lines, lines)
+	}
+	for id, want := range lines {
+		got := v.Lines[id]
+		if len(got) != len(want) {
+			t.Errorf("line %d = %v, want %v", id, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("line %d = %v, want %v", id, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestReplicateInvalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		nx, ny, nz int
+	}{
+		{"zero", 0, 1, 1},
+		{"negative", 1, -1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			if err := Replicate(s, tt.nx, tt.ny, tt.nz); err == nil {
+				t.Error("Replicate = nil, want an error")
+			}
+			if len(s.Atoms) != 6 {
+				t.Errorf("len(Atoms) = %d, want 6", len(s.Atoms))
+			}
+		})
+	}
+}
+
+func TestReplicateImageFlags(t *testing.T) {
+	// the bond crosses the periodic boundary along x: the atom 2 is unwrapped
+	// at 10.3 with the image flag 1 or at -0.3 with the image flag -1.
+	tests := []struct {
+		name  string
+		atom2 key.Atom
+		want  map[int][2]float64 // x and image flag along x of each atom
+	}{
+		{"positive image", key.Atom{AtomType: 1, X: 0.3, N: true, NX: 1}, map[int][2]float64{
+			1: {9.5, 0}, 2: {10.3, 0}, 3: {19.5, 0}, 4: {0.3, 1},
+		}},
+		{"negative image", key.Atom{AtomType: 1, X: 9.7, N: true, NX: -1}, map[int][2]float64{
+			1: {9.5, 0}, 2: {19.7, -1}, 3: {19.5, 0}, 4: {9.7, 0},
+		}},
+		{"no image flag", key.Atom{AtomType: 1, X: 0.3}, map[int][2]float64{
+			1: {9.5, 0}, 2: {0.3, 0}, 3: {19.5, 0}, 4: {10.3, 0},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				X     [2]float64        `lmpsdat:"xlo xhi"`
+				Y     [2]float64        `lmpsdat:"ylo yhi"`
+				Z     [2]float64        `lmpsdat:"zlo zhi"`
+				Atoms map[int]*key.Atom `lmpsdat:"Atoms, atomic"`
+				Bonds map[int]*key.Link `lmpsdat:"Bonds"`
+			}
+			atom2 := tt.atom2
+			v.Atoms = map[int]*key.Atom{1: {AtomType: 1, X: 9.5, N: atom2.N}, 2: &atom2}
+			v.Bonds = map[int]*key.Link{1: key.NewLink(1, 1, 2)}
+			v.X, v.Y, v.Z = [2]float64{0, 10}, [2]float64{0, 10}, [2]float64{0, 10}
+			if err := Replicate(&v, 2, 1, 1); err != nil {
+				t.Fatal(err)
+			}
+			for id, want := range tt.want {
+				a := v.Atoms[id]
+				if a == nil || math.Abs(a.X-want[0]) > 1e-12 || float64(a.NX) != want[1] {
+					t.Errorf("atom %d = %+v, want x = %g and image flag = %g", id, a, want[0], want[1])
+				}
+			}
+			if a := v.Bonds[2].Atoms(); a[0] != 3 || a[1] != 4 {
+				t.Errorf("bond 2 atoms = %v, want [3 4]", a)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package lmpsdat
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// ParseSection decodes a single table or header whose Name is name from data,
+// without the rest of the LAMMPS data file. data must begin with the header of
+// the table (e.g. "Masses"), after optional blank lines, and the values that
+// follow the table are ignored. The atom style of the Atoms table is detected
+// (see key.Options.DetectAtomStyle).
+//
+// A table reads as many values as given by the Headers it depends on. These
+// Headers are passed in deps and must be set beforehand (see key.Header.Set):
+//   - Masses and Coeffs tables: the number of types (e.g. NameAtomTypes for
+//     NameMasses, NameBondTypes for NameBondCoeffs);
+//   - Atoms: NameAtomsNbr and NameAtomTypes;
+//   - Bonds, Angles, and Dihedrals: the number of values (e.g. NameBondsNbr)
+//     and the number of types (e.g. NameBondTypes);
+//   - Lines and Triangles: NameAtomsNbr and the number of values (e.g.
+//     NameLinesNbr).
+//
+// The headers and the boxes (e.g. NameAtomsNbr, NameBoxX) do not need any
+// Header. The Check method of the Key returned is not called as some of its
+// Headers (e.g. NameAtomsNbr for the Bonds table) may be missing.
+func ParseSection(name key.Name, data []byte, deps ...key.Key) (key.Key, error) {
+	if !key.IsName(name) {
+		return nil, fmt.Errorf("name = %s is not supported", name)
+	}
+	keys := key.MakeKeys([]key.Name{name}, nil)
+	key.SetOptions(keys, &key.Options{DetectAtomStyle: true})
+	k := keys[name]
+	if len(deps) > 0 {
+		if err := k.SetKeys(deps...); err != nil {
+			return nil, fmt.Errorf("k.SetKeys for Key = %s: %w", name, err)
+		}
+	}
+
+	r := bufio.NewScanner(bytes.NewReader(data))
+	for r.Scan() {
+		s := r.Bytes()
+		if len(bytes.TrimSpace(s)) == 0 {
+			continue
+		}
+		if !k.Keyword(s) {
+			return nil, fmt.Errorf("data does not begin with the header of %s", name)
+		}
+		if err := k.Decode(s, r); err != nil {
+			return nil, fmt.Errorf("k.Decode for Key = %s: %w", name, err)
+		}
+		return k, nil
+	}
+	if r.Err() != nil {
+		return nil, fmt.Errorf("r.Scan: %w", r.Err())
+	}
+	return nil, fmt.Errorf("data does not contain %s", name)
+}
@@ -0,0 +1,82 @@
+package lmpsdat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// header returns a Header whose Name is name and whose value is v.
+func header(name key.Name, v int) *key.Header {
+	h := key.NewHeader(name)
+	h.Set(v)
+	return h
+}
+
+func TestParseSection(t *testing.T) {
+	tests := []struct {
+		name key.Name
+		data string
+		deps []key.Key
+		want string
+	}{
+		{key.NameMasses, "\nMasses\n\n1 15.9994\n2 1.008\n", []key.Key{header(key.NameAtomTypes, 2)},
+			"Masses\n\n1 15.9994\n2 1.008\n"},
+		{key.NameBonds, "Bonds\n\n1 1 1 2\n2 1 1 3\n\nAngles\n\n1 1 2 1 3\n", []key.Key{header(key.NameBondsNbr, 2), header(key.NameBondTypes, 1)},
+			"Bonds\n\n1 1 1 2\n2 1 1 3\n"},
+		{key.NameAtoms, "Atoms\n\n1 1 0.5 1.5 2.5\n", []key.Key{header(key.NameAtomsNbr, 1), header(key.NameAtomTypes, 1)},
+			"Atoms\n\n1 1 0.5 1.5 2.5\n"},
+		{key.NameBoxX, "-5 5 xlo xhi\n", nil, "-5 5 xlo xhi\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.name), func(t *testing.T) {
+			k, err := ParseSection(tt.name, []byte(tt.data), tt.deps...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if k.Name() != tt.name {
+				t.Errorf("Name = %s, want %s", k.Name(), tt.name)
+			}
+			var sb strings.Builder
+			if err := k.Encode(&sb); err != nil {
+				t.Fatal(err)
+			}
+			if got := sb.String(); got != tt.want {
+				t.Errorf("Encode = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	k, err := ParseSection(key.NameBonds, []byte("Bonds\n\n1 1 1 2\n2 1 1 3\n"), header(key.NameBondsNbr, 2), header(key.NameBondTypes, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := k.(*key.Links).Map()[2].Atoms(); len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Errorf("bond 2 = %v, want [1 3]", got)
+	}
+}
+
+func TestParseSectionInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     key.Name
+		data    string
+		deps    []key.Key
+		wantErr string
+	}{
+		{"unknown name", "Foo", "Foo\n\n1 2\n", nil, "is not supported"},
+		{"other table", key.NameMasses, "Bonds\n\n1 1 1 2\n", []key.Key{header(key.NameAtomTypes, 1)}, "does not begin with the header of Masses"},
+		{"empty", key.NameMasses, "\n\n", []key.Key{header(key.NameAtomTypes, 1)}, "does not contain Masses"},
+		{"truncated", key.NameMasses, "Masses\n\n1 15.9994\n", []key.Key{header(key.NameAtomTypes, 2)}, "k.Decode"},
+		{"wrong dependency", key.NameMasses, "Masses\n\n1 15.9994\n", []key.Key{header(key.NameBondsNbr, 1)}, "k.SetKeys"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSection(tt.key, []byte(tt.data), tt.deps...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSection = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package lmpsdat
+
+import (
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// Stats summarizes a LAMMPS data file decoded with the DecodeWithStats method.
+type Stats struct {
+	// Rows contains the number of values (= 1 line) decoded for each table
+	// and header found in the file. A header counts as one value.
+	Rows map[key.Name]int
+
+	// AtomStyle is the atom style used to decode the Atoms table, e.g. the
+	// one detected with SetDetectAtomStyle. It is nil if the struct has no
+	// field tagged with NameAtoms.
+	AtomStyle key.AtomStyle
+
+	// ImageFlags is true if at least one atom has the image flags.
+	ImageFlags bool
+
+	// FilledImageFlags is the number of atoms whose missing image flags were
+	// set to 0 0 0 (see SetMixedImageFlags). These atoms would have been
+	// rejected by the Check method of the Atoms table otherwise.
+	FilledImageFlags int
+
+	// Skipped contains the Names of the tables skipped by the best-effort
+	// decoding (see SetBestEffort) in the order they were found.
+	Skipped []key.Name
+}
+
+// DecodeWithStats works like Decode but also returns a summary of the decoded
+// file. If an error occurs, Rows still contains the tables and headers decoded
+// before the error.
+func (dec *Decoder) DecodeWithStats(v interface{}) (Stats, error) {
+	s := &Stats{Rows: make(map[key.Name]int)}
+	dec.stats = s
+	defer func() { dec.stats = nil }()
+	err := dec.Decode(v)
+	return *s, err
+}
+
+// rowsOf returns the number of values of k, or one if k is a header.
+func rowsOf(k key.Key) int {
+	if l, ok := k.(lener); ok {
+		return l.Len()
+	}
+	return 1
+}
+
+// add records the number of values of the Key k whose Name is name. It does
+// nothing if s is nil.
+func (s *Stats) add(name key.Name, k key.Key) {
+	if s == nil {
+		return
+	}
+	s.Rows[name] = rowsOf(k)
+}
+
+// skip records that the table whose Name is name was skipped. It does nothing
+// if s is nil.
+func (s *Stats) skip(name key.Name) {
+	if s == nil {
+		return
+	}
+	s.Skipped = append(s.Skipped, name)
+}
+
+// finish records the information about the atoms of keys. It does nothing if s
+// is nil.
+func (s *Stats) finish(keys map[key.Name]key.Key) {
+	if s == nil {
+		return
+	}
+	atoms, ok := keys[key.NameAtoms].(*key.Atoms)
+	if !ok {
+		return
+	}
+	s.AtomStyle = atoms.AtomStyle()
+	s.ImageFlags = atoms.ImageFlags()
+	s.FilledImageFlags = atoms.FilledImageFlags()
+}
@@ -0,0 +1,57 @@
+package lmpsdat
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestDecodeWithStats(t *testing.T) {
+	full := readFile(t, "full.data")
+	var s system
+	stats, err := NewDecoder(strings.NewReader(full)).DecodeWithStats(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Stats{
+		Rows: map[key.Name]int{
+			key.NameAtomsNbr: 1, key.NameAtomTypes: 1, key.NameBondsNbr: 1, key.NameBondTypes: 1,
+			key.NameAnglesNbr: 1, key.NameAngleTypes: 1, key.NameBoxX: 1, key.NameBoxY: 1, key.NameBoxZ: 1,
+			key.NameMasses: 2, key.NamePairCoeffs: 2, key.NameBondCoeffs: 1, key.NameAngleCoeffs: 1,
+			key.NameAtoms: 6, key.NameBonds: 4, key.NameAngles: 2,
+		},
+		AtomStyle: key.AtomStyleFull,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("Stats = %+v, want %+v", stats, want)
+	}
+
+	// the image flags of the second atom are filled.
+	in := strings.Replace(full, "1 1 1 -0.8476 1 1 1\n", "1 1 1 -0.8476 1 1 1 0 0 1\n", 1)
+	dec := NewDecoder(strings.NewReader(in))
+	dec.SetMixedImageFlags(true)
+	stats, err = dec.DecodeWithStats(&s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.ImageFlags || stats.FilledImageFlags != 5 {
+		t.Errorf("ImageFlags = %v and FilledImageFlags = %d, want true and 5", stats.ImageFlags, stats.FilledImageFlags)
+	}
+
+	// the tables skipped by the best-effort decoding are listed.
+	in = strings.Replace(full, "1 450 1\n", "1 450 x\n", 1)
+	dec = NewDecoder(strings.NewReader(in))
+	dec.SetBestEffort(func(key.Name, error) {})
+	stats, err = dec.DecodeWithStats(&system{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stats.Skipped, []key.Name{key.NameBondCoeffs}) {
+		t.Errorf("Skipped = %v, want [%s]", stats.Skipped, key.NameBondCoeffs)
+	}
+	if stats.Rows[key.NameMasses] != 2 {
+		t.Errorf("Rows = %v, want the tables decoded before the error", stats.Rows)
+	}
+}
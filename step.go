@@ -0,0 +1,132 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// flusher is implemented by the buffered writers (e.g. bufio.Writer).
+type flusher interface {
+	Flush() error
+}
+
+// step contains the state of the step-wise encoding (see WriteHeader).
+type step struct {
+	headers map[key.Name]key.Key
+	written map[key.Name]bool
+}
+
+// WriteHeader begins a step-wise encoding: it writes the title and the headers
+// of the struct pointed to by v to the stream. The tables of v are ignored:
+// they are written one by one with WriteSection, and the encoding ends with
+// Finish. This allows to stream a huge Atoms table and then a huge Bonds table
+// without holding both in memory simultaneously. The output is identical to the
+// one of Encode if the tables are written in the order of key.ListSections.
+//
+// Unlike Encode, which sets the headers from the length of the tables (see
+// key.Key.SetKeysVal) and infers a number of types equal to zero from the
+// largest type used, WriteHeader writes the headers as they are: the fields of
+// v containing the number of values (e.g. NameAtomsNbr) and of types (e.g.
+// NameAtomTypes) must be set. Only a table of v that is not nil sets its
+// headers.
+func (enc *Encoder) WriteHeader(v interface{}) error {
+	if enc.step != nil {
+		return fmt.Errorf("WriteHeader was already called: use the Finish method")
+	}
+	keys, err := enc.fillKeys(v)
+	if err != nil {
+		return err
+	}
+	for n, k := range keys {
+		if !key.IsHeader(k) && n != key.NameTitle {
+			delete(keys, n)
+		}
+	}
+	if err := enc.checkKeys(keys); err != nil {
+		return err
+	}
+	if err := enc.encodeHeaders(keys); err != nil {
+		return err
+	}
+	enc.step = &step{headers: keys, written: make(map[key.Name]bool)}
+	return enc.flush()
+}
+
+// WriteSection writes the table of the struct pointed to by v whose Name is
+// name to the stream and flushes the writer if it implements a Flush method
+// (e.g. bufio.Writer). WriteHeader must be called first.
+//
+// The table is checked against the headers written by WriteHeader: an error is
+// returned if a header it requires was not written (e.g. NameBondsNbr for
+// NameBonds) or if its number of values does not match the header. Each table
+// can be written only once.
+func (enc *Encoder) WriteSection(v interface{}, name key.Name) error {
+	if enc.step == nil {
+		return fmt.Errorf("WriteHeader was not called")
+	}
+	if enc.step.written[name] {
+		return fmt.Errorf("table = %s was already written", name)
+	}
+	keys, err := enc.fillKeys(v)
+	if err != nil {
+		return err
+	}
+	k, ok := keys[name]
+	if !ok || key.IsHeader(k) || name == key.NameTitle {
+		return fmt.Errorf("table = %s is not a field of the struct", name)
+	}
+
+	for n, h := range keys {
+		if _, ok := h.(*key.Header); !ok {
+			continue // the boxes are not required by the tables
+		}
+		written, ok := enc.step.headers[n]
+		if !ok {
+			return fmt.Errorf("header = %s required by table = %s was not written by WriteHeader", n, name)
+		}
+		if val := h.Get().(int); val != 0 && val != written.Get().(int) {
+			return fmt.Errorf("header = %s was written as %d but table = %s requires %d", n, written.Get(), name, val)
+		}
+		if err := h.Set(written.Get()); err != nil {
+			return fmt.Errorf("h.Set for Key = %s: %w", n, err)
+		}
+	}
+	if err := enc.checkKeys(keys); err != nil {
+		return err
+	}
+
+	if err := enc.encodeTable(k); err != nil {
+		return err
+	}
+	enc.step.written[name] = true
+	return enc.flush()
+}
+
+// Finish ends the step-wise encoding begun by WriteHeader. It returns an error
+// if a header containing a number of values greater than zero (e.g. "10
+// bonds") was written without its table. The Encoder can then be used again.
+func (enc *Encoder) Finish() error {
+	if enc.step == nil {
+		return fmt.Errorf("WriteHeader was not called")
+	}
+	s := enc.step
+	enc.step = nil
+	for _, table := range key.ListSections {
+		h, ok := s.headers[countOf[table]]
+		if ok && h.Get().(int) > 0 && !s.written[table] {
+			return fmt.Errorf("table = %s was not written but header = %s is %d", table, h.Name(), h.Get())
+		}
+	}
+	return enc.flush()
+}
+
+// flush flushes the writer if it implements a Flush method.
+func (enc *Encoder) flush() error {
+	if f, ok := enc.w.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("Flush: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package lmpsdat
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// tables lists the tables of system in the order of key.ListSections.
+var tables = []key.Name{
+	key.NameMasses, key.NamePairCoeffs, key.NameBondCoeffs, key.NameAngleCoeffs,
+	key.NameAtoms, key.NameBonds, key.NameAngles,
+}
+
+func TestEncodeStepWise(t *testing.T) {
+	want := encodeString(t, fullSystem(t))
+
+	// headers returns the system without its tables: the headers are set by
+	// the fields of the counts only.
+	headers := func(s *system) *system {
+		h := *s
+		h.Masses, h.PairCoeffs, h.BondCoeffs, h.AngleCoeffs = nil, nil, nil, nil
+		h.Atoms, h.Bonds, h.Angles = nil, nil, nil
+		return &h
+	}
+	tests := []struct {
+		name     string
+		header   func(*system) *system
+		buffered bool
+	}{
+		{"whole struct", func(s *system) *system { return s }, false},
+		{"headers only", headers, false},
+		{"buffered", headers, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			var b bytes.Buffer
+			w := bufio.NewWriter(&b)
+			enc := NewEncoder(&b)
+			if tt.buffered {
+				enc = NewEncoder(w)
+			}
+			if err := enc.WriteHeader(tt.header(s)); err != nil {
+				t.Fatal(err)
+			}
+			for _, n := range tables {
+				if err := enc.WriteSection(s, n); err != nil {
+					t.Fatalf("WriteSection %s: %v", n, err)
+				}
+				if tt.buffered && w.Buffered() != 0 {
+					t.Errorf("%d bytes buffered after WriteSection %s", w.Buffered(), n)
+				}
+			}
+			if err := enc.Finish(); err != nil {
+				t.Fatal(err)
+			}
+			if got := b.String(); got != want {
+				t.Errorf("step-wise output differs from Encode:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// mustWriteHeader calls the WriteHeader method of enc and fails if it returns an
+// error.
+func mustWriteHeader(t testing.TB, enc *Encoder, v interface{}) {
+	t.Helper()
+	if err := enc.WriteHeader(v); err != nil {
+		t.Fatalf("WriteHeader: %v", err)
+	}
+}
+
+func TestEncodeStepWiseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, enc *Encoder, s *system) error
+	}{
+		{"section before header", func(t *testing.T, enc *Encoder, s *system) error {
+			return enc.WriteSection(s, key.NameAtoms)
+		}},
+		{"finish before header", func(t *testing.T, enc *Encoder, s *system) error {
+			return enc.Finish()
+		}},
+		{"header twice", func(t *testing.T, enc *Encoder, s *system) error {
+			mustWriteHeader(t, enc, s)
+			return enc.WriteHeader(s)
+		}},
+		{"section twice", func(t *testing.T, enc *Encoder, s *system) error {
+			mustWriteHeader(t, enc, s)
+			if err := enc.WriteSection(s, key.NameAtoms); err != nil {
+				t.Fatal(err)
+			}
+			return enc.WriteSection(s, key.NameAtoms)
+		}},
+		{"header as a section", func(t *testing.T, enc *Encoder, s *system) error {
+			mustWriteHeader(t, enc, s)
+			return enc.WriteSection(s, key.NameAtomsNbr)
+		}},
+		{"count not matching", func(t *testing.T, enc *Encoder, s *system) error {
+			mustWriteHeader(t, enc, s)
+			delete(s.Atoms, 6)
+			return enc.WriteSection(s, key.NameAtoms)
+		}},
+		// the number of types is not inferred by WriteHeader.
+		{"types not set", func(t *testing.T, enc *Encoder, s *system) error {
+			h := *s
+			h.AtomTypes, h.Masses, h.PairCoeffs, h.Atoms = 0, nil, nil, nil
+			mustWriteHeader(t, enc, &h)
+			return enc.WriteSection(s, key.NameAtoms)
+		}},
+		{"missing table", func(t *testing.T, enc *Encoder, s *system) error {
+			mustWriteHeader(t, enc, s)
+			return enc.Finish()
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(t, NewEncoder(&bytes.Buffer{}), fullSystem(t)); err == nil {
+				t.Error("error = nil, want an error")
+			}
+		})
+	}
+}
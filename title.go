@@ -0,0 +1,57 @@
+package lmpsdat
+
+import (
+	"fmt"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// GetTitle returns the title stored in v. v can be a pointer of a struct having
+// a field tagged with NameTitle or a map of Keys (e.g. returned by
+// key.MakeKeys) containing NameTitle.
+func GetTitle(v interface{}) (string, error) {
+	if keys, ok := v.(map[key.Name]key.Key); ok {
+		k, ok := keys[key.NameTitle]
+		if !ok {
+			return "", fmt.Errorf("Key = %s is missing", key.NameTitle)
+		}
+		title, ok := k.Get().(string)
+		if !ok {
+			return "", fmt.Errorf("Key = %s is not a string", key.NameTitle)
+		}
+		return title, nil
+	}
+
+	f, err := fields(v)
+	if err != nil {
+		return "", err
+	}
+	title, ok, err := fieldOf(f, key.NameTitle)
+	if err != nil {
+		return "", err
+	} else if !ok {
+		return "", fmt.Errorf("field with Name = %s is missing", key.NameTitle)
+	}
+	return title.(string), nil
+}
+
+// SetTitle sets the title stored in v to s. v can be a pointer of a struct
+// having a field tagged with NameTitle or a map of Keys containing NameTitle.
+func SetTitle(v interface{}, s string) error {
+	if keys, ok := v.(map[key.Name]key.Key); ok {
+		k, ok := keys[key.NameTitle]
+		if !ok {
+			return fmt.Errorf("Key = %s is missing", key.NameTitle)
+		}
+		return k.Set(s)
+	}
+
+	f, err := fields(v)
+	if err != nil {
+		return err
+	}
+	if _, ok := f[key.NameTitle]; !ok {
+		return fmt.Errorf("field with Name = %s is missing", key.NameTitle)
+	}
+	return setField(f, key.NameTitle, s)
+}
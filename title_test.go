@@ -0,0 +1,44 @@
+package lmpsdat
+
+import (
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		v    interface{}
+	}{
+		{"struct", fullSystem(t)},
+		{"keys", key.MakeKeys([]key.Name{key.NameTitle}, nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SetTitle(tt.v, "water box"); err != nil {
+				t.Fatal(err)
+			}
+			title, err := GetTitle(tt.v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if title != "water box" {
+				t.Errorf("GetTitle = %q, want %q", title, "water box")
+			}
+		})
+	}
+
+	var a struct {
+		AtomsNbr int `lmpsdat:"atoms"`
+	}
+	if _, err := GetTitle(&a); err == nil {
+		t.Error("GetTitle = nil without a Title field")
+	}
+	if err := SetTitle(&a, "x"); err == nil {
+		t.Error("SetTitle = nil without a Title field")
+	}
+	if err := SetTitle(map[key.Name]key.Key{}, "x"); err == nil {
+		t.Error("SetTitle = nil without a Title Key")
+	}
+}
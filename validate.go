@@ -0,0 +1,123 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// coeffsOf links the Names of the Masses and Coeffs tables to the Names of the
+// Headers containing the number of types. Each type has exactly one value.
+var coeffsOf = map[key.Name]key.Name{
+	key.NameMasses:                  key.NameAtomTypes,
+	key.NamePairCoeffs:              key.NameAtomTypes,
+	key.NameBondCoeffs:              key.NameBondTypes,
+	key.NameAngleCoeffs:             key.NameAngleTypes,
+	key.NameBondBondCoeffs:          key.NameAngleTypes,
+	key.NameBondAngleCoeffs:         key.NameAngleTypes,
+	key.NameDihedralCoeffs:          key.NameDihedralTypes,
+	key.NameMiddleBondTorsionCoeffs: key.NameDihedralTypes,
+	key.NameEndBondTorsionCoeffs:    key.NameDihedralTypes,
+	key.NameAngleTorsionCoeffs:      key.NameDihedralTypes,
+	key.NameAngleAngleTorsionCoeffs: key.NameDihedralTypes,
+	key.NameBondBond13Coeffs:        key.NameDihedralTypes,
+	key.NameAngleAngleCoeffs:        key.NameImproperTypes,
+}
+
+// linkCoeffsOf links the Names of the Links tables to the Names of the Coeffs
+// tables containing the coefficients of their types.
+var linkCoeffsOf = map[key.Name]key.Name{
+	key.NameBonds:     key.NameBondCoeffs,
+	key.NameAngles:    key.NameAngleCoeffs,
+	key.NameDihedrals: key.NameDihedralCoeffs,
+}
+
+// Validate verifies that the fields of the struct pointed to by v containing a
+// number of types or values (e.g. NameAtomTypes, NameBondsNbr) are equal to
+// the number of values of the corresponding tables (e.g. NameMasses,
+// NameBonds). The tables and the headers that are not fields of the struct are
+// ignored, as well as the empty Masses and Coeffs tables as they are optional.
+//
+// The Check methods of the Keys cannot detect such a mismatch when encoding
+// because the Encoder sets the headers from the length of the tables (see
+// key.Key.SetKeysVal): for instance, a Masses table with 4 values silently
+// replaces "3 atom types" by "4 atom types". Validate should therefore be
+// called before Encode if the headers are set by hand.
+//
+// Validate also verifies that each type used by the Bonds, Angles, and Dihedrals
+// tables has a value in the corresponding Coeffs table (e.g. NameBondCoeffs)
+// if this table is a non-empty field of the struct.
+//
+// The first mismatch found in the order of key.ListSections is returned, then
+// the first type without coefficients. An error is also returned if a field
+// cannot be converted to the type of its Key (e.g. a Header field of kind
+// string).
+func Validate(v interface{}) error {
+	f, err := fields(v)
+	if err != nil {
+		return err
+	}
+	for _, table := range key.ListSections {
+		header, coeffs := coeffsOf[table]
+		if !coeffs {
+			header = countOf[table]
+		}
+		n, ok, err := tableLen(f, table)
+		if err != nil {
+			return err
+		}
+		h, ok2, err := intOf(f, header)
+		if err != nil {
+			return err
+		}
+		if !ok || !ok2 {
+			continue
+		}
+		if n == 0 && coeffs {
+			continue
+		}
+		if n != h {
+			return fmt.Errorf("%s = %d is not equal to the number of values of %s = %d", header, h, table, n)
+		}
+	}
+	return validateLinkCoeffs(f)
+}
+
+// validateLinkCoeffs returns an error for the smallest type used by a Links
+// table that has no value in its non-empty Coeffs table.
+func validateLinkCoeffs(f map[key.Name]reflect.Value) error {
+	for _, name := range linkNames {
+		links, ok, err := linksOf(f, name)
+		if err != nil {
+			return err
+		}
+		c, ok2, err := fieldOf(f, linkCoeffsOf[name])
+		if err != nil {
+			return err
+		}
+		coeffs, _ := c.(map[int][]float64)
+		if !ok || !ok2 || len(coeffs) == 0 {
+			continue
+		}
+		used := make(map[int]bool)
+		for id, link := range links {
+			if link == nil {
+				return fmt.Errorf("link = %d of %s is nil", id, name)
+			}
+			used[link.Type()] = true
+		}
+		types := make([]int, 0, len(used))
+		for typ := range used {
+			types = append(types, typ)
+		}
+		sort.Ints(types)
+		for _, typ := range types {
+			if _, ok := coeffs[typ]; !ok {
+				return fmt.Errorf("type = %d of %s has no coefficients in %s", typ, name, linkCoeffsOf[name])
+			}
+		}
+	}
+	return nil
+}
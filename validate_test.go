@@ -0,0 +1,144 @@
+package lmpsdat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(s *system)
+		wantErr string
+	}{
+		{"valid", func(s *system) {}, ""},
+		{"more masses than types", func(s *system) { s.Masses[3] = 12.011 }, "atom types = 2 is not equal to the number of values of Masses = 3"},
+		{"fewer types than declared", func(s *system) { s.AtomTypes = 3 }, "atom types = 3 is not equal to the number of values of Masses = 2"},
+		{"bonds", func(s *system) { s.BondsNbr = 5 }, "bonds = 5 is not equal to the number of values of Bonds = 4"},
+		{"empty coeffs", func(s *system) { s.AngleCoeffs = nil }, ""},
+		{"type without coefficients", func(s *system) { s.Bonds[1].SetType(2) }, "type = 2 of Bonds has no coefficients in Bond Coeffs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			tt.modify(s)
+			err := Validate(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateLinkCoeffs(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(s *system)
+		wantErr string
+	}{
+		{"all types", func(s *system) {}, ""},
+		{"bond type", func(s *system) { s.Bonds[3].SetType(3) }, "type = 3 of Bonds has no coefficients in Bond Coeffs"},
+		{"smallest type first", func(s *system) {
+			s.Bonds[1].SetType(4)
+			s.Bonds[2].SetType(2)
+		}, "type = 2 of Bonds"},
+		{"angle type", func(s *system) { s.Angles[2].SetType(2) }, "type = 2 of Angles has no coefficients in Angle Coeffs"},
+		{"unused coefficients", func(s *system) {
+			s.BondCoeffs[2] = []float64{340, 1.09}
+			s.BondTypes = 2
+		}, ""},
+		{"empty coeffs", func(s *system) {
+			s.BondCoeffs = map[int][]float64{}
+			s.Bonds[1].SetType(2)
+		}, ""},
+		{"nil link", func(s *system) { s.Angles[2] = nil }, "link = 2 of Angles is nil"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			tt.modify(s)
+			err := Validate(s)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	topology := VersionProfile{Name: "topology", MoleculeTopology: true}
+	tests := []struct {
+		name   string
+		modify func(s *system)
+		// errors wanted with ProfileCurrent, ProfileLegacy, and topology.
+		current, legacy, topology string
+	}{
+		{"valid", func(s *system) {}, "", "", ""},
+		{"atom identifiers not contiguous", func(s *system) {
+			s.Atoms[7] = s.Atoms[6]
+			delete(s.Atoms, 6)
+			s.Bonds[4].Atoms()[1] = 7
+			s.Angles[2].Atoms()[2] = 7
+		}, "", "profile = legacy: identifier = 7 of Atoms is not between 1 and the number of values = 6", ""},
+		{"bond identifiers not contiguous", func(s *system) {
+			s.Bonds[9] = s.Bonds[4]
+			delete(s.Bonds, 4)
+		}, "", "profile = legacy: identifier = 9 of Bonds", ""},
+		{"flat box", func(s *system) { s.Z = [2]float64{0, 0} }, "", "profile = legacy: box zlo zhi = [0 0] has no length", ""},
+		{"masses in the input script", func(s *system) { s.Masses = nil }, "", "profile = legacy: mass of type = 1 of atom = 1 is missing", ""},
+		{"molecule split by a bond", func(s *system) { s.Atoms[5].MolTag = 1 }, "", "", "profile = topology:"},
+		{"invalid file", func(s *system) { s.BondsNbr = 5 }, "bonds = 5", "bonds = 5", "bonds = 5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fullSystem(t)
+			tt.modify(s)
+			for _, p := range []struct {
+				profile VersionProfile
+				wantErr string
+			}{
+				{ProfileCurrent, tt.current},
+				{ProfileLegacy, tt.legacy},
+				{topology, tt.topology},
+			} {
+				err := ValidateProfile(s, p.profile)
+				if p.wantErr == "" {
+					if err != nil {
+						t.Errorf("ValidateProfile with profile = %s: %v", p.profile.Name, err)
+					}
+					continue
+				}
+				if err == nil || !strings.Contains(err.Error(), p.wantErr) {
+					t.Errorf("ValidateProfile with profile = %s: %v, want an error containing %q", p.profile.Name, err, p.wantErr)
+				}
+			}
+		})
+	}
+
+	// the box is required by the legacy profile.
+	v := struct {
+		AtomsNbr  int               `lmpsdat:"atoms"`
+		AtomTypes int               `lmpsdat:"atom types"`
+		Atoms     map[int]*key.Atom `lmpsdat:"Atoms, full"`
+	}{1, 1, map[int]*key.Atom{1: {AtomType: 1}}}
+	if err := ValidateProfile(&v, ProfileCurrent); err != nil {
+		t.Errorf("ValidateProfile with profile = current: %v", err)
+	}
+	if err := ValidateProfile(&v, ProfileLegacy); err == nil || !strings.Contains(err.Error(), "field with Name = xlo xhi is missing") {
+		t.Errorf("ValidateProfile with profile = legacy: %v, want a missing box", err)
+	}
+}
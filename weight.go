@@ -0,0 +1,71 @@
+package lmpsdat
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+// MolecularWeights returns the total mass of each molecule, i.e. the sum of the
+// masses of its atoms. The keys of the returned map are the molecule tags
+// (see Atom.MolTag). masses contains the mass of each atom type (see
+// NameMasses). An error is returned if the mass of an atom type is missing.
+//
+// The atoms whose molecule tag is zero (i.e. not part of a molecule) are
+// grouped under the key zero.
+func MolecularWeights(atoms map[int]*key.Atom, masses map[int]float64) (map[int]float64, error) {
+	weights := make(map[int]float64)
+	for id, atom := range atoms {
+		if atom == nil {
+			return nil, fmt.Errorf("atom = %d is nil", id)
+		}
+		m, ok := masses[atom.AtomType]
+		if !ok {
+			return nil, fmt.Errorf("mass of type = %d of atom = %d is missing", atom.AtomType, id)
+		}
+		weights[atom.MolTag] += m
+	}
+	return weights, nil
+}
+
+// RadiusOfGyration returns the mass-weighted radius of gyration of each
+// molecule, i.e. the square root of the mean squared distance between its atoms
+// and its center of mass (see CenterOfMass). The keys of the returned map are
+// the molecule tags (see Atom.MolTag). masses contains the mass of each atom
+// type (see NameMasses). The radius of gyration of a molecule of one atom is
+// zero. An error is returned if the mass of an atom type is missing or if the
+// total mass of a molecule is not greater than zero.
+//
+// The atoms whose molecule tag is zero (i.e. not part of a molecule) are
+// grouped under the key zero. The coordinates are used as they are: the image
+// flags are not taken into account.
+func RadiusOfGyration(atoms map[int]*key.Atom, masses map[int]float64) (map[int]float64, error) {
+	molecules := make(map[int]map[int]*key.Atom)
+	for id, atom := range atoms {
+		if atom == nil {
+			return nil, fmt.Errorf("atom = %d is nil", id)
+		}
+		if molecules[atom.MolTag] == nil {
+			molecules[atom.MolTag] = make(map[int]*key.Atom)
+		}
+		molecules[atom.MolTag][id] = atom
+	}
+
+	rg := make(map[int]float64, len(molecules))
+	for tag, mol := range molecules {
+		c, err := CenterOfMass(mol, masses)
+		if err != nil {
+			return nil, fmt.Errorf("molecule = %d: %w", tag, err)
+		}
+		var sum, total float64
+		for _, atom := range mol {
+			m := masses[atom.AtomType]
+			dx, dy, dz := atom.X-c[0], atom.Y-c[1], atom.Z-c[2]
+			sum += m * (dx*dx + dy*dy + dz*dz)
+			total += m
+		}
+		rg[tag] = math.Sqrt(sum / total)
+	}
+	return rg, nil
+}
@@ -0,0 +1,104 @@
+package lmpsdat
+
+import (
+	"math"
+	"testing"
+
+	"github.com/kpotier/lmpsdat/key"
+)
+
+func TestMolecularWeights(t *testing.T) {
+	s := fullSystem(t)
+	tests := []struct {
+		name    string
+		atoms   map[int]*key.Atom
+		masses  map[int]float64
+		want    map[int]float64
+		wantErr bool
+	}{
+		{"two waters", s.Atoms, s.Masses, map[int]float64{1: 18.0154, 2: 18.0154}, false},
+		{"without molecule", map[int]*key.Atom{
+			1: {MolTag: 1, AtomType: 1},
+			2: {AtomType: 2},
+			3: {AtomType: 2},
+		}, s.Masses, map[int]float64{1: 15.9994, 0: 2.016}, false},
+		{"missing mass", s.Atoms, map[int]float64{1: 15.9994}, nil, true},
+		{"nil atom", map[int]*key.Atom{1: nil}, s.Masses, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MolecularWeights(tt.atoms, tt.masses)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("MolecularWeights = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("MolecularWeights = %v, want %v", got, tt.want)
+			}
+			for tag, w := range tt.want {
+				if math.Abs(got[tag]-w) > 1e-9 {
+					t.Errorf("weight of molecule = %d = %g, want %g", tag, got[tag], w)
+				}
+			}
+		})
+	}
+}
+
+func TestRadiusOfGyration(t *testing.T) {
+	masses := map[int]float64{1: 1, 2: 2, 3: 0}
+	tests := []struct {
+		name    string
+		atoms   map[int]*key.Atom
+		want    map[int]float64
+		wantErr bool
+	}{
+		{"linear", map[int]*key.Atom{
+			1: {MolTag: 1, AtomType: 1, X: 0, Y: 1, Z: 1},
+			2: {MolTag: 1, AtomType: 1, X: 1, Y: 1, Z: 1},
+			3: {MolTag: 1, AtomType: 1, X: 2, Y: 1, Z: 1},
+		}, map[int]float64{1: math.Sqrt(2.0 / 3)}, false},
+		{"mass-weighted", map[int]*key.Atom{
+			1: {MolTag: 1, AtomType: 1, X: 0},
+			2: {MolTag: 1, AtomType: 2, X: 3},
+		}, map[int]float64{1: math.Sqrt(2)}, false},
+		{"single atom", map[int]*key.Atom{
+			1: {MolTag: 1, AtomType: 1, X: 0},
+			2: {MolTag: 1, AtomType: 1, X: 2},
+			3: {MolTag: 2, AtomType: 2, X: 5, Y: 5, Z: 5},
+		}, map[int]float64{1: 1, 2: 0}, false},
+		{"without molecule", map[int]*key.Atom{
+			1: {AtomType: 1, Z: -1},
+			2: {AtomType: 1, Z: 1},
+		}, map[int]float64{0: 1}, false},
+		{"missing mass", map[int]*key.Atom{1: {MolTag: 1, AtomType: 4}}, nil, true},
+		{"zero mass", map[int]*key.Atom{1: {MolTag: 1, AtomType: 3}}, nil, true},
+		{"nil atom", map[int]*key.Atom{1: nil}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RadiusOfGyration(tt.atoms, masses)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("RadiusOfGyration = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("RadiusOfGyration = %v, want %v", got, tt.want)
+			}
+			for tag, rg := range tt.want {
+				if math.Abs(got[tag]-rg) > 1e-12 {
+					t.Errorf("radius of gyration of molecule = %d = %g, want %g", tag, got[tag], rg)
+				}
+			}
+		})
+	}
+}